@@ -0,0 +1,328 @@
+package controllers
+
+import (
+	"errors"
+	"golang-final-project/services"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func UpdateAdminRole(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Role string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !services.IsValidRole(request.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+
+	idString := c.Param("id")
+
+	id, err := uuid.Parse(idString)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Admin ID"})
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	if principal.AdminID == id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot change your own role"})
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	if _, err := services.GetTenantAdminByID(db, tenant, id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+		return
+	}
+
+	if err := services.UpdateAdminRole(db, id, request.Role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Outstanding access tokens still carry the old role; the admin picks up
+	// the new one on the next refresh
+	if err := services.TokenRevocations.RevokeAdminTokens(id, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Admin role updated successfully"})
+}
+
+func GetMyProfile(c *gin.Context, db *gorm.DB) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	admin, err := services.GetAdminByID(db, principal.AdminID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, admin)
+}
+
+// UpdateMyProfile changes the name right away. A new email is only applied
+// once the verification link sent to it is used.
+func UpdateMyProfile(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Name  *string `json:"name"`
+		Email *string `json:"email" binding:"omitempty,email"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	admin, err := services.GetAdminByID(db, principal.AdminID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin not found"})
+		return
+	}
+
+	if request.Name != nil {
+		name := strings.TrimSpace(*request.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name must not be empty"})
+			return
+		}
+
+		if err := services.UpdateAdminName(db, admin.ID, name); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		admin.Name = name
+	}
+
+	response := gin.H{"message": "Profile updated successfully", "admin": admin}
+
+	if request.Email != nil && *request.Email != admin.Email {
+		email := *request.Email
+
+		token, err := services.RequestEmailChange(db, admin, email)
+		if errors.Is(err, services.ErrEmailTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := services.SendVerificationEmail(email, admin.Name, token); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+			return
+		}
+
+		if err := services.SendEmailChangeNotice(admin.Email, admin.Name, email); err != nil {
+			log.Printf("Failed to send email change notice: %v", err)
+		}
+
+		response["message"] = "Profile updated successfully, please verify your new email address"
+		response["pendingEmail"] = email
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteMyAccount deletes the calling admin. productPolicy decides what
+// happens to the products they created and defaults to block.
+func DeleteMyAccount(c *gin.Context, db *gorm.DB, cld *cloudinary.Cloudinary) {
+	var request struct {
+		Password          string     `json:"password"`
+		ProductPolicy     string     `json:"productPolicy"`
+		TransferToAdminID *uuid.UUID `json:"transferToAdminID"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.ProductPolicy == "" {
+		request.ProductPolicy = services.ProductPolicyBlock
+	}
+
+	if !services.IsValidProductPolicy(request.ProductPolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product policy"})
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	admin, err := services.GetAdminByID(db, principal.AdminID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin not found"})
+		return
+	}
+
+	// Single sign-on admins never had a password to confirm with
+	if admin.OIDCSubject == nil {
+		if err := services.CheckPassword(request.Password, admin.Password); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Password is incorrect"})
+			return
+		}
+	}
+
+	imageURLs, err := services.DeleteAdminAccount(db, admin, request.ProductPolicy, request.TransferToAdminID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAdminOwnsProducts):
+			c.JSON(http.StatusConflict, gin.H{"error": "Admin still owns products, transfer or delete them first"})
+		case errors.Is(err, services.ErrInvalidTransferTarget):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transfer target"})
+		case errors.Is(err, services.ErrLastOwner):
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete the last active owner"})
+		case errors.Is(err, services.ErrVariantInUse):
+			c.JSON(http.StatusConflict, gin.H{"error": "A variant is on an open order, an open purchase order or an active reservation"})
+		case errors.Is(err, services.ErrLastOrganizationMember):
+			c.JSON(http.StatusConflict, gin.H{"error": "Admin is the last member of an organization that still has products"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// The account is gone either way, so a failed image cleanup is only logged
+	for _, imageURL := range imageURLs {
+		publicImageID := services.GetPublicImageIDFromCloudinaryURL(imageURL)
+		if _, err := cld.Upload.Destroy(c.Request.Context(), uploader.DestroyParams{PublicID: publicImageID}); err != nil {
+			log.Printf("Failed to delete image %s: %v", publicImageID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
+}
+
+func GetAllAdminsWithPagination(c *gin.Context, db *gorm.DB) {
+	page := 1
+	pageSize := 10
+
+	if pageParam, exists := c.GetQuery("page"); exists {
+		parsedPage, err := strconv.Atoi(pageParam)
+		if err == nil {
+			page = parsedPage
+		}
+	}
+
+	if pageSizeParam, exists := c.GetQuery("pageSize"); exists {
+		parsedPageSize, err := strconv.Atoi(pageSizeParam)
+		if err == nil {
+			pageSize = parsedPageSize
+		}
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	admins, err := services.GetAllAdminsWithPaginationAndSearch(db, tenant, page, pageSize, c.Query("search"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, admins)
+}
+
+func DeactivateAdmin(c *gin.Context, db *gorm.DB) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Admin ID"})
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	if principal.AdminID == id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot deactivate yourself"})
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	admin, err := services.GetTenantAdminByID(db, tenant, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+		return
+	}
+
+	if err := services.DeactivateAdmin(db, admin); err != nil {
+		if errors.Is(err, services.ErrLastOwner) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot deactivate the last active owner"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Admin deactivated successfully"})
+}
+
+func ReactivateAdmin(c *gin.Context, db *gorm.DB) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Admin ID"})
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	if _, err := services.GetTenantAdminByID(db, tenant, id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+		return
+	}
+
+	if err := services.ReactivateAdmin(db, id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Admin reactivated successfully"})
+}
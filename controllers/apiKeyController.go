@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"errors"
+	"golang-final-project/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func CreateAPIKey(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Name          string   `json:"name" binding:"required"`
+		Scopes        []string `json:"scopes" binding:"required,min=1"`
+		ExpiresInDays int      `json:"expiresInDays" binding:"min=0"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, scope := range request.Scopes {
+		if !services.IsValidAPIKeyScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scope " + scope})
+			return
+		}
+	}
+
+	var expiresAt *time.Time
+	if request.ExpiresInDays > 0 {
+		expiry := time.Now().AddDate(0, 0, request.ExpiresInDays)
+		expiresAt = &expiry
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	apiKey, key, err := services.CreateAPIKey(db, principal.AdminID, request.Name, request.Scopes, expiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The plaintext key is only ever returned here
+	c.JSON(http.StatusCreated, gin.H{
+		"apiKey": apiKey,
+		"key":    key,
+	})
+}
+
+func GetMyAPIKeys(c *gin.Context, db *gorm.DB) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	apiKeys, err := services.GetAPIKeysByAdminID(db, principal.AdminID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKeys)
+}
+
+func RevokeAPIKey(c *gin.Context, db *gorm.DB) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	err = services.RevokeAPIKey(db, principal.AdminID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
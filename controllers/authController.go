@@ -1,11 +1,17 @@
 package controllers
 
 import (
+	"errors"
 	"golang-final-project/models"
 	"golang-final-project/services"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -15,6 +21,7 @@ func Register(c *gin.Context, db *gorm.DB) {
 		Email         string `json:"email" binding:"required"`
 		Password      string `json:"password" binding:"required"`
 		PasswordCheck string `json:"passwordCheck" binding:"required"`
+		InviteToken   string `json:"inviteToken"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -27,6 +34,35 @@ func Register(c *gin.Context, db *gorm.DB) {
 		return
 	}
 
+	if errs := services.PasswordPolicyFromEnv().Validate("password", request.Password, request.Email, request.Name); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password does not meet the password policy", "errors": errs})
+		return
+	}
+
+	adminCount, err := services.CountAdmins(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var invitation *models.Invitation
+	if request.InviteToken != "" {
+		invitation, err = services.GetPendingInvitation(db, request.InviteToken, request.Email)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invitation"})
+			return
+		}
+	}
+
+	mode := services.RegistrationMode()
+
+	// The first admin of a fresh installation can always register, otherwise
+	// nobody could send the first invitation
+	if mode == services.RegistrationInviteOnly && invitation == nil && adminCount > 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Registration requires an invitation"})
+		return
+	}
+
 	encryptedPassword, err := services.EncryptPassword(request.Password)
 
 	if err != nil {
@@ -37,8 +73,24 @@ func Register(c *gin.Context, db *gorm.DB) {
 		Name:     request.Name,
 		Email:    request.Email,
 		Password: encryptedPassword,
+		Role:     services.RoleEditor,
+	}
+
+	// The first admin of a fresh installation owns it
+	if adminCount == 0 {
+		admin.Role = services.RoleOwner
+	}
+
+	// Following the invitation link proves the admin owns the mailbox
+	if invitation != nil {
+		now := time.Now()
+		admin.Role = invitation.Role
+		admin.EmailVerifiedAt = &now
 	}
 
+	needsVerification := mode == services.RegistrationVerifyEmail && invitation == nil
+	var verificationToken string
+
 	tx := db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -47,16 +99,103 @@ func Register(c *gin.Context, db *gorm.DB) {
 		}
 	}()
 
-	if err := services.CreateAdmin(db, &admin); err != nil {
+	if err := services.CreateAdmin(tx, &admin); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		panic(err)
 	}
 
+	if invitation != nil {
+		if err := services.AcceptInvitation(tx, invitation); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invitation"})
+			panic(err)
+		}
+
+		if err := services.AddOrganizationMember(tx, invitation.OrganizationID, admin.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			panic(err)
+		}
+	} else {
+		if _, err := services.CreatePersonalOrganization(tx, &admin); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			panic(err)
+		}
+	}
+
+	if needsVerification {
+		verificationToken, err = services.CreateEmailVerification(tx, &admin, admin.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			panic(err)
+		}
+	}
+
 	tx.Commit()
 
+	if needsVerification {
+		if err := services.SendVerificationEmail(admin.Email, admin.Name, verificationToken); err != nil {
+			log.Printf("Failed to send verification email to %s: %v", admin.Email, err)
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"message": "Admin created successfully, please verify your email"})
+		return
+	}
+
 	c.JSON(http.StatusCreated, gin.H{"message": "Admin created successfully"})
 }
 
+func VerifyEmail(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := services.VerifyEmail(db, request.Token); err != nil {
+		if errors.Is(err, services.ErrInvalidVerificationToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification token"})
+			return
+		}
+		if errors.Is(err, services.ErrEmailTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+func ResendVerificationEmail(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Email string `json:"email" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Respond the same way whether or not the email is registered
+	admin, err := services.GetAdminByEmail(db, request.Email)
+	if err == nil && admin.EmailVerifiedAt == nil {
+		token, err := services.CreateEmailVerification(db, admin, admin.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := services.SendVerificationEmail(admin.Email, admin.Name, token); err != nil {
+			log.Printf("Failed to send verification email to %s: %v", admin.Email, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the email is registered and unverified, a verification email has been sent"})
+}
+
 func Login(c *gin.Context, db *gorm.DB) {
 	var request struct {
 		Email    string `json:"email" binding:"required"`
@@ -68,25 +207,419 @@ func Login(c *gin.Context, db *gorm.DB) {
 		return
 	}
 
+	ip := c.ClientIP()
+
 	admin, err := services.GetAdminByEmail(db, request.Email)
+	var adminID *uuid.UUID
+	if err == nil {
+		adminID = &admin.ID
+	}
+
+	// The attempt counts as a failure until the password checks out
+	attempt, retryAfter, throttleErr := services.BeginLoginAttempt(request.Email, ip, adminID)
+	if throttleErr != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": throttleErr.Error()})
+		return
+	}
+
+	if retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts, try again later"})
+		return
+	}
 
 	if err != nil {
+		services.CheckDummyPassword(request.Password)
+		loginAttemptFailed(db, attempt)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
 	if err := services.CheckPassword(request.Password, admin.Password); err != nil {
+		loginAttemptFailed(db, attempt)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		return
+	}
+
+	if err := services.LoginAttemptSucceeded(request.Email, ip); err != nil {
+		log.Printf("Failed to reset login failures: %v", err)
+	}
+
+	if admin.DeactivatedAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is deactivated"})
+		return
+	}
+
+	if services.RegistrationMode() == services.RegistrationVerifyEmail && admin.EmailVerifiedAt == nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Email not verified"})
+		return
+	}
+
+	if admin.MFAEnabledAt != nil {
+		mfaToken, err := services.GenerateMFAPendingToken(admin)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Generate token failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"adminID":     admin.ID,
+			"mfaRequired": true,
+			"mfaToken":    mfaToken,
+		})
+		return
+	}
+
+	token, refreshToken, err := services.IssueSession(db, admin, services.AuthMethodPassword)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Generate token failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"adminID":      admin.ID,
+		"token":        token,
+		"refreshToken": refreshToken,
+	})
+}
+
+func loginAttemptFailed(db *gorm.DB, attempt services.LoginAttempt) {
+	if err := services.LoginAttemptFailed(db, attempt); err != nil {
+		log.Printf("Failed to record login lockout: %v", err)
+	}
+}
+
+func Refresh(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		RefreshToken string `json:"refreshToken" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	token, err := services.GenerateJWT(admin.ID)
+	refreshToken, newRefreshToken, err := services.RotateRefreshToken(db, request.RefreshToken)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	admin, err := services.GetAdminByID(db, refreshToken.AdminID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	token, err := services.IssueAccessToken(db, admin, refreshToken.AuthMethod)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Generate token failed"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"adminID": admin.ID,
-		"token":   token,
+		"adminID":      refreshToken.AdminID,
+		"token":        token,
+		"refreshToken": newRefreshToken,
 	})
 }
+
+func Logout(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+
+	// The body is optional; an access token alone is enough to log out
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	if err := services.TokenRevocations.RevokeToken(principal.TokenID, principal.TokenExpiresAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.RefreshToken != "" {
+		err := services.RevokeRefreshToken(db, principal.AdminID, request.RefreshToken)
+		if err != nil && !errors.Is(err, services.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+func LogoutAll(c *gin.Context, db *gorm.DB) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	if err := services.RevokeAdminSessions(db, principal.AdminID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out from all sessions successfully"})
+}
+
+func CreateInvitation(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Email          string     `json:"email" binding:"required,email"`
+		Role           string     `json:"role"`
+		OrganizationID *uuid.UUID `json:"organizationID"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.Role == "" {
+		request.Role = services.RoleEditor
+	}
+
+	if !services.IsValidRole(request.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	inviter, err := services.GetAdminByID(db, principal.AdminID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin not found"})
+		return
+	}
+
+	var organizationID uuid.UUID
+	if request.OrganizationID != nil {
+		organizationID = *request.OrganizationID
+	} else {
+		organizationID, err = services.GetDefaultOrganizationID(db, inviter.ID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Admin does not belong to any organization"})
+			return
+		}
+	}
+
+	isMember, err := services.IsOrganizationMember(db, organizationID, inviter.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin is not a member of this organization"})
+		return
+	}
+
+	if _, err := services.GetAdminByEmail(db, request.Email); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		return
+	}
+
+	invitation := models.Invitation{
+		Email:          request.Email,
+		Role:           request.Role,
+		OrganizationID: organizationID,
+		InvitedByID:    inviter.ID,
+	}
+
+	token, err := services.CreateInvitation(db, &invitation)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.SendInvitationEmail(invitation.Email, inviter.Name, token); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send invitation email"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+func ForgotPassword(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Email string `json:"email" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Respond the same way whether or not the email is registered
+	admin, err := services.GetAdminByEmail(db, request.Email)
+	if err == nil {
+		token, err := services.CreatePasswordReset(db, admin)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := services.SendPasswordResetEmail(admin.Email, admin.Name, token); err != nil {
+			log.Printf("Failed to send password reset email to %s: %v", admin.Email, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the email is registered, a password reset email has been sent"})
+}
+
+func ResetPassword(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Token         string `json:"token" binding:"required"`
+		Password      string `json:"password" binding:"required"`
+		PasswordCheck string `json:"passwordCheck" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.Password != request.PasswordCheck {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password not match"})
+		return
+	}
+
+	admin, err := services.GetPasswordResetAdmin(db, request.Token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPasswordResetToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired password reset token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if errs := services.PasswordPolicyFromEnv().Validate("password", request.Password, admin.Email, admin.Name); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password does not meet the password policy", "errors": errs})
+		return
+	}
+
+	if _, err := services.ResetPassword(db, request.Token, request.Password); err != nil {
+		if errors.Is(err, services.ErrInvalidPasswordResetToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired password reset token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+func ChangePassword(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		CurrentPassword  string `json:"currentPassword" binding:"required"`
+		NewPassword      string `json:"newPassword" binding:"required"`
+		NewPasswordCheck string `json:"newPasswordCheck" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.NewPassword != request.NewPasswordCheck {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password not match"})
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	admin, err := services.GetAdminByID(db, principal.AdminID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin not found"})
+		return
+	}
+
+	if err := services.CheckPassword(request.CurrentPassword, admin.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Current password is incorrect", "errors": []services.FieldError{
+			{Field: "currentPassword", Code: "incorrect", Message: "Current password is incorrect"},
+		}})
+		return
+	}
+
+	errs := services.PasswordPolicyFromEnv().Validate("newPassword", request.NewPassword, admin.Email, admin.Name)
+	if request.NewPassword == request.CurrentPassword {
+		errs = append(errs, services.FieldError{Field: "newPassword", Code: "unchanged", Message: "New password must differ from the current password"})
+	}
+	if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password does not meet the password policy", "errors": errs})
+		return
+	}
+
+	encryptedPassword, err := services.EncryptPassword(request.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Sign out every other session and hand this client fresh tokens
+	if err := services.ChangeAdminPassword(db, admin.ID, encryptedPassword); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, refreshToken, err := services.IssueSession(db, admin, principal.AuthMethod)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Generate token failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Password changed successfully",
+		"token":        token,
+		"refreshToken": refreshToken,
+	})
+}
+
+// currentPrincipal returns the authenticated principal, or responds with 401
+// when the route was not behind an authentication middleware.
+func currentPrincipal(c *gin.Context) (*services.Principal, bool) {
+	principal, err := services.PrincipalFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return nil, false
+	}
+
+	return principal, true
+}
+
+// currentTenant is currentPrincipal for tenant-scoped queries. A rejected
+// cross-tenant request gets a 403.
+func currentTenant(c *gin.Context) (services.Tenant, bool) {
+	tenant, err := services.TenantFromContext(c)
+	if errors.Is(err, services.ErrNoPrincipal) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return services.Tenant{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return services.Tenant{}, false
+	}
+
+	return tenant, true
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"golang-final-project/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func CreateCustomerGroup(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Name           string     `json:"name" binding:"required,max=255"`
+		Code           string     `json:"code" binding:"required,max=50"`
+		OrganizationID *uuid.UUID `json:"organizationID"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	organizationID, ok := targetOrganizationID(c, db, request.OrganizationID)
+	if !ok {
+		return
+	}
+
+	group := models.CustomerGroup{
+		OrganizationID: organizationID,
+		Name:           request.Name,
+		Code:           request.Code,
+	}
+
+	if err := services.CreateCustomerGroup(db, &group); err != nil {
+		if errors.Is(err, services.ErrCustomerGroupCodeConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Customer group code is already in use"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+func GetAllCustomerGroups(c *gin.Context, db *gorm.DB) {
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	groups, err := services.GetAllCustomerGroups(db, tenant)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+func GetCustomerGroupByID(c *gin.Context, db *gorm.DB) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Customer Group ID"})
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	group, err := services.GetCustomerGroupByID(db, tenant, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer group not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+func UpdateCustomerGroupByID(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Name string `json:"name" binding:"required,max=255"`
+		Code string `json:"code" binding:"required,max=50"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Customer Group ID"})
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	err = services.UpdateCustomerGroupByID(db, tenant, id, request.Name, request.Code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer group not found"})
+		return
+	}
+	if errors.Is(err, services.ErrCustomerGroupCodeConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Customer group code is already in use"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Customer group updated successfully"})
+}
+
+func DeleteCustomerGroupByID(c *gin.Context, db *gorm.DB) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Customer Group ID"})
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	err = services.DeleteCustomerGroupByID(db, tenant, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer group not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Customer group deleted successfully"})
+}
@@ -0,0 +1,15 @@
+package controllers
+
+import (
+	"golang-final-project/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetJWKS(c *gin.Context) {
+	// Verifiers should refetch when they meet an unknown kid right after a
+	// rotation, so a short cache is enough
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, services.Keys.JWKS())
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"golang-final-project/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func CreateLocation(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Name           string     `json:"name" binding:"required,max=255"`
+		Code           string     `json:"code" binding:"required,max=50"`
+		OrganizationID *uuid.UUID `json:"organizationID"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	organizationID, ok := targetOrganizationID(c, db, request.OrganizationID)
+	if !ok {
+		return
+	}
+
+	// The default location is created on first use, so that code stays
+	// reserved for it
+	if _, err := services.EnsureDefaultLocation(db, organizationID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	location := models.Location{
+		OrganizationID: organizationID,
+		Name:           request.Name,
+		Code:           request.Code,
+	}
+
+	if err := services.CreateLocation(db, &location); err != nil {
+		if errors.Is(err, services.ErrLocationCodeConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Location code is already in use"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, location)
+}
+
+func GetAllLocations(c *gin.Context, db *gorm.DB) {
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	locations, err := services.GetAllLocations(db, tenant)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, locations)
+}
+
+func GetLocationByID(c *gin.Context, db *gorm.DB) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Location ID"})
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	location, err := services.GetLocationByID(db, tenant, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Location not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, location)
+}
+
+func UpdateLocationByID(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Name string `json:"name" binding:"required,max=255"`
+		Code string `json:"code" binding:"required,max=50"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Location ID"})
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	err = services.UpdateLocationByID(db, tenant, id, request.Name, request.Code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Location not found"})
+		return
+	}
+	if errors.Is(err, services.ErrLocationCodeConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Location code is already in use"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Location updated successfully"})
+}
+
+func DeleteLocationByID(c *gin.Context, db *gorm.DB) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Location ID"})
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	err = services.DeleteLocationByID(db, tenant, id)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Location not found"})
+	case errors.Is(err, services.ErrDefaultLocation):
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete the default location"})
+	case errors.Is(err, services.ErrLocationNotEmpty):
+		c.JSON(http.StatusConflict, gin.H{"error": "Location still holds stock, transfer it first"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Location deleted successfully"})
+	}
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"errors"
+	"golang-final-project/services"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func EnrollTOTP(c *gin.Context, db *gorm.DB) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	admin, err := services.GetAdminByID(db, principal.AdminID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin not found"})
+		return
+	}
+
+	secret, uri, err := services.StartTOTPEnrollment(db, admin)
+	if errors.Is(err, services.ErrMFAAlreadyEnabled) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Multi-factor authentication is already enabled"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":     secret,
+		"otpauthURI": uri,
+	})
+}
+
+func ConfirmTOTP(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	admin, err := services.GetAdminByID(db, principal.AdminID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin not found"})
+		return
+	}
+
+	recoveryCodes, err := services.ConfirmTOTPEnrollment(db, admin, request.Code)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Multi-factor authentication enabled successfully",
+		"recoveryCodes": recoveryCodes,
+	})
+}
+
+// VerifyMFA is the second login step: it exchanges the mfa pending token from
+// Login plus a TOTP or recovery code for the real tokens.
+func VerifyMFA(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		MFAToken     string `json:"mfaToken" binding:"required"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recoveryCode"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.Code == "" && request.RecoveryCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Code or recovery code is required"})
+		return
+	}
+
+	claims, err := services.ParseMFAPendingToken(request.MFAToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired mfa token"})
+		return
+	}
+
+	admin, err := services.GetAdminByID(db, claims.AdminID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired mfa token"})
+		return
+	}
+
+	// Codes are short, so guesses count towards the same lockout as passwords
+	ip := c.ClientIP()
+
+	attempt, retryAfter, err := services.BeginLoginAttempt(admin.Email, ip, &admin.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts, try again later"})
+		return
+	}
+
+	if err := services.VerifyMFACode(db, admin, request.Code, request.RecoveryCode); err != nil {
+		// Only wrong codes stay counted as failures
+		if errors.Is(err, services.ErrInvalidMFACode) {
+			loginAttemptFailed(db, attempt)
+		} else if err := services.CancelLoginAttempt(admin.Email, ip); err != nil {
+			log.Printf("Failed to cancel login attempt: %v", err)
+		}
+		respondMFAError(c, err)
+		return
+	}
+
+	if err := services.LoginAttemptSucceeded(admin.Email, ip); err != nil {
+		log.Printf("Failed to reset login failures: %v", err)
+	}
+
+	token, refreshToken, err := services.IssueSession(db, admin, services.AuthMethodMFA)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Generate token failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"adminID":      admin.ID,
+		"token":        token,
+		"refreshToken": refreshToken,
+	})
+}
+
+func RegenerateRecoveryCodes(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	admin, err := services.GetAdminByID(db, principal.AdminID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin not found"})
+		return
+	}
+
+	recoveryCodes, err := services.RegenerateRecoveryCodes(db, admin, request.Code)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": recoveryCodes})
+}
+
+func DisableMFA(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	admin, err := services.GetAdminByID(db, principal.AdminID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin not found"})
+		return
+	}
+
+	if err := services.DisableMFA(db, admin, request.Code); err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Multi-factor authentication disabled successfully"})
+}
+
+func respondMFAError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidMFACode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authentication code"})
+	case errors.Is(err, services.ErrMFAAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": "Multi-factor authentication is already enabled"})
+	case errors.Is(err, services.ErrMFANotEnrolled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Multi-factor authentication is not enrolled"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"golang-final-project/models"
+	"golang-final-project/services"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// oidcStateCookie ties the login state to the browser that started the
+// login, so nobody can complete their own login in someone else's browser.
+const oidcStateCookie = "oidc_state"
+
+// OIDCLogin returns the identity provider URL the browser should be sent to.
+func OIDCLogin(c *gin.Context, db *gorm.DB) {
+	if services.OIDC == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not configured"})
+		return
+	}
+
+	authorizationURL, state, err := services.OIDC.AuthorizationURL(db)
+	if err != nil {
+		log.Printf("Failed to start OIDC login: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, int((10 * time.Minute).Seconds()), "/api/auth/oidc", "", c.Request.TLS != nil, true)
+
+	c.JSON(http.StatusOK, gin.H{"authorizationURL": authorizationURL})
+}
+
+// OIDCCallback redeems the code the identity provider redirected back with
+// and issues our own tokens for the linked admin.
+func OIDCCallback(c *gin.Context, db *gorm.DB) {
+	if services.OIDC == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not configured"})
+		return
+	}
+
+	var request struct {
+		Code  string `json:"code" binding:"required"`
+		State string `json:"state" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cookieState, _ := c.Cookie(oidcStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, "/api/auth/oidc", "", c.Request.TLS != nil, true)
+
+	if cookieState == "" || subtle.ConstantTimeCompare([]byte(cookieState), []byte(request.State)) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired login state"})
+		return
+	}
+
+	identity, err := services.OIDC.Exchange(db, request.Code, request.State)
+	if errors.Is(err, services.ErrInvalidOIDCState) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired login state"})
+		return
+	}
+	if err != nil {
+		log.Printf("OIDC code exchange failed: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Single sign-on failed"})
+		return
+	}
+
+	admin, err := services.ResolveOIDCAdmin(db, services.OIDC, identity)
+	var linkRequired *services.OIDCLinkRequiredError
+	if errors.As(err, &linkRequired) {
+		linkToken, err := services.GenerateOIDCLinkToken(linkRequired.AdminID, identity.Subject)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Generate token failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"linkRequired": true,
+			"linkToken":    linkToken,
+		})
+		return
+	}
+	if errors.Is(err, services.ErrOIDCEmailUnverified) || errors.Is(err, services.ErrOIDCAdminNotFound) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "No admin account is linked to this identity"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// A second factor at the identity provider only counts as one here when
+	// OIDC_TRUST_MFA is on
+	authMethod := services.AuthMethodOIDC
+	if identity.MFA {
+		authMethod = services.AuthMethodMFA
+	}
+
+	respondOIDCSession(c, db, admin, authMethod)
+}
+
+// OIDCLink links the identity from the callback to the existing admin with
+// the same email once they confirm it with their password. Password guesses
+// count towards the login lockout.
+func OIDCLink(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		LinkToken string `json:"linkToken" binding:"required"`
+		Password  string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := services.ParseOIDCLinkToken(request.LinkToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired link token"})
+		return
+	}
+
+	admin, err := services.GetAdminByID(db, claims.AdminID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired link token"})
+		return
+	}
+
+	ip := c.ClientIP()
+
+	attempt, retryAfter, err := services.BeginLoginAttempt(admin.Email, ip, &admin.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts, try again later"})
+		return
+	}
+
+	if err := services.CheckPassword(request.Password, admin.Password); err != nil {
+		loginAttemptFailed(db, attempt)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		return
+	}
+
+	if err := services.LoginAttemptSucceeded(admin.Email, ip); err != nil {
+		log.Printf("Failed to reset login failures: %v", err)
+	}
+
+	if admin.DeactivatedAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is deactivated"})
+		return
+	}
+
+	if err := services.LinkOIDCSubject(db, admin.ID, claims.Subject); err != nil {
+		if errors.Is(err, services.ErrOIDCSubjectAlreadyUsed) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The account or the identity is already linked"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondOIDCSession(c, db, admin, services.AuthMethodPassword)
+}
+
+// respondOIDCSession issues tokens for an admin who signed in through the
+// identity provider, or asks for the second factor first when the sign in did
+// not include one.
+func respondOIDCSession(c *gin.Context, db *gorm.DB, admin *models.Admin, authMethod string) {
+	if admin.DeactivatedAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is deactivated"})
+		return
+	}
+
+	if admin.MFAEnabledAt != nil && authMethod != services.AuthMethodMFA {
+		mfaToken, err := services.GenerateMFAPendingToken(admin)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Generate token failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"adminID":     admin.ID,
+			"mfaRequired": true,
+			"mfaToken":    mfaToken,
+		})
+		return
+	}
+
+	token, refreshToken, err := services.IssueSession(db, admin, authMethod)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Generate token failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"adminID":      admin.ID,
+		"token":        token,
+		"refreshToken": refreshToken,
+	})
+}
@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"golang-final-project/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreateOrder creates a pending order. Stock is taken from the given
+// location, or from the organization's default location. Lines without a
+// unit price are charged the variant's current price for the customer group.
+func CreateOrder(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		OrganizationID  *uuid.UUID `json:"organizationID"`
+		LocationID      *uuid.UUID `json:"locationID"`
+		Reference       string     `json:"reference" binding:"max=255"`
+		Note            string     `json:"note" binding:"max=255"`
+		Currency        string     `json:"currency" binding:"required,iso4217"`
+		CustomerGroupID *uuid.UUID `json:"customerGroupID"`
+		Lines           []struct {
+			VariantID uuid.UUID `json:"variantID" binding:"required"`
+			Quantity  int       `json:"quantity" binding:"required,gt=0"`
+			UnitPrice *int64    `json:"unitPrice" binding:"omitempty,gte=0"`
+		} `json:"lines" binding:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	organizationID, ok := targetOrganizationID(c, db, request.OrganizationID)
+	if !ok {
+		return
+	}
+
+	order := models.Order{
+		OrganizationID:  organizationID,
+		Reference:       request.Reference,
+		Note:            request.Note,
+		Currency:        request.Currency,
+		CustomerGroupID: request.CustomerGroupID,
+		CreatedByID:     principal.AdminID,
+	}
+
+	if request.LocationID != nil {
+		tenant, ok := currentTenant(c)
+		if !ok {
+			return
+		}
+
+		location, err := services.GetLocationByID(db, tenant, *request.LocationID)
+		if err != nil || location.OrganizationID != organizationID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Location does not belong to this organization"})
+			return
+		}
+		order.LocationID = location.ID
+	} else {
+		locationID, err := services.EnsureDefaultLocation(db, organizationID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		order.LocationID = locationID
+	}
+
+	lines := make([]services.OrderLineInput, 0, len(request.Lines))
+	for _, line := range request.Lines {
+		lines = append(lines, services.OrderLineInput{VariantID: line.VariantID, Quantity: line.Quantity, UnitPrice: line.UnitPrice})
+	}
+
+	if err := services.CreateOrder(db, &order, lines); err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, order)
+}
+
+func GetAllOrders(c *gin.Context, db *gorm.DB) {
+	page := 1
+	pageSize := 10
+
+	if pageParam, exists := c.GetQuery("page"); exists {
+		parsedPage, err := strconv.Atoi(pageParam)
+		if err == nil && parsedPage > 0 {
+			page = parsedPage
+		}
+	}
+
+	if pageSizeParam, exists := c.GetQuery("pageSize"); exists {
+		parsedPageSize, err := strconv.Atoi(pageSizeParam)
+		if err == nil && parsedPageSize > 0 {
+			pageSize = parsedPageSize
+		}
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	orders, err := services.GetAllOrders(db, tenant, c.Query("status"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+func GetOrderByID(c *gin.Context, db *gorm.DB) {
+	order, ok := orderForMember(c, db)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+func ConfirmOrder(c *gin.Context, db *gorm.DB) {
+	changeOrder(c, db, services.ConfirmOrder)
+}
+
+func FulfilOrder(c *gin.Context, db *gorm.DB) {
+	changeOrder(c, db, services.FulfilOrder)
+}
+
+func CancelOrder(c *gin.Context, db *gorm.DB) {
+	changeOrder(c, db, services.CancelOrder)
+}
+
+// changeOrder runs a status change on the order and responds with the
+// updated order.
+func changeOrder(c *gin.Context, db *gorm.DB, change func(*gorm.DB, uuid.UUID, services.StockMovementInput) error) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	order, ok := orderForMember(c, db)
+	if !ok {
+		return
+	}
+
+	err := change(db, order.ID, services.StockMovementInput{
+		ActorID:    &principal.AdminID,
+		AuthMethod: principal.AuthMethod,
+	})
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	order, err = services.GetOrderByID(db, tenant, order.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+func orderForMember(c *gin.Context, db *gorm.DB) (*models.Order, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Order ID"})
+		return nil, false
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return nil, false
+	}
+
+	order, err := services.GetOrderByID(db, tenant, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return nil, false
+	}
+
+	return order, true
+}
+
+func respondOrderError(c *gin.Context, err error) {
+	var lineErr *services.OrderLineError
+	var insufficient *services.InsufficientStockError
+
+	switch {
+	case errors.As(err, &lineErr) && errors.As(err, &insufficient):
+		c.JSON(http.StatusConflict, gin.H{"error": "Insufficient stock", "variantID": lineErr.VariantID, "quantity": insufficient.Available})
+	case errors.Is(err, services.ErrOrderNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": "Order is not pending"})
+	case errors.Is(err, services.ErrOrderNotFulfillable):
+		c.JSON(http.StatusConflict, gin.H{"error": "Order can not be fulfilled"})
+	case errors.Is(err, services.ErrOrderNotCancellable):
+		c.JSON(http.StatusConflict, gin.H{"error": "Order can no longer be cancelled"})
+	case errors.As(err, &lineErr) && errors.Is(err, services.ErrVariantNotPriced):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Variant has no price, give the line a unit price", "variantID": lineErr.VariantID})
+	case errors.Is(err, services.ErrCurrencyMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Variant is priced in another currency than the order"})
+	case errors.Is(err, services.ErrInvalidCustomerGroup):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Customer group does not belong to the order's organization"})
+	case errors.Is(err, services.ErrInvalidOrderLines):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Order lines need distinct variants, positive quantities and non-negative prices"})
+	case errors.Is(err, services.ErrVariantNotInOrganization):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Variant does not belong to the order's organization"})
+	default:
+		respondStockError(c, err)
+	}
+}
@@ -0,0 +1,309 @@
+package controllers
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"golang-final-project/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func CreateOrganization(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Name string `json:"name" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	organization := models.Organization{Name: request.Name}
+
+	if err := services.CreateOrganization(db, &organization, principal.AdminID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, organization)
+}
+
+func GetMyOrganizations(c *gin.Context, db *gorm.DB) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	organizations, err := services.GetOrganizationsByAdminID(db, principal.AdminID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, organizations)
+}
+
+func GetOrganizationByID(c *gin.Context, db *gorm.DB) {
+	id, ok := organizationIDForMember(c, db)
+	if !ok {
+		return
+	}
+
+	organization, err := services.GetOrganizationByID(db, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, organization)
+}
+
+// AddOrganizationMember invites a registered admin to the organization. They
+// only become a member once they accept, see AcceptOrganizationInvitation.
+func AddOrganizationMember(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Email string `json:"email" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, ok := organizationIDForMember(c, db)
+	if !ok {
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	inviter, err := services.GetAdminByID(db, principal.AdminID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin not found"})
+		return
+	}
+
+	organization, err := services.GetOrganizationByID(db, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	admin, err := services.GetAdminByEmail(db, request.Email)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+		return
+	}
+
+	isMember, err := services.IsOrganizationMember(db, id, admin.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if isMember {
+		c.JSON(http.StatusConflict, gin.H{"error": "Admin is already a member"})
+		return
+	}
+
+	// Joining does not change the admin's role, the invitation just records it
+	invitation := models.Invitation{
+		Email:          admin.Email,
+		Role:           admin.Role,
+		OrganizationID: id,
+		InvitedByID:    inviter.ID,
+	}
+
+	token, err := services.CreateInvitation(db, &invitation)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.SendOrganizationInvitationEmail(invitation.Email, inviter.Name, organization.Name, token); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send invitation email"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+// AcceptOrganizationInvitation adds the signed in admin to the organization
+// they were invited to.
+func AcceptOrganizationInvitation(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	admin, err := services.GetAdminByID(db, principal.AdminID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin not found"})
+		return
+	}
+
+	invitation, err := services.AcceptOrganizationInvitation(db, request.Token, admin)
+	if errors.Is(err, services.ErrInvalidInvitation) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired invitation"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation accepted", "organizationID": invitation.OrganizationID})
+}
+
+func RemoveOrganizationMember(c *gin.Context, db *gorm.DB) {
+	id, ok := organizationIDForMember(c, db)
+	if !ok {
+		return
+	}
+
+	adminID, err := uuid.Parse(c.Param("adminID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Admin ID"})
+		return
+	}
+
+	err = services.RemoveOrganizationMember(db, id, adminID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin is not a member of this organization"})
+		return
+	}
+	if errors.Is(err, services.ErrLastOrganizationMember) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot remove the last member of an organization"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+// UpdateOrganizationStockAlerts sets the webhook and email addresses the
+// organization's stock alerts are sent to.
+func UpdateOrganizationStockAlerts(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		WebhookURL    string `json:"webhookURL" binding:"max=255"`
+		WebhookSecret string `json:"webhookSecret" binding:"max=255"`
+		EmailTo       string `json:"emailTo" binding:"max=1000"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, ok := organizationIDForMember(c, db)
+	if !ok {
+		return
+	}
+
+	err := services.UpdateOrganizationStockAlerts(db, id, request.WebhookURL, request.WebhookSecret, request.EmailTo)
+	if errors.Is(err, services.ErrInvalidWebhookURL) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Webhook URL must be an absolute http or https URL"})
+		return
+	}
+	if errors.Is(err, services.ErrWebhookAddressNotAllowed) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Webhook URL must point to a public address"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Stock alert settings updated successfully"})
+}
+
+// organizationIDForMember parses the :id param and checks that the calling
+// admin belongs to that organization, writing the error response otherwise.
+func organizationIDForMember(c *gin.Context, db *gorm.DB) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Organization ID"})
+		return uuid.Nil, false
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	isMember, err := services.IsOrganizationMember(db, id, principal.AdminID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return uuid.Nil, false
+	}
+
+	if !isMember {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
+
+// targetOrganizationID picks the organization a new record belongs to: the
+// requested one, or else the principal's organization or the admin's default
+// one. The admin must be able to access it.
+func targetOrganizationID(c *gin.Context, db *gorm.DB, requested *uuid.UUID) (uuid.UUID, bool) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	organizationID := principal.OrganizationID
+	if requested != nil {
+		organizationID = *requested
+	} else if organizationID == uuid.Nil {
+		defaultOrganizationID, err := services.GetDefaultOrganizationID(db, principal.AdminID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Admin does not belong to any organization"})
+			return uuid.Nil, false
+		}
+		organizationID = defaultOrganizationID
+	}
+
+	isMember, err := tenant.CanAccess(db, organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return uuid.Nil, false
+	}
+
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin is not a member of this organization"})
+		return uuid.Nil, false
+	}
+
+	return organizationID, true
+}
@@ -0,0 +1,256 @@
+package controllers
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"golang-final-project/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type priceListRequest struct {
+	Name             string      `json:"name" binding:"required,max=255"`
+	Currency         string      `json:"currency" binding:"required,iso4217"`
+	Priority         int         `json:"priority"`
+	StartsAt         *time.Time  `json:"startsAt"`
+	EndsAt           *time.Time  `json:"endsAt"`
+	CustomerGroupIDs []uuid.UUID `json:"customerGroupIDs" binding:"unique"`
+}
+
+func (request priceListRequest) priceList() *models.PriceList {
+	return &models.PriceList{
+		Name:     request.Name,
+		Currency: request.Currency,
+		Priority: request.Priority,
+		StartsAt: request.StartsAt,
+		EndsAt:   request.EndsAt,
+	}
+}
+
+func CreatePriceList(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		priceListRequest
+		OrganizationID *uuid.UUID `json:"organizationID"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	organizationID, ok := targetOrganizationID(c, db, request.OrganizationID)
+	if !ok {
+		return
+	}
+
+	priceList := request.priceList()
+	priceList.OrganizationID = organizationID
+
+	if err := services.CreatePriceList(db, priceList, request.CustomerGroupIDs); err != nil {
+		respondPriceListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, priceList)
+}
+
+func GetAllPriceLists(c *gin.Context, db *gorm.DB) {
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	priceLists, err := services.GetAllPriceLists(db, tenant)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, priceLists)
+}
+
+func GetPriceListByID(c *gin.Context, db *gorm.DB) {
+	priceList, ok := priceListForMember(c, db)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, priceList)
+}
+
+func UpdatePriceList(c *gin.Context, db *gorm.DB) {
+	var request priceListRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	priceList, ok := priceListForMember(c, db)
+	if !ok {
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	if err := services.UpdatePriceList(db, tenant, priceList.ID, request.priceList(), request.CustomerGroupIDs); err != nil {
+		respondPriceListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Price list updated successfully"})
+}
+
+func DeletePriceList(c *gin.Context, db *gorm.DB) {
+	priceList, ok := priceListForMember(c, db)
+	if !ok {
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	if err := services.DeletePriceListByID(db, tenant, priceList.ID); err != nil {
+		respondPriceListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Price list deleted successfully"})
+}
+
+// SetPriceListPrices replaces all quantity-break tiers of the price list.
+func SetPriceListPrices(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Prices []struct {
+			VariantID   uuid.UUID `json:"variantID" binding:"required"`
+			MinQuantity int       `json:"minQuantity" binding:"required,gte=1"`
+			Amount      int64     `json:"amount"`
+		} `json:"prices" binding:"dive"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	priceList, ok := priceListForMember(c, db)
+	if !ok {
+		return
+	}
+
+	tiers := make([]services.PriceTierInput, 0, len(request.Prices))
+	for _, price := range request.Prices {
+		tiers = append(tiers, services.PriceTierInput{VariantID: price.VariantID, MinQuantity: price.MinQuantity, Amount: price.Amount})
+	}
+
+	if err := services.SetPriceListPrices(db, priceList.ID, tiers); err != nil {
+		respondPriceListError(c, err)
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	priceList, err := services.GetPriceListByID(db, tenant, priceList.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, priceList)
+}
+
+// ResolveVariantPrice returns the unit price of the variant for the quantity
+// and customer group in the query, with every rule that was considered.
+func ResolveVariantPrice(c *gin.Context, db *gorm.DB) {
+	quantity := 1
+	if quantityParam, exists := c.GetQuery("quantity"); exists {
+		parsedQuantity, err := strconv.Atoi(quantityParam)
+		if err != nil || parsedQuantity < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity must be a positive number"})
+			return
+		}
+		quantity = parsedQuantity
+	}
+
+	var customerGroupID *uuid.UUID
+	if customerGroupParam, exists := c.GetQuery("customerGroupID"); exists {
+		parsedCustomerGroupID, err := uuid.Parse(customerGroupParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Customer Group ID"})
+			return
+		}
+		customerGroupID = &parsedCustomerGroupID
+	}
+
+	variant, ok := variantForMember(c, db)
+	if !ok {
+		return
+	}
+
+	resolution, err := services.ResolveCustomerPrice(db, variant.ID, quantity, customerGroupID, time.Now())
+	if errors.Is(err, services.ErrVariantNotPriced) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Variant has no price"})
+		return
+	}
+	if err != nil {
+		respondPriceListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resolution)
+}
+
+func priceListForMember(c *gin.Context, db *gorm.DB) (*models.PriceList, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Price List ID"})
+		return nil, false
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return nil, false
+	}
+
+	priceList, err := services.GetPriceListByID(db, tenant, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Price list not found"})
+		return nil, false
+	}
+
+	return priceList, true
+}
+
+func respondPriceListError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidPriceListWindow):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A price list must end after it starts"})
+	case errors.Is(err, services.ErrInvalidPriceTiers):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Price tiers need a minimum quantity of at least one and must not repeat"})
+	case errors.Is(err, services.ErrInvalidCustomerGroup):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Customer group does not belong to this organization"})
+	case errors.Is(err, services.ErrInvalidPriceQuantity):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity must be at least one"})
+	case errors.Is(err, services.ErrVariantNotInOrganization):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Variant does not belong to the price list's organization"})
+	case errors.Is(err, services.ErrCurrencyMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Prices must be in the price list's currency"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Price list not found"})
+	default:
+		respondPriceError(c, err)
+	}
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"errors"
+	"golang-final-project/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Amounts are integer minor units, e.g. cents, of the currency.
+type priceRequest struct {
+	Currency       string     `json:"currency" binding:"omitempty,iso4217"`
+	Price          *int64     `json:"price"`
+	CompareAtPrice *int64     `json:"compareAtPrice"`
+	SalePrice      *int64     `json:"salePrice"`
+	SaleStartsAt   *time.Time `json:"saleStartsAt"`
+	SaleEndsAt     *time.Time `json:"saleEndsAt"`
+}
+
+func (request priceRequest) input() services.PriceInput {
+	return services.PriceInput{
+		Currency:       request.Currency,
+		Price:          request.Price,
+		CompareAtPrice: request.CompareAtPrice,
+		SalePrice:      request.SalePrice,
+		SaleStartsAt:   request.SaleStartsAt,
+		SaleEndsAt:     request.SaleEndsAt,
+	}
+}
+
+func UpdateProductPrice(c *gin.Context, db *gorm.DB) {
+	var request priceRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Product ID"})
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	product, err := services.GetProductByID(db, tenant, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	isMember, err := tenant.CanAccess(db, product.OrganizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin is not a member of this product's organization"})
+		return
+	}
+
+	if err := services.UpdateProductPrice(db, tenant, id, request.input()); err != nil {
+		respondPriceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product price updated successfully"})
+}
+
+func UpdateVariantPrice(c *gin.Context, db *gorm.DB) {
+	var request priceRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	variant, ok := variantForMember(c, db)
+	if !ok {
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	if err := services.UpdateVariantPrice(db, tenant, variant.ID, request.input()); err != nil {
+		respondPriceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Variant price updated successfully"})
+}
+
+func respondPriceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNegativePrice):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Prices must not be negative"})
+	case errors.Is(err, services.ErrInvalidCurrency):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid currency"})
+	case errors.Is(err, services.ErrCurrencyRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A currency is required with a price"})
+	case errors.Is(err, services.ErrCurrencyMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Prices must be in the product's currency"})
+	case errors.Is(err, services.ErrInvalidSaleWindow):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A sale must end after it starts"})
+	case errors.Is(err, services.ErrSaleWithoutPrice):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A sale price needs a regular price"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"golang-final-project/models"
 	"golang-final-project/services"
 	"net/http"
@@ -15,8 +16,9 @@ import (
 
 func CreateProduct(c *gin.Context, db *gorm.DB, cld *cloudinary.Cloudinary) {
 	var request struct {
-		Name     string `json:"name" binding:"required"`
-		ImageUrl string `json:"imageUrl" binding:"required"`
+		Name           string     `json:"name" binding:"required"`
+		ImageUrl       string     `json:"imageUrl" binding:"required"`
+		OrganizationID *uuid.UUID `json:"organizationID"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -24,16 +26,36 @@ func CreateProduct(c *gin.Context, db *gorm.DB, cld *cloudinary.Cloudinary) {
 		return
 	}
 
-	// Get Admin ID
-	adminIDString, err := services.ExtractAdminID(c)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error get Admin ID"})
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
 		return
 	}
 
-	adminID, err := uuid.Parse(adminIDString)
+	organizationID := principal.OrganizationID
+	if request.OrganizationID != nil {
+		organizationID = *request.OrganizationID
+	} else if organizationID == uuid.Nil {
+		defaultOrganizationID, err := services.GetDefaultOrganizationID(db, principal.AdminID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Admin does not belong to any organization"})
+			return
+		}
+		organizationID = defaultOrganizationID
+	}
+
+	isMember, err := tenant.CanAccess(db, organizationID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Admin ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin is not a member of this organization"})
 		return
 	}
 
@@ -45,9 +67,10 @@ func CreateProduct(c *gin.Context, db *gorm.DB, cld *cloudinary.Cloudinary) {
 	}
 
 	product := models.Product{
-		Name:     request.Name,
-		ImageUrl: uploadResult.SecureURL,
-		AdminID:  adminID,
+		Name:           request.Name,
+		ImageUrl:       uploadResult.SecureURL,
+		AdminID:        principal.AdminID,
+		OrganizationID: organizationID,
 	}
 
 	tx := db.Begin()
@@ -86,8 +109,13 @@ func GetAllProductsWithPagination(c *gin.Context, db *gorm.DB) {
 		}
 	}
 
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
 	searchName := c.Query("search")
-	products, err := services.GetAllProductsWithPaginationAndSearch(db, page, pageSize, searchName)
+	products, err := services.GetAllProductsWithPaginationAndSearch(db, tenant, page, pageSize, searchName)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -111,7 +139,12 @@ func GetProductByID(c *gin.Context, db *gorm.DB) {
 		return
 	}
 
-	product, err := services.GetProductByID(db, id)
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	product, err := services.GetProductByID(db, tenant, id)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -145,27 +178,25 @@ func UpdateProductByID(c *gin.Context, db *gorm.DB, cld *cloudinary.Cloudinary)
 		return
 	}
 
-	// Get Admin ID
-	adminIDString, err := services.ExtractAdminID(c)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error get Admin ID"})
+	tenant, ok := currentTenant(c)
+	if !ok {
 		return
 	}
 
-	adminID, err := uuid.Parse(adminIDString)
+	existingProduct, err := services.GetProductByID(db, tenant, id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Admin ID"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
 	}
 
-	existingProduct, err := services.GetProductByID(db, id)
+	isMember, err := tenant.CanAccess(db, existingProduct.OrganizationID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if existingProduct.AdminID != adminID {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized with this Admin ID"})
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin is not a member of this product's organization"})
 		return
 	}
 
@@ -191,7 +222,7 @@ func UpdateProductByID(c *gin.Context, db *gorm.DB, cld *cloudinary.Cloudinary)
 	existingProduct.Name = request.Name
 	existingProduct.ImageUrl = updatedImageUrl
 
-	if err := services.UpdateProductByID(db, id, existingProduct); err != nil {
+	if err := services.UpdateProductByID(db, tenant, id, existingProduct); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		panic(err)
 	}
@@ -215,31 +246,29 @@ func DeleteProductByID(c *gin.Context, db *gorm.DB, cld *cloudinary.Cloudinary)
 		return
 	}
 
-	product, err := services.GetProductByID(db, id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	tenant, ok := currentTenant(c)
+	if !ok {
 		return
 	}
 
-	// Get Admin ID
-	adminIDString, err := services.ExtractAdminID(c)
+	product, err := services.GetProductByID(db, tenant, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error get Admin ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	adminID, err := uuid.Parse(adminIDString)
+	isMember, err := tenant.CanAccess(db, product.OrganizationID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Admin ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if product.AdminID != adminID {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized with this Admin ID"})
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin is not a member of this product's organization"})
 		return
 	}
 
-	variants, _ := services.GetVariantsByProductID(db, id)
+	variants, _ := services.GetVariantsByProductID(db, tenant, id)
 	publicImageID := services.GetPublicImageIDFromCloudinaryURL(product.ImageUrl)
 
 	tx := db.Begin()
@@ -251,14 +280,19 @@ func DeleteProductByID(c *gin.Context, db *gorm.DB, cld *cloudinary.Cloudinary)
 	}()
 
 	if variants != nil {
-		err := services.DeleteVariantsByProductID(db, id)
+		err := services.DeleteVariantsByProductID(db, tenant, id)
+		if errors.Is(err, services.ErrVariantInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A variant is on an open order, an open purchase order or an active reservation"})
+			tx.Rollback()
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Failed to delete Variant"})
 			panic(err)
 		}
 	}
 
-	err = services.DeleteProductByID(db, id)
+	err = services.DeleteProductByID(db, tenant, id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		panic(err)
@@ -0,0 +1,313 @@
+package controllers
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"golang-final-project/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type purchaseOrderLineRequest struct {
+	VariantID uuid.UUID `json:"variantID" binding:"required"`
+	Quantity  int       `json:"quantity" binding:"required,gt=0"`
+}
+
+func purchaseOrderLineInputs(lines []purchaseOrderLineRequest) []services.PurchaseOrderLineInput {
+	inputs := make([]services.PurchaseOrderLineInput, 0, len(lines))
+	for _, line := range lines {
+		inputs = append(inputs, services.PurchaseOrderLineInput{VariantID: line.VariantID, Quantity: line.Quantity})
+	}
+	return inputs
+}
+
+// CreatePurchaseOrder creates a draft for the supplier's organization. Goods
+// are received at the given location, or at the default location.
+func CreatePurchaseOrder(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		SupplierID uuid.UUID                  `json:"supplierID" binding:"required"`
+		LocationID *uuid.UUID                 `json:"locationID"`
+		Reference  string                     `json:"reference" binding:"max=255"`
+		Note       string                     `json:"note" binding:"max=255"`
+		Lines      []purchaseOrderLineRequest `json:"lines" binding:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	supplier, err := services.GetSupplierByID(db, tenant, request.SupplierID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Supplier not found"})
+		return
+	}
+
+	isMember, err := tenant.CanAccess(db, supplier.OrganizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin is not a member of this supplier's organization"})
+		return
+	}
+
+	order := models.PurchaseOrder{
+		OrganizationID: supplier.OrganizationID,
+		SupplierID:     supplier.ID,
+		Reference:      request.Reference,
+		Note:           request.Note,
+		CreatedByID:    principal.AdminID,
+	}
+
+	if request.LocationID != nil {
+		location, err := services.GetLocationByID(db, tenant, *request.LocationID)
+		if err != nil || location.OrganizationID != supplier.OrganizationID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Location does not belong to this supplier's organization"})
+			return
+		}
+		order.LocationID = location.ID
+	} else {
+		locationID, err := services.EnsureDefaultLocation(db, supplier.OrganizationID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		order.LocationID = locationID
+	}
+
+	if err := services.CreatePurchaseOrder(db, &order, purchaseOrderLineInputs(request.Lines)); err != nil {
+		respondPurchaseOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, order)
+}
+
+func GetAllPurchaseOrders(c *gin.Context, db *gorm.DB) {
+	page := 1
+	pageSize := 10
+
+	if pageParam, exists := c.GetQuery("page"); exists {
+		parsedPage, err := strconv.Atoi(pageParam)
+		if err == nil && parsedPage > 0 {
+			page = parsedPage
+		}
+	}
+
+	if pageSizeParam, exists := c.GetQuery("pageSize"); exists {
+		parsedPageSize, err := strconv.Atoi(pageSizeParam)
+		if err == nil && parsedPageSize > 0 {
+			pageSize = parsedPageSize
+		}
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	orders, err := services.GetAllPurchaseOrders(db, tenant, c.Query("status"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+func GetPurchaseOrderByID(c *gin.Context, db *gorm.DB) {
+	order, ok := purchaseOrderForMember(c, db)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+func UpdatePurchaseOrder(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Reference string                     `json:"reference" binding:"max=255"`
+		Note      string                     `json:"note" binding:"max=255"`
+		Lines     []purchaseOrderLineRequest `json:"lines" binding:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, ok := purchaseOrderForMember(c, db)
+	if !ok {
+		return
+	}
+
+	err := services.UpdatePurchaseOrder(db, order.ID, request.Reference, request.Note, purchaseOrderLineInputs(request.Lines))
+	if err != nil {
+		respondPurchaseOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Purchase order updated successfully"})
+}
+
+func SubmitPurchaseOrder(c *gin.Context, db *gorm.DB) {
+	order, ok := purchaseOrderForMember(c, db)
+	if !ok {
+		return
+	}
+
+	if err := services.SubmitPurchaseOrder(db, order.ID); err != nil {
+		respondPurchaseOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Purchase order submitted successfully"})
+}
+
+func CancelPurchaseOrder(c *gin.Context, db *gorm.DB) {
+	order, ok := purchaseOrderForMember(c, db)
+	if !ok {
+		return
+	}
+
+	if err := services.CancelPurchaseOrder(db, order.ID); err != nil {
+		respondPurchaseOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Purchase order cancelled successfully"})
+}
+
+// ClosePurchaseOrder closes a partially received order short once the rest
+// is not going to arrive.
+func ClosePurchaseOrder(c *gin.Context, db *gorm.DB) {
+	order, ok := purchaseOrderForMember(c, db)
+	if !ok {
+		return
+	}
+
+	if err := services.ClosePurchaseOrder(db, order.ID); err != nil {
+		respondPurchaseOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Purchase order closed successfully"})
+}
+
+// ReceivePurchaseOrder books goods in against the order's lines. The
+// location in the body overrides the order's receiving location.
+func ReceivePurchaseOrder(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		LocationID *uuid.UUID `json:"locationID"`
+		Note       string     `json:"note" binding:"max=255"`
+		Lines      []struct {
+			VariantID uuid.UUID `json:"variantID" binding:"required"`
+			Quantity  int       `json:"quantity" binding:"required,gt=0"`
+		} `json:"lines" binding:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	order, ok := purchaseOrderForMember(c, db)
+	if !ok {
+		return
+	}
+
+	receipts := make([]services.GoodsReceipt, 0, len(request.Lines))
+	for _, line := range request.Lines {
+		receipts = append(receipts, services.GoodsReceipt{VariantID: line.VariantID, Quantity: line.Quantity})
+	}
+
+	err := services.ReceivePurchaseOrder(db, order.ID, receipts, services.StockMovementInput{
+		LocationID: request.LocationID,
+		Note:       request.Note,
+		ActorID:    &principal.AdminID,
+		AuthMethod: principal.AuthMethod,
+	})
+	if err != nil {
+		respondPurchaseOrderError(c, err)
+		return
+	}
+
+	order, err = services.GetPurchaseOrderByID(db, tenant, order.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+func purchaseOrderForMember(c *gin.Context, db *gorm.DB) (*models.PurchaseOrder, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Purchase Order ID"})
+		return nil, false
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return nil, false
+	}
+
+	order, err := services.GetPurchaseOrderByID(db, tenant, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Purchase order not found"})
+		return nil, false
+	}
+
+	return order, true
+}
+
+func respondPurchaseOrderError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPurchaseOrderNotDraft):
+		c.JSON(http.StatusConflict, gin.H{"error": "Purchase order is not a draft"})
+	case errors.Is(err, services.ErrPurchaseOrderNotReceivable):
+		c.JSON(http.StatusConflict, gin.H{"error": "Purchase order is not awaiting goods"})
+	case errors.Is(err, services.ErrPurchaseOrderNotCancellable):
+		c.JSON(http.StatusConflict, gin.H{"error": "Purchase order can no longer be cancelled"})
+	case errors.Is(err, services.ErrPurchaseOrderNotClosable):
+		c.JSON(http.StatusConflict, gin.H{"error": "Only a partially received purchase order can be closed"})
+	case errors.Is(err, services.ErrInvalidPurchaseOrderLines):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Purchase order lines need distinct variants and positive quantities"})
+	case errors.Is(err, services.ErrVariantNotInOrganization):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Variant does not belong to the purchase order's organization"})
+	case errors.Is(err, services.ErrVariantNotOnPurchaseOrder):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Variant is not on the purchase order"})
+	case errors.Is(err, services.ErrOverReceipt):
+		c.JSON(http.StatusConflict, gin.H{"error": "Received quantity exceeds the quantity outstanding"})
+	default:
+		respondStockError(c, err)
+	}
+}
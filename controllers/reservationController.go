@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"golang-final-project/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func CreateReservation(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		VariantID  uuid.UUID `json:"variantID" binding:"required"`
+		Quantity   int       `json:"quantity" binding:"required,gt=0"`
+		TTLSeconds int       `json:"ttlSeconds" binding:"gte=0"`
+		Reference  string    `json:"reference" binding:"max=255"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Checked in seconds, a huge ttlSeconds would overflow the duration
+	if request.TTLSeconds > int(services.MaxReservationTTL/time.Second) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reservation TTL is too long"})
+		return
+	}
+
+	ttl := services.DefaultReservationTTL
+	if request.TTLSeconds > 0 {
+		ttl = time.Duration(request.TTLSeconds) * time.Second
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	variant, ok := variantByIDForMember(c, db, request.VariantID)
+	if !ok {
+		return
+	}
+
+	reservation, err := services.ReserveStock(db, variant.ID, principal.AdminID, request.Quantity, ttl, request.Reference)
+	if errors.Is(err, services.ErrInsufficientAvailableStock) {
+		available, err := services.GetVariantAvailable(db, variant.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "Insufficient available stock", "available": available})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, reservation)
+}
+
+func GetReservationByID(c *gin.Context, db *gorm.DB) {
+	reservation, ok := reservationForMember(c, db)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+// ConfirmReservation takes the units from the location in the optional body,
+// or from the default location.
+func ConfirmReservation(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		LocationID *uuid.UUID `json:"locationID"`
+	}
+
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	reservation, ok := reservationForMember(c, db)
+	if !ok {
+		return
+	}
+
+	confirmed, err := services.ConfirmReservation(db, reservation.ID, services.StockMovementInput{
+		LocationID: request.LocationID,
+		ActorID:    &principal.AdminID,
+		AuthMethod: principal.AuthMethod,
+	})
+	if errors.Is(err, services.ErrReservationNotActive) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Reservation is no longer active"})
+		return
+	}
+	if err != nil {
+		respondStockError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, confirmed)
+}
+
+func ReleaseReservation(c *gin.Context, db *gorm.DB) {
+	reservation, ok := reservationForMember(c, db)
+	if !ok {
+		return
+	}
+
+	released, err := services.ReleaseReservation(db, reservation.ID)
+	if errors.Is(err, services.ErrReservationNotActive) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Reservation is no longer active"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, released)
+}
+
+// reservationForMember loads the :id reservation if the calling admin can
+// access its variant, writing the error response otherwise.
+func reservationForMember(c *gin.Context, db *gorm.DB) (*models.StockReservation, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Reservation ID"})
+		return nil, false
+	}
+
+	reservation, err := services.GetReservationByID(db, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reservation not found"})
+		return nil, false
+	}
+
+	if _, ok := variantByIDForMember(c, db, reservation.VariantID); !ok {
+		return nil, false
+	}
+
+	return reservation, true
+}
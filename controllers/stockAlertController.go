@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"golang-final-project/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type thresholdsRequest struct {
+	LowStockThreshold *int `json:"lowStockThreshold" binding:"omitempty,gte=0"`
+	ReorderPoint      *int `json:"reorderPoint" binding:"omitempty,gte=0"`
+}
+
+func UpdateProductThresholds(c *gin.Context, db *gorm.DB) {
+	var request thresholdsRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Product ID"})
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	product, err := services.GetProductByID(db, tenant, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	isMember, err := tenant.CanAccess(db, product.OrganizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin is not a member of this product's organization"})
+		return
+	}
+
+	if err := services.UpdateProductThresholds(db, tenant, id, request.LowStockThreshold, request.ReorderPoint); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product thresholds updated successfully"})
+}
+
+func UpdateVariantThresholds(c *gin.Context, db *gorm.DB) {
+	var request thresholdsRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	variant, ok := variantForMember(c, db)
+	if !ok {
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	if err := services.UpdateVariantThresholds(db, tenant, variant.ID, request.LowStockThreshold, request.ReorderPoint); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Variant thresholds updated successfully"})
+}
+
+func GetLowStockReport(c *gin.Context, db *gorm.DB) {
+	page := 1
+	pageSize := 10
+
+	if pageParam, exists := c.GetQuery("page"); exists {
+		parsedPage, err := strconv.Atoi(pageParam)
+		if err == nil {
+			page = parsedPage
+		}
+	}
+
+	if pageSizeParam, exists := c.GetQuery("pageSize"); exists {
+		parsedPageSize, err := strconv.Atoi(pageSizeParam)
+		if err == nil {
+			pageSize = parsedPageSize
+		}
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	items, err := services.GetLowStockVariants(db, tenant, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
@@ -0,0 +1,264 @@
+package controllers
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"golang-final-project/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func CreateStockMovement(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Type       string     `json:"type" binding:"required"`
+		Quantity   int        `json:"quantity" binding:"required"`
+		LocationID *uuid.UUID `json:"locationID"`
+		ReasonCode string     `json:"reasonCode"`
+		Reference  string     `json:"reference" binding:"max=255"`
+		Note       string     `json:"note" binding:"max=255"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	variant, ok := variantForMember(c, db)
+	if !ok {
+		return
+	}
+
+	movement, err := services.RecordStockMovement(db, variant.ID, services.StockMovementInput{
+		Type:       request.Type,
+		Quantity:   request.Quantity,
+		LocationID: request.LocationID,
+		ReasonCode: request.ReasonCode,
+		Reference:  request.Reference,
+		Note:       request.Note,
+		ActorID:    &principal.AdminID,
+		AuthMethod: principal.AuthMethod,
+	})
+	if err != nil {
+		respondStockError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, movement)
+}
+
+// AdjustStock applies a signed delta, e.g. from a point-of-sale integration.
+// It never takes stock below zero and answers 409 with the current level
+// instead.
+func AdjustStock(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Delta      int        `json:"delta" binding:"required"`
+		Type       string     `json:"type"`
+		LocationID *uuid.UUID `json:"locationID"`
+		ReasonCode string     `json:"reasonCode"`
+		Reference  string     `json:"reference" binding:"max=255"`
+		Note       string     `json:"note" binding:"max=255"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.Type == "" {
+		request.Type = services.MovementAdjust
+	}
+
+	if request.Type == services.MovementAdjust && request.ReasonCode == "" {
+		request.ReasonCode = services.ReasonOther
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	variant, ok := variantForMember(c, db)
+	if !ok {
+		return
+	}
+
+	movement, err := services.RecordStockMovement(db, variant.ID, services.StockMovementInput{
+		Type:       request.Type,
+		Quantity:   request.Delta,
+		LocationID: request.LocationID,
+		ReasonCode: request.ReasonCode,
+		Reference:  request.Reference,
+		Note:       request.Note,
+		ActorID:    &principal.AdminID,
+		AuthMethod: principal.AuthMethod,
+	})
+	if err != nil {
+		respondStockError(c, err)
+		return
+	}
+
+	quantity, err := services.GetVariantQuantity(db, variant.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"quantity":         quantity,
+		"locationQuantity": movement.QuantityAfter,
+		"movement":         movement,
+	})
+}
+
+// TransferStock moves units of the variant between two of its
+// organization's locations.
+func TransferStock(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		FromLocationID uuid.UUID `json:"fromLocationID" binding:"required"`
+		ToLocationID   uuid.UUID `json:"toLocationID" binding:"required"`
+		Quantity       int       `json:"quantity" binding:"required,gt=0"`
+		Reference      string    `json:"reference" binding:"max=255"`
+		Note           string    `json:"note" binding:"max=255"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	variant, ok := variantForMember(c, db)
+	if !ok {
+		return
+	}
+
+	movements, err := services.TransferStock(db, variant.ID, request.FromLocationID, request.ToLocationID, request.Quantity, services.StockMovementInput{
+		Reference:  request.Reference,
+		Note:       request.Note,
+		ActorID:    &principal.AdminID,
+		AuthMethod: principal.AuthMethod,
+	})
+	if err != nil {
+		respondStockError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, movements)
+}
+
+func GetStockMovements(c *gin.Context, db *gorm.DB) {
+	page := 1
+	pageSize := 10
+
+	if pageParam, exists := c.GetQuery("page"); exists {
+		parsedPage, err := strconv.Atoi(pageParam)
+		if err == nil {
+			page = parsedPage
+		}
+	}
+
+	if pageSizeParam, exists := c.GetQuery("pageSize"); exists {
+		parsedPageSize, err := strconv.Atoi(pageSizeParam)
+		if err == nil {
+			pageSize = parsedPageSize
+		}
+	}
+
+	variant, ok := variantForMember(c, db)
+	if !ok {
+		return
+	}
+
+	movements, err := services.GetStockMovementsByVariantID(db, variant.ID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, movements)
+}
+
+// variantForMember loads the :id variant and checks that the calling admin
+// belongs to its product's organization, writing the error response
+// otherwise.
+func variantForMember(c *gin.Context, db *gorm.DB) (*models.Variant, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Variant ID"})
+		return nil, false
+	}
+
+	return variantByIDForMember(c, db, id)
+}
+
+func variantByIDForMember(c *gin.Context, db *gorm.DB, id uuid.UUID) (*models.Variant, bool) {
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return nil, false
+	}
+
+	variant, err := services.GetVariantByID(db, tenant, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Variant not found"})
+		return nil, false
+	}
+
+	product, err := services.GetProductByID(db, tenant, variant.ProductID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return nil, false
+	}
+
+	isMember, err := tenant.CanAccess(db, product.OrganizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin is not a member of this product's organization"})
+		return nil, false
+	}
+
+	return variant, true
+}
+
+// respondStockError maps stock movement errors to responses. Insufficient
+// stock is a 409 carrying the current level at the location.
+func respondStockError(c *gin.Context, err error) {
+	var insufficient *services.InsufficientStockError
+
+	switch {
+	case errors.As(err, &insufficient):
+		c.JSON(http.StatusConflict, gin.H{"error": "Insufficient stock", "quantity": insufficient.Available})
+	case errors.Is(err, services.ErrInvalidMovementType):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movement type"})
+	case errors.Is(err, services.ErrInvalidMovementQuantity):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid quantity for this movement type"})
+	case errors.Is(err, services.ErrInvalidReasonCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or missing reason code"})
+	case errors.Is(err, services.ErrInvalidLocation):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Location does not belong to this variant's organization"})
+	case errors.Is(err, services.ErrMultiLocationStock):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Variant is stocked at more than one location, pass a locationID"})
+	case errors.Is(err, services.ErrInvalidTransfer):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Transfer needs a positive quantity and two different locations"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Variant not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
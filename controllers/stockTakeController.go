@@ -0,0 +1,253 @@
+package controllers
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"golang-final-project/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OpenStockTake starts a count at the given location, or at the default
+// location of the admin's organization.
+func OpenStockTake(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		LocationID *uuid.UUID `json:"locationID"`
+		Note       string     `json:"note" binding:"max=255"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	stockTake := models.StockTake{
+		Note:       request.Note,
+		OpenedByID: principal.AdminID,
+	}
+
+	if request.LocationID != nil {
+		location, err := services.GetLocationByID(db, tenant, *request.LocationID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Location not found"})
+			return
+		}
+		stockTake.OrganizationID = location.OrganizationID
+		stockTake.LocationID = location.ID
+	} else {
+		organizationID, ok := targetOrganizationID(c, db, nil)
+		if !ok {
+			return
+		}
+
+		locationID, err := services.EnsureDefaultLocation(db, organizationID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		stockTake.OrganizationID = organizationID
+		stockTake.LocationID = locationID
+	}
+
+	if err := services.OpenStockTake(db, &stockTake); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, stockTake)
+}
+
+func GetAllStockTakes(c *gin.Context, db *gorm.DB) {
+	page := 1
+	pageSize := 10
+
+	if pageParam, exists := c.GetQuery("page"); exists {
+		parsedPage, err := strconv.Atoi(pageParam)
+		if err == nil && parsedPage > 0 {
+			page = parsedPage
+		}
+	}
+
+	if pageSizeParam, exists := c.GetQuery("pageSize"); exists {
+		parsedPageSize, err := strconv.Atoi(pageSizeParam)
+		if err == nil && parsedPageSize > 0 {
+			pageSize = parsedPageSize
+		}
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	stockTakes, err := services.GetAllStockTakes(db, tenant, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stockTakes)
+}
+
+func GetStockTakeByID(c *gin.Context, db *gorm.DB) {
+	stockTake, ok := stockTakeForMember(c, db)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, stockTake)
+}
+
+func SubmitStockCounts(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		Counts []struct {
+			VariantID       uuid.UUID `json:"variantID" binding:"required"`
+			CountedQuantity *int      `json:"countedQuantity" binding:"required,gte=0"`
+		} `json:"counts" binding:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	stockTake, ok := stockTakeForMember(c, db)
+	if !ok {
+		return
+	}
+
+	counts := make([]services.StockCount, 0, len(request.Counts))
+	for _, count := range request.Counts {
+		counts = append(counts, services.StockCount{VariantID: count.VariantID, CountedQuantity: *count.CountedQuantity})
+	}
+
+	err := services.SubmitStockCounts(db, stockTake.ID, principal.AdminID, counts)
+	if err != nil {
+		respondStockTakeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Counts submitted successfully"})
+}
+
+func GetStockTakeReport(c *gin.Context, db *gorm.DB) {
+	stockTake, ok := stockTakeForMember(c, db)
+	if !ok {
+		return
+	}
+
+	report, err := services.GetStockTakeReport(db, stockTake)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func PostStockTake(c *gin.Context, db *gorm.DB) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	stockTake, ok := stockTakeForMember(c, db)
+	if !ok {
+		return
+	}
+
+	err := services.PostStockTake(db, stockTake.ID, services.StockMovementInput{
+		ActorID:    &principal.AdminID,
+		AuthMethod: principal.AuthMethod,
+	})
+	if err != nil {
+		respondStockTakeError(c, err)
+		return
+	}
+
+	stockTake, err = services.GetStockTakeByID(db, tenant, stockTake.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := services.GetStockTakeReport(db, stockTake)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func CancelStockTake(c *gin.Context, db *gorm.DB) {
+	stockTake, ok := stockTakeForMember(c, db)
+	if !ok {
+		return
+	}
+
+	if err := services.CancelStockTake(db, stockTake.ID); err != nil {
+		respondStockTakeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Stock take cancelled successfully"})
+}
+
+func stockTakeForMember(c *gin.Context, db *gorm.DB) (*models.StockTake, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Stock Take ID"})
+		return nil, false
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return nil, false
+	}
+
+	stockTake, err := services.GetStockTakeByID(db, tenant, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stock take not found"})
+		return nil, false
+	}
+
+	return stockTake, true
+}
+
+func respondStockTakeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrStockTakeNotOpen):
+		c.JSON(http.StatusConflict, gin.H{"error": "Stock take is not open"})
+	case errors.Is(err, services.ErrStockTakeEmpty):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Stock take has no counts"})
+	case errors.Is(err, services.ErrVariantNotInTake):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Variant does not belong to the stock take's organization"})
+	default:
+		respondStockError(c, err)
+	}
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"golang-final-project/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type supplierRequest struct {
+	Name  string `json:"name" binding:"required,max=255"`
+	Email string `json:"email" binding:"omitempty,email,max=255"`
+	Phone string `json:"phone" binding:"max=50"`
+	Note  string `json:"note" binding:"max=255"`
+}
+
+func CreateSupplier(c *gin.Context, db *gorm.DB) {
+	var request struct {
+		supplierRequest
+		OrganizationID *uuid.UUID `json:"organizationID"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	organizationID, ok := targetOrganizationID(c, db, request.OrganizationID)
+	if !ok {
+		return
+	}
+
+	supplier := models.Supplier{
+		OrganizationID: organizationID,
+		Name:           request.Name,
+		Email:          request.Email,
+		Phone:          request.Phone,
+		Note:           request.Note,
+	}
+
+	if err := services.CreateSupplier(db, &supplier); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, supplier)
+}
+
+func GetAllSuppliers(c *gin.Context, db *gorm.DB) {
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	suppliers, err := services.GetAllSuppliers(db, tenant, c.Query("search"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, suppliers)
+}
+
+func GetSupplierByID(c *gin.Context, db *gorm.DB) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Supplier ID"})
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	supplier, err := services.GetSupplierByID(db, tenant, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Supplier not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, supplier)
+}
+
+func UpdateSupplierByID(c *gin.Context, db *gorm.DB) {
+	var request supplierRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Supplier ID"})
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	err = services.UpdateSupplierByID(db, tenant, id, &models.Supplier{
+		Name:  request.Name,
+		Email: request.Email,
+		Phone: request.Phone,
+		Note:  request.Note,
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Supplier not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Supplier updated successfully"})
+}
+
+func DeleteSupplierByID(c *gin.Context, db *gorm.DB) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Supplier ID"})
+		return
+	}
+
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	err = services.DeleteSupplierByID(db, tenant, id)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Supplier not found"})
+	case errors.Is(err, services.ErrSupplierInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "Supplier has purchase orders"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Supplier deleted successfully"})
+	}
+}
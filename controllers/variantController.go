@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"golang-final-project/models"
 	"golang-final-project/services"
 	"net/http"
@@ -23,29 +24,36 @@ func CreateVariant(c *gin.Context, db *gorm.DB) {
 		return
 	}
 
-	// Get Admin ID
-	adminIDString, err := services.ExtractAdminID(c)
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	product, err := services.GetProductByID(db, tenant, request.ProductID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error get Admin ID"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
 	}
 
-	adminID, err := uuid.Parse(adminIDString)
+	isMember, err := tenant.CanAccess(db, product.OrganizationID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Admin ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	product, _ := services.GetProductByID(db, request.ProductID)
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin is not a member of this product's organization"})
+		return
+	}
 
-	if product.AdminID != adminID {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized with this Admin ID"})
+	principal, ok := currentPrincipal(c)
+	if !ok {
 		return
 	}
 
+	// Stock starts at zero and the initial quantity goes through the ledger
 	variant := models.Variant{
 		VariantName: request.VariantName,
-		Quantity:    request.Quantity,
 		ProductID:   request.ProductID,
 	}
 
@@ -57,11 +65,23 @@ func CreateVariant(c *gin.Context, db *gorm.DB) {
 		}
 	}()
 
-	if err := services.CreateVariant(db, &variant); err != nil {
+	if err := services.CreateVariant(tx, &variant); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		panic(err)
 	}
 
+	_, err = services.RecordStockMovement(tx, variant.ID, services.StockMovementInput{
+		Type:       services.MovementReceive,
+		Quantity:   request.Quantity,
+		ReasonCode: services.ReasonInitial,
+		ActorID:    &principal.AdminID,
+		AuthMethod: principal.AuthMethod,
+	})
+	if err != nil {
+		respondStockError(c, err)
+		panic(err)
+	}
+
 	tx.Commit()
 
 	c.JSON(http.StatusCreated, gin.H{"message": "Variant created successfully"})
@@ -85,8 +105,13 @@ func GetAllVariantsWithPagination(c *gin.Context, db *gorm.DB) {
 		}
 	}
 
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
 	searchName := c.Query("search")
-	variants, err := services.GetAllVariantsWithPaginationAndSearch(db, page, pageSize, searchName)
+	variants, err := services.GetAllVariantsWithPaginationAndSearch(db, tenant, page, pageSize, searchName)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -110,7 +135,12 @@ func GetVariantByID(c *gin.Context, db *gorm.DB) {
 		return
 	}
 
-	variant, err := services.GetVariantByID(db, id)
+	tenant, ok := currentTenant(c)
+	if !ok {
+		return
+	}
+
+	variant, err := services.GetVariantByID(db, tenant, id)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -122,8 +152,9 @@ func GetVariantByID(c *gin.Context, db *gorm.DB) {
 
 func UpdateVariantByID(c *gin.Context, db *gorm.DB) {
 	var request struct {
-		VariantName string `json:"variantName" binding:"required"`
-		Quantity    int    `json:"quantity" binding:"required"`
+		VariantName string     `json:"variantName" binding:"required"`
+		Quantity    int        `json:"quantity" binding:"required"`
+		LocationID  *uuid.UUID `json:"locationID"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -144,34 +175,34 @@ func UpdateVariantByID(c *gin.Context, db *gorm.DB) {
 		return
 	}
 
-	// Get Admin ID
-	adminIDString, err := services.ExtractAdminID(c)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error get Admin ID"})
+	tenant, ok := currentTenant(c)
+	if !ok {
 		return
 	}
 
-	adminID, err := uuid.Parse(adminIDString)
+	existingVariant, err := services.GetVariantByID(db, tenant, id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Admin ID"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Variant not found"})
 		return
 	}
 
-	existingVariant, err := services.GetVariantByID(db, id)
+	product, _ := services.GetProductByID(db, tenant, existingVariant.ProductID)
+
+	isMember, err := tenant.CanAccess(db, product.OrganizationID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Variant not found"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	product, _ := services.GetProductByID(db, existingVariant.ProductID)
-
-	if product.AdminID != adminID {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized with this Admin ID"})
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin is not a member of this product's organization"})
 		return
 	}
 
-	existingVariant.VariantName = request.VariantName
-	existingVariant.Quantity = request.Quantity
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
 
 	tx := db.Begin()
 	defer func() {
@@ -181,11 +212,24 @@ func UpdateVariantByID(c *gin.Context, db *gorm.DB) {
 		}
 	}()
 
-	if err := services.UpdateVariantByID(db, id, existingVariant); err != nil {
+	if err := services.UpdateVariantByID(tx, tenant, id, &models.Variant{VariantName: request.VariantName}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		panic(err)
 	}
 
+	// An absolute quantity is recorded as a correction in the ledger. With a
+	// locationID it is the quantity at that location
+	_, err = services.SetStockLevel(tx, id, request.Quantity, services.StockMovementInput{
+		LocationID: request.LocationID,
+		ReasonCode: services.ReasonCorrection,
+		ActorID:    &principal.AdminID,
+		AuthMethod: principal.AuthMethod,
+	})
+	if err != nil {
+		respondStockError(c, err)
+		panic(err)
+	}
+
 	tx.Commit()
 
 	c.JSON(http.StatusOK, gin.H{"message": "Variant updated successfully"})
@@ -205,30 +249,28 @@ func DeleteVariantByID(c *gin.Context, db *gorm.DB) {
 		return
 	}
 
-	// Get Admin ID
-	adminIDString, err := services.ExtractAdminID(c)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error get Admin ID"})
+	tenant, ok := currentTenant(c)
+	if !ok {
 		return
 	}
 
-	adminID, err := uuid.Parse(adminIDString)
+	variant, err := services.GetVariantByID(db, tenant, id)
+
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Admin ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Variant not found"})
 		return
 	}
 
-	variant, err := services.GetVariantByID(db, id)
+	product, _ := services.GetProductByID(db, tenant, variant.ProductID)
 
+	isMember, err := tenant.CanAccess(db, product.OrganizationID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Variant not found"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	product, _ := services.GetProductByID(db, variant.ProductID)
-
-	if product.AdminID != adminID {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized with this Admin ID"})
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin is not a member of this product's organization"})
 		return
 	}
 
@@ -240,7 +282,12 @@ func DeleteVariantByID(c *gin.Context, db *gorm.DB) {
 		}
 	}()
 
-	err = services.DeleteVariantByID(db, id)
+	err = services.DeleteVariantByID(db, tenant, id)
+	if errors.Is(err, services.ErrVariantInUse) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Variant is on an open order, an open purchase order or an active reservation"})
+		tx.Rollback()
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		panic(err)
@@ -3,6 +3,7 @@ package database
 import (
 	"fmt"
 	"golang-final-project/models"
+	"golang-final-project/services"
 	"log"
 	"os"
 
@@ -31,7 +32,61 @@ func init() {
 		log.Fatal("error connecting to database: ", err)
 	}
 
-	db.Debug().AutoMigrate(&models.Admin{}, &models.Product{}, &models.Variant{})
+	db.Debug().AutoMigrate(
+		&models.Admin{},
+		&models.Product{},
+		&models.Variant{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.AdminRevocation{},
+		&models.Organization{},
+		&models.Membership{},
+		&models.EmailVerification{},
+		&models.Invitation{},
+		&models.PasswordReset{},
+		&models.LoginAttempt{},
+		&models.AuditEvent{},
+		&models.RecoveryCode{},
+		&models.APIKey{},
+		&models.SigningKey{},
+		&models.OIDCLoginState{},
+		&models.StockMovement{},
+		&models.StockReservation{},
+		&models.Location{},
+		&models.StockLevel{},
+		&models.StockAlert{},
+		&models.StockTake{},
+		&models.StockTakeLine{},
+		&models.Supplier{},
+		&models.PurchaseOrder{},
+		&models.PurchaseOrderLine{},
+		&models.Order{},
+		&models.OrderLine{},
+		&models.CustomerGroup{},
+		&models.PriceList{},
+		&models.PriceListPrice{},
+		&models.PriceListAssignment{},
+	)
+
+	if err := services.MigrateProductsToOrganizations(db); err != nil {
+		log.Fatal("error migrating products to organizations: ", err)
+	}
+
+	if err := services.BackfillEmailVerification(db); err != nil {
+		log.Fatal("error backfilling email verification: ", err)
+	}
+
+	if err := services.EnsureOwner(db); err != nil {
+		log.Fatal("error assigning owner role: ", err)
+	}
+
+	if err := services.BackfillStockMovements(db); err != nil {
+		log.Fatal("error backfilling stock movements: ", err)
+	}
+
+	if err := services.MigrateStockToLocations(db); err != nil {
+		log.Fatal("error migrating stock to locations: ", err)
+	}
 }
 
 func ConnectDB() *gorm.DB {
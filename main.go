@@ -3,8 +3,10 @@ package main
 import (
 	database "golang-final-project/dabatase"
 	"golang-final-project/routes"
+	"golang-final-project/services"
 	"log"
 	"os"
+	"time"
 
 	"github.com/cloudinary/cloudinary-go/v2"
 	"github.com/gin-gonic/gin"
@@ -17,11 +19,48 @@ func main() {
 	}
 
 	db := database.ConnectDB()
+
+	keyset, err := services.LoadKeysetFromEnv(db)
+	if err != nil {
+		log.Fatalf("Failed to load JWT signing keys, %v", err)
+	}
+	services.Keys = keyset
+	keyset.StartRotation()
+
+	services.StartReservationSweeper(db, time.Minute)
+
+	services.DefaultMailer = services.NewMailerFromEnv()
+	services.DefaultStockAlertNotifier = services.NewStockAlertNotifierFromEnv(db)
+	services.StartStockAlertDispatcher(db, 10*time.Second)
+	services.OIDC = services.NewOIDCProviderFromEnv()
+
+	if os.Getenv("LOGIN_ATTEMPT_STORE") != "memory" {
+		services.LoginAttempts = services.NewGormLoginAttemptStore(db)
+	}
+
+	// Revocations are persisted unless explicitly kept in memory (e.g. local development)
+	if os.Getenv("TOKEN_REVOCATION_STORE") != "memory" {
+		services.TokenRevocations = services.NewGormRevocationStore(db)
+	}
+
 	r := gin.Default()
 
 	routes.AuthRoute(r, db)
+	routes.JWKSRoute(r)
+	routes.OIDCRoute(r, db)
+	routes.MFARoute(r, db)
 	routes.ProductRoute(r, db, cld)
 	routes.VariantRoutes(r, db)
+	routes.AdminRoute(r, db, cld)
+	routes.OrganizationRoute(r, db)
+	routes.APIKeyRoute(r, db)
+	routes.ReservationRoute(r, db)
+	routes.LocationRoute(r, db)
+	routes.StockTakeRoute(r, db)
+	routes.PurchasingRoute(r, db)
+	routes.OrderRoute(r, db)
+	routes.PricingRoute(r, db)
+	routes.InventoryRoute(r, db)
 
 	port := envPortOr("3000")
 	r.Run(port)
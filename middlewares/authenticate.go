@@ -6,7 +6,6 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 func AuthenticateJWT() gin.HandlerFunc {
@@ -36,17 +35,29 @@ func AuthenticateJWT() gin.HandlerFunc {
 
 		claims := &services.Claims{}
 
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return services.JwtKey, nil
-		})
+		token, err := services.ParseToken(tokenString, claims)
 
-		if err != nil || !token.Valid {
+		// Tokens with a purpose, like the MFA pending token, are not access tokens
+		if err != nil || !token.Valid || claims.Purpose != "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
 
-		c.Set("adminID", claims.ID)
+		revoked, err := services.IsClaimsRevoked(claims)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check token revocation"})
+			c.Abort()
+			return
+		}
+
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
+		services.SetPrincipal(c, services.PrincipalFromClaims(claims))
 		c.Next()
 	}
 }
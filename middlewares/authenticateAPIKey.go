@@ -0,0 +1,60 @@
+package middlewares
+
+import (
+	"errors"
+	"golang-final-project/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Authenticate accepts either an X-API-Key header or a Bearer JWT. API key
+// requests get a Principal like AuthenticateJWT's, limited to the key's
+// scopes.
+func Authenticate(db *gorm.DB) gin.HandlerFunc {
+	authenticateJWT := AuthenticateJWT()
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(services.APIKeyHeader)
+		if key == "" {
+			authenticateJWT(c)
+			return
+		}
+
+		apiKey, admin, err := services.AuthenticateAPIKey(db, key)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidAPIKey) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check API key"})
+			}
+			c.Abort()
+			return
+		}
+
+		organizationID, err := services.GetDefaultOrganizationID(db, admin.ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check API key"})
+			c.Abort()
+			return
+		}
+
+		principal := &services.Principal{
+			AdminID:        admin.ID,
+			Role:           admin.Role,
+			OrganizationID: organizationID,
+			AuthMethod:     services.AuthMethodAPIKey,
+			TokenID:        apiKey.ID.String(),
+			// Keys never carry cross-tenant access, even a superadmin's
+			Superadmin: false,
+			Scopes:     services.APIKeyScopes(apiKey),
+		}
+		if apiKey.ExpiresAt != nil {
+			principal.TokenExpiresAt = *apiKey.ExpiresAt
+		}
+
+		services.SetPrincipal(c, principal)
+		c.Next()
+	}
+}
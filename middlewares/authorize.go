@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"golang-final-project/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission must run after AuthenticateJWT or Authenticate, which
+// store the Principal. API keys are further limited to their scopes.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := services.PrincipalFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+
+		if !services.HasPermission(principal.Role, permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+			c.Abort()
+			return
+		}
+
+		if !principal.Can(permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API key scope does not allow this action"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireMFA only lets through sessions that completed a second factor. It
+// must run after AuthenticateJWT.
+func RequireMFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := services.PrincipalFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+
+		if principal.AuthMethod != services.AuthMethodMFA {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Multi-factor authentication required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -13,11 +13,19 @@ func (admin *Admin) BeforeCreate(tx *gorm.DB) (err error) {
 }
 
 type Admin struct {
-	ID        uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
-	Name      string    `json:"name" gorm:"type:varchar(255);not null"`
-	Email     string    `json:"email" gorm:"type:varchar(255);uniqueIndex;not null"`
-	Password  string    `json:"password" gorm:"type:varchar(255);not null"`
-	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
-	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
-	Products  []Product `json:"products" gorm:"foreignKey:AdminID"`
+	ID              uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	Name            string     `json:"name" gorm:"type:varchar(255);not null"`
+	Email           string     `json:"email" gorm:"type:varchar(255);uniqueIndex;not null"`
+	Password        string     `json:"-" gorm:"type:varchar(255);not null"`
+	Role            string     `json:"role" gorm:"type:varchar(20);not null;default:'editor'"`
+	Superadmin      bool       `json:"superadmin" gorm:"not null;default:false"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt"`
+	TOTPSecret      string     `json:"-" gorm:"type:varchar(64)"`
+	TOTPLastStep    int64      `json:"-" gorm:"not null;default:0"`
+	MFAEnabledAt    *time.Time `json:"mfaEnabledAt"`
+	OIDCSubject     *string    `json:"-" gorm:"column:oidc_subject;type:varchar(255);uniqueIndex"`
+	DeactivatedAt   *time.Time `json:"deactivatedAt"`
+	CreatedAt       time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
+	Products        []Product  `json:"products" gorm:"foreignKey:AdminID"`
 }
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AdminRevocation invalidates every access token of an admin issued before
+// RevokedAt ("sign out everywhere").
+type AdminRevocation struct {
+	AdminID   uuid.UUID `json:"adminID" gorm:"type:char(36);primary_key"`
+	RevokedAt time.Time `json:"revokedAt" gorm:"not null"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (apiKey *APIKey) BeforeCreate(tx *gorm.DB) (err error) {
+	apiKey.ID = uuid.New()
+	return
+}
+
+type APIKey struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	AdminID    uuid.UUID  `json:"adminID" gorm:"type:char(36);index;not null"`
+	Name       string     `json:"name" gorm:"type:varchar(255);not null"`
+	Prefix     string     `json:"prefix" gorm:"type:varchar(32);uniqueIndex;not null"`
+	KeyHash    string     `json:"-" gorm:"type:char(64);not null"`
+	Scopes     string     `json:"scopes" gorm:"type:varchar(255);not null"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+}
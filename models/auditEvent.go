@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (auditEvent *AuditEvent) BeforeCreate(tx *gorm.DB) (err error) {
+	auditEvent.ID = uuid.New()
+	return
+}
+
+type AuditEvent struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	Type      string     `json:"type" gorm:"type:varchar(64);index;not null"`
+	AdminID   *uuid.UUID `json:"adminID" gorm:"type:char(36);index"`
+	IP        string     `json:"ip" gorm:"type:varchar(64)"`
+	Detail    string     `json:"detail" gorm:"type:text"`
+	CreatedAt time.Time  `json:"createdAt" gorm:"autoCreateTime;index"`
+}
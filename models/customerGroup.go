@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (customerGroup *CustomerGroup) BeforeCreate(tx *gorm.DB) (err error) {
+	customerGroup.ID = uuid.New()
+	return
+}
+
+type CustomerGroup struct {
+	ID             uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	OrganizationID uuid.UUID `json:"organizationID" gorm:"type:char(36);not null;uniqueIndex:idx_customer_group_organization_code"`
+	Name           string    `json:"name" gorm:"type:varchar(255);not null"`
+	Code           string    `json:"code" gorm:"type:varchar(50);not null;uniqueIndex:idx_customer_group_organization_code"`
+	CreatedAt      time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (emailVerification *EmailVerification) BeforeCreate(tx *gorm.DB) (err error) {
+	emailVerification.ID = uuid.New()
+	return
+}
+
+type EmailVerification struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	AdminID   uuid.UUID  `json:"adminID" gorm:"type:char(36);index;not null"`
+	Email     string     `json:"email" gorm:"type:varchar(255);not null"`
+	TokenHash string     `json:"-" gorm:"type:char(64);uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expiresAt" gorm:"not null"`
+	UsedAt    *time.Time `json:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+}
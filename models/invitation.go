@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (invitation *Invitation) BeforeCreate(tx *gorm.DB) (err error) {
+	invitation.ID = uuid.New()
+	return
+}
+
+type Invitation struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	Email          string     `json:"email" gorm:"type:varchar(255);index;not null"`
+	Role           string     `json:"role" gorm:"type:varchar(20);not null"`
+	OrganizationID uuid.UUID  `json:"organizationID" gorm:"type:char(36);not null"`
+	InvitedByID    uuid.UUID  `json:"invitedByID" gorm:"type:char(36);not null"`
+	TokenHash      string     `json:"-" gorm:"type:char(64);uniqueIndex;not null"`
+	ExpiresAt      time.Time  `json:"expiresAt" gorm:"not null"`
+	AcceptedAt     *time.Time `json:"acceptedAt"`
+	CreatedAt      time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+}
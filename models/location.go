@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (location *Location) BeforeCreate(tx *gorm.DB) (err error) {
+	location.ID = uuid.New()
+	return
+}
+
+type Location struct {
+	ID             uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	OrganizationID uuid.UUID `json:"organizationID" gorm:"type:char(36);not null;uniqueIndex:idx_location_organization_code"`
+	Name           string    `json:"name" gorm:"type:varchar(255);not null"`
+	Code           string    `json:"code" gorm:"type:varchar(50);not null;uniqueIndex:idx_location_organization_code"`
+	IsDefault      bool      `json:"isDefault" gorm:"not null;default:false"`
+	CreatedAt      time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
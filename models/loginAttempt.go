@@ -0,0 +1,12 @@
+package models
+
+import (
+	"time"
+)
+
+type LoginAttempt struct {
+	Key           string     `json:"key" gorm:"type:varchar(320);primary_key"`
+	Failures      int        `json:"failures" gorm:"not null"`
+	LastFailureAt time.Time  `json:"lastFailureAt" gorm:"not null"`
+	LockedUntil   *time.Time `json:"lockedUntil"`
+}
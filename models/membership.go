@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (membership *Membership) BeforeCreate(tx *gorm.DB) (err error) {
+	membership.ID = uuid.New()
+	return
+}
+
+type Membership struct {
+	ID             uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	OrganizationID uuid.UUID `json:"organizationID" gorm:"type:char(36);not null;uniqueIndex:idx_membership_organization_admin"`
+	AdminID        uuid.UUID `json:"adminID" gorm:"type:char(36);not null;uniqueIndex:idx_membership_organization_admin;index"`
+	CreatedAt      time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	Admin          *Admin    `json:"admin,omitempty" gorm:"foreignKey:AdminID"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+type OIDCLoginState struct {
+	StateHash    string    `json:"-" gorm:"type:char(64);primary_key"`
+	CodeVerifier string    `json:"-" gorm:"type:varchar(128);not null"`
+	Nonce        string    `json:"-" gorm:"type:varchar(64);not null"`
+	ExpiresAt    time.Time `json:"expiresAt" gorm:"index;not null"`
+	CreatedAt    time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
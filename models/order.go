@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (order *Order) BeforeCreate(tx *gorm.DB) (err error) {
+	order.ID = uuid.New()
+	return
+}
+
+type Order struct {
+	ID              uuid.UUID   `json:"id" gorm:"type:char(36);primary_key"`
+	OrganizationID  uuid.UUID   `json:"organizationID" gorm:"type:char(36);index;not null"`
+	LocationID      uuid.UUID   `json:"locationID" gorm:"type:char(36);not null"`
+	Status          string      `json:"status" gorm:"type:varchar(20);not null"`
+	Reference       string      `json:"reference" gorm:"type:varchar(255)"`
+	Note            string      `json:"note" gorm:"type:varchar(255)"`
+	Currency        string      `json:"currency" gorm:"type:char(3);not null"`
+	CustomerGroupID *uuid.UUID  `json:"customerGroupID" gorm:"type:char(36)"`
+	TotalAmount     int64       `json:"totalAmount" gorm:"not null"`
+	CreatedByID     uuid.UUID   `json:"createdByID" gorm:"type:char(36);not null"`
+	ConfirmedAt     *time.Time  `json:"confirmedAt"`
+	FulfilledAt     *time.Time  `json:"fulfilledAt"`
+	CancelledAt     *time.Time  `json:"cancelledAt"`
+	CreatedAt       time.Time   `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time   `json:"updatedAt" gorm:"autoUpdateTime"`
+	Lines           []OrderLine `json:"lines,omitempty" gorm:"foreignKey:OrderID"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (orderLine *OrderLine) BeforeCreate(tx *gorm.DB) (err error) {
+	orderLine.ID = uuid.New()
+	return
+}
+
+type OrderLine struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	OrderID   uuid.UUID `json:"orderID" gorm:"type:char(36);not null;uniqueIndex:idx_order_line_variant"`
+	VariantID uuid.UUID `json:"variantID" gorm:"type:char(36);not null;index;uniqueIndex:idx_order_line_variant"`
+	Quantity  int       `json:"quantity" gorm:"not null"`
+	UnitPrice int64     `json:"unitPrice" gorm:"not null"`
+	Status    string    `json:"status" gorm:"type:varchar(20);not null"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
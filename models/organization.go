@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (organization *Organization) BeforeCreate(tx *gorm.DB) (err error) {
+	organization.ID = uuid.New()
+	return
+}
+
+type Organization struct {
+	ID                      uuid.UUID    `json:"id" gorm:"type:char(36);primary_key"`
+	Name                    string       `json:"name" gorm:"type:varchar(255);not null"`
+	StockAlertWebhookURL    string       `json:"stockAlertWebhookURL" gorm:"type:varchar(255);not null;default:''"`
+	StockAlertWebhookSecret string       `json:"-" gorm:"type:varchar(255);not null;default:''"`
+	StockAlertEmailTo       string       `json:"stockAlertEmailTo" gorm:"type:varchar(1000);not null;default:''"`
+	CreatedAt               time.Time    `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt               time.Time    `json:"updatedAt" gorm:"autoUpdateTime"`
+	Memberships             []Membership `json:"memberships,omitempty" gorm:"foreignKey:OrganizationID"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (passwordReset *PasswordReset) BeforeCreate(tx *gorm.DB) (err error) {
+	passwordReset.ID = uuid.New()
+	return
+}
+
+type PasswordReset struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	AdminID   uuid.UUID  `json:"adminID" gorm:"type:char(36);index;not null"`
+	TokenHash string     `json:"-" gorm:"type:char(64);uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expiresAt" gorm:"not null"`
+	UsedAt    *time.Time `json:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+}
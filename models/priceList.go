@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (priceList *PriceList) BeforeCreate(tx *gorm.DB) (err error) {
+	priceList.ID = uuid.New()
+	return
+}
+
+type PriceList struct {
+	ID             uuid.UUID             `json:"id" gorm:"type:char(36);primary_key"`
+	OrganizationID uuid.UUID             `json:"organizationID" gorm:"type:char(36);index;not null"`
+	Name           string                `json:"name" gorm:"type:varchar(255);not null"`
+	Currency       string                `json:"currency" gorm:"type:char(3);not null"`
+	Priority       int                   `json:"priority" gorm:"not null;default:0"`
+	StartsAt       *time.Time            `json:"startsAt"`
+	EndsAt         *time.Time            `json:"endsAt"`
+	CreatedAt      time.Time             `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time             `json:"updatedAt" gorm:"autoUpdateTime"`
+	Prices         []PriceListPrice      `json:"prices,omitempty" gorm:"foreignKey:PriceListID"`
+	Assignments    []PriceListAssignment `json:"assignments,omitempty" gorm:"foreignKey:PriceListID"`
+}
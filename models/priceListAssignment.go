@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (priceListAssignment *PriceListAssignment) BeforeCreate(tx *gorm.DB) (err error) {
+	priceListAssignment.ID = uuid.New()
+	return
+}
+
+type PriceListAssignment struct {
+	ID              uuid.UUID      `json:"id" gorm:"type:char(36);primary_key"`
+	PriceListID     uuid.UUID      `json:"priceListID" gorm:"type:char(36);not null;uniqueIndex:idx_price_list_assignment"`
+	CustomerGroupID uuid.UUID      `json:"customerGroupID" gorm:"type:char(36);not null;uniqueIndex:idx_price_list_assignment;index"`
+	CreatedAt       time.Time      `json:"createdAt" gorm:"autoCreateTime"`
+	CustomerGroup   *CustomerGroup `json:"customerGroup,omitempty" gorm:"foreignKey:CustomerGroupID"`
+}
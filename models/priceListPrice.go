@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (priceListPrice *PriceListPrice) BeforeCreate(tx *gorm.DB) (err error) {
+	priceListPrice.ID = uuid.New()
+	return
+}
+
+type PriceListPrice struct {
+	ID          uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	PriceListID uuid.UUID `json:"priceListID" gorm:"type:char(36);not null;uniqueIndex:idx_price_list_price_tier"`
+	VariantID   uuid.UUID `json:"variantID" gorm:"type:char(36);not null;index;uniqueIndex:idx_price_list_price_tier"`
+	MinQuantity int       `json:"minQuantity" gorm:"not null;uniqueIndex:idx_price_list_price_tier"`
+	Amount      int64     `json:"amount" gorm:"not null"`
+	CreatedAt   time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
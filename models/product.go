@@ -13,11 +13,20 @@ func (product *Product) BeforeCreate(tx *gorm.DB) (err error) {
 }
 
 type Product struct {
-	ID        uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
-	Name      string    `json:"name" gorm:"type:varchar(255);not null"`
-	ImageUrl  string    `json:"imageUrl" gorm:"type:varchar(255);not null"`
-	AdminID   uuid.UUID `json:"adminID" gorm:"type:char(36);not null"`
-	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
-	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
-	Variants  []Variant `json:"variants" gorm:"foreignKey:ProductID"`
+	ID                uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	Name              string     `json:"name" gorm:"type:varchar(255);not null"`
+	ImageUrl          string     `json:"imageUrl" gorm:"type:varchar(255);not null"`
+	AdminID           uuid.UUID  `json:"adminID" gorm:"type:char(36);not null"`
+	OrganizationID    uuid.UUID  `json:"organizationID" gorm:"type:char(36);index"`
+	LowStockThreshold *int       `json:"lowStockThreshold"`
+	ReorderPoint      *int       `json:"reorderPoint"`
+	Currency          string     `json:"currency" gorm:"type:char(3);not null;default:''"`
+	Price             *int64     `json:"price"`
+	CompareAtPrice    *int64     `json:"compareAtPrice"`
+	SalePrice         *int64     `json:"salePrice"`
+	SaleStartsAt      *time.Time `json:"saleStartsAt"`
+	SaleEndsAt        *time.Time `json:"saleEndsAt"`
+	CreatedAt         time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
+	Variants          []Variant  `json:"variants" gorm:"foreignKey:ProductID"`
 }
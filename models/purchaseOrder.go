@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (purchaseOrder *PurchaseOrder) BeforeCreate(tx *gorm.DB) (err error) {
+	purchaseOrder.ID = uuid.New()
+	return
+}
+
+type PurchaseOrder struct {
+	ID             uuid.UUID           `json:"id" gorm:"type:char(36);primary_key"`
+	OrganizationID uuid.UUID           `json:"organizationID" gorm:"type:char(36);index;not null"`
+	SupplierID     uuid.UUID           `json:"supplierID" gorm:"type:char(36);index;not null"`
+	LocationID     uuid.UUID           `json:"locationID" gorm:"type:char(36);not null"`
+	Status         string              `json:"status" gorm:"type:varchar(20);not null"`
+	Reference      string              `json:"reference" gorm:"type:varchar(255)"`
+	Note           string              `json:"note" gorm:"type:varchar(255)"`
+	CreatedByID    uuid.UUID           `json:"createdByID" gorm:"type:char(36);not null"`
+	SubmittedAt    *time.Time          `json:"submittedAt"`
+	ReceivedAt     *time.Time          `json:"receivedAt"`
+	CancelledAt    *time.Time          `json:"cancelledAt"`
+	ClosedAt       *time.Time          `json:"closedAt"`
+	CreatedAt      time.Time           `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time           `json:"updatedAt" gorm:"autoUpdateTime"`
+	Supplier       *Supplier           `json:"supplier,omitempty" gorm:"foreignKey:SupplierID"`
+	Location       *Location           `json:"location,omitempty" gorm:"foreignKey:LocationID"`
+	Lines          []PurchaseOrderLine `json:"lines,omitempty" gorm:"foreignKey:PurchaseOrderID"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (purchaseOrderLine *PurchaseOrderLine) BeforeCreate(tx *gorm.DB) (err error) {
+	purchaseOrderLine.ID = uuid.New()
+	return
+}
+
+type PurchaseOrderLine struct {
+	ID               uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	PurchaseOrderID  uuid.UUID `json:"purchaseOrderID" gorm:"type:char(36);not null;uniqueIndex:idx_purchase_order_line_variant"`
+	VariantID        uuid.UUID `json:"variantID" gorm:"type:char(36);not null;index;uniqueIndex:idx_purchase_order_line_variant"`
+	QuantityOrdered  int       `json:"quantityOrdered" gorm:"not null"`
+	QuantityReceived int       `json:"quantityReceived" gorm:"not null;default:0"`
+	CreatedAt        time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (recoveryCode *RecoveryCode) BeforeCreate(tx *gorm.DB) (err error) {
+	recoveryCode.ID = uuid.New()
+	return
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	AdminID   uuid.UUID  `json:"adminID" gorm:"type:char(36);index;not null"`
+	CodeHash  string     `json:"-" gorm:"type:char(64);not null"`
+	UsedAt    *time.Time `json:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (refreshToken *RefreshToken) BeforeCreate(tx *gorm.DB) (err error) {
+	refreshToken.ID = uuid.New()
+	return
+}
+
+type RefreshToken struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	AdminID    uuid.UUID  `json:"adminID" gorm:"type:char(36);index;not null"`
+	FamilyID   uuid.UUID  `json:"familyID" gorm:"type:char(36);index;not null"`
+	TokenHash  string     `json:"-" gorm:"type:char(64);uniqueIndex;not null"`
+	AuthMethod string     `json:"authMethod" gorm:"type:varchar(20);not null;default:'password'"`
+	ExpiresAt  time.Time  `json:"expiresAt" gorm:"not null"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+}
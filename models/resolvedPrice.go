@@ -0,0 +1,10 @@
+package models
+
+// ResolvedPrice is what a variant sells for at a given moment, in minor
+// units of Currency.
+type ResolvedPrice struct {
+	Currency       string `json:"currency"`
+	Amount         int64  `json:"amount"`
+	CompareAtPrice *int64 `json:"compareAtPrice,omitempty"`
+	OnSale         bool   `json:"onSale"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import (
+	"time"
+)
+
+type RevokedToken struct {
+	TokenID   string    `json:"tokenID" gorm:"type:varchar(64);primary_key"`
+	ExpiresAt time.Time `json:"expiresAt" gorm:"index;not null"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+type SigningKey struct {
+	KID        string     `json:"kid" gorm:"column:kid;type:varchar(64);primary_key"`
+	Algorithm  string     `json:"algorithm" gorm:"type:varchar(10);not null"`
+	PrivateKey string     `json:"-" gorm:"type:text;not null"`
+	PublicKey  string     `json:"publicKey" gorm:"type:text;not null"`
+	CreatedAt  time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	RetiredAt  *time.Time `json:"retiredAt"`
+	ExpiresAt  *time.Time `json:"expiresAt" gorm:"index"`
+}
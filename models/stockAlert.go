@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (stockAlert *StockAlert) BeforeCreate(tx *gorm.DB) (err error) {
+	stockAlert.ID = uuid.New()
+	return
+}
+
+type StockAlert struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	VariantID      uuid.UUID  `json:"variantID" gorm:"type:char(36);index;not null"`
+	ProductID      uuid.UUID  `json:"productID" gorm:"type:char(36);not null"`
+	OrganizationID uuid.UUID  `json:"organizationID" gorm:"type:char(36);index"`
+	Type           string     `json:"type" gorm:"type:varchar(20);not null"`
+	Threshold      int        `json:"threshold" gorm:"not null"`
+	Quantity       int        `json:"quantity" gorm:"not null"`
+	Attempts       int        `json:"attempts" gorm:"not null;default:0"`
+	LastError      string     `json:"lastError" gorm:"type:varchar(255)"`
+	WebhookSentAt  *time.Time `json:"webhookSentAt"`
+	EmailSentAt    *time.Time `json:"emailSentAt"`
+	SentAt         *time.Time `json:"sentAt" gorm:"index"`
+	CreatedAt      time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+}
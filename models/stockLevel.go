@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type StockLevel struct {
+	VariantID  uuid.UUID `json:"variantID" gorm:"type:char(36);primary_key"`
+	LocationID uuid.UUID `json:"locationID" gorm:"type:char(36);primary_key;index"`
+	Quantity   int       `json:"quantity" gorm:"not null;default:0"`
+	UpdatedAt  time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+	Location   *Location `json:"location,omitempty" gorm:"foreignKey:LocationID"`
+}
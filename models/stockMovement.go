@@ -0,0 +1,39 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrStockMovementImmutable = errors.New("stock movements are append-only")
+
+func (stockMovement *StockMovement) BeforeCreate(tx *gorm.DB) (err error) {
+	stockMovement.ID = uuid.New()
+	return
+}
+
+func (stockMovement *StockMovement) BeforeUpdate(tx *gorm.DB) (err error) {
+	return ErrStockMovementImmutable
+}
+
+func (stockMovement *StockMovement) BeforeDelete(tx *gorm.DB) (err error) {
+	return ErrStockMovementImmutable
+}
+
+type StockMovement struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	VariantID     uuid.UUID  `json:"variantID" gorm:"type:char(36);index:idx_stock_movement_variant_created;not null"`
+	LocationID    *uuid.UUID `json:"locationID" gorm:"type:char(36);index"`
+	Type          string     `json:"type" gorm:"type:varchar(20);not null"`
+	Quantity      int        `json:"quantity" gorm:"not null"`
+	QuantityAfter int        `json:"quantityAfter" gorm:"not null"`
+	ReasonCode    string     `json:"reasonCode" gorm:"type:varchar(50)"`
+	Reference     string     `json:"reference" gorm:"type:varchar(255);index"`
+	Note          string     `json:"note" gorm:"type:varchar(255)"`
+	ActorID       *uuid.UUID `json:"actorID" gorm:"type:char(36);index"`
+	AuthMethod    string     `json:"authMethod" gorm:"type:varchar(20)"`
+	CreatedAt     time.Time  `json:"createdAt" gorm:"autoCreateTime;index:idx_stock_movement_variant_created"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (stockReservation *StockReservation) BeforeCreate(tx *gorm.DB) (err error) {
+	stockReservation.ID = uuid.New()
+	return
+}
+
+type StockReservation struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	VariantID   uuid.UUID  `json:"variantID" gorm:"type:char(36);index;not null"`
+	Quantity    int        `json:"quantity" gorm:"not null"`
+	Status      string     `json:"status" gorm:"type:varchar(20);index:idx_stock_reservation_status_expires;not null"`
+	Reference   string     `json:"reference" gorm:"type:varchar(255);index"`
+	CreatedByID uuid.UUID  `json:"createdByID" gorm:"type:char(36);not null"`
+	ExpiresAt   time.Time  `json:"expiresAt" gorm:"index:idx_stock_reservation_status_expires;not null"`
+	ConfirmedAt *time.Time `json:"confirmedAt"`
+	ReleasedAt  *time.Time `json:"releasedAt"`
+	CreatedAt   time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (stockTake *StockTake) BeforeCreate(tx *gorm.DB) (err error) {
+	stockTake.ID = uuid.New()
+	return
+}
+
+type StockTake struct {
+	ID             uuid.UUID       `json:"id" gorm:"type:char(36);primary_key"`
+	OrganizationID uuid.UUID       `json:"organizationID" gorm:"type:char(36);index;not null"`
+	LocationID     uuid.UUID       `json:"locationID" gorm:"type:char(36);not null"`
+	Status         string          `json:"status" gorm:"type:varchar(20);not null"`
+	Note           string          `json:"note" gorm:"type:varchar(255)"`
+	OpenedByID     uuid.UUID       `json:"openedByID" gorm:"type:char(36);not null"`
+	PostedByID     *uuid.UUID      `json:"postedByID" gorm:"type:char(36)"`
+	PostedAt       *time.Time      `json:"postedAt"`
+	CancelledAt    *time.Time      `json:"cancelledAt"`
+	CreatedAt      time.Time       `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time       `json:"updatedAt" gorm:"autoUpdateTime"`
+	Location       *Location       `json:"location,omitempty" gorm:"foreignKey:LocationID"`
+	Lines          []StockTakeLine `json:"lines,omitempty" gorm:"foreignKey:StockTakeID"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (stockTakeLine *StockTakeLine) BeforeCreate(tx *gorm.DB) (err error) {
+	stockTakeLine.ID = uuid.New()
+	return
+}
+
+type StockTakeLine struct {
+	ID              uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	StockTakeID     uuid.UUID  `json:"stockTakeID" gorm:"type:char(36);not null;uniqueIndex:idx_stock_take_line_variant"`
+	VariantID       uuid.UUID  `json:"variantID" gorm:"type:char(36);not null;uniqueIndex:idx_stock_take_line_variant"`
+	CountedQuantity int        `json:"countedQuantity" gorm:"not null"`
+	CountedByID     uuid.UUID  `json:"countedByID" gorm:"type:char(36);not null"`
+	CountedAt       time.Time  `json:"countedAt" gorm:"not null"`
+	SystemQuantity  *int       `json:"systemQuantity"`
+	Variance        *int       `json:"variance"`
+	MovementID      *uuid.UUID `json:"movementID" gorm:"type:char(36)"`
+	Variant         *Variant   `json:"variant,omitempty" gorm:"foreignKey:VariantID"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (supplier *Supplier) BeforeCreate(tx *gorm.DB) (err error) {
+	supplier.ID = uuid.New()
+	return
+}
+
+type Supplier struct {
+	ID             uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	OrganizationID uuid.UUID `json:"organizationID" gorm:"type:char(36);index;not null"`
+	Name           string    `json:"name" gorm:"type:varchar(255);not null"`
+	Email          string    `json:"email" gorm:"type:varchar(255)"`
+	Phone          string    `json:"phone" gorm:"type:varchar(50)"`
+	Note           string    `json:"note" gorm:"type:varchar(255)"`
+	CreatedAt      time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
@@ -12,11 +12,28 @@ func (variant *Variant) BeforeCreate(tx *gorm.DB) (err error) {
 	return
 }
 
+func (variant *Variant) AfterFind(tx *gorm.DB) (err error) {
+	variant.Available = variant.Quantity - variant.Reserved
+	return
+}
+
 type Variant struct {
-	ID          uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
-	VariantName string    `json:"variantName" gorm:"type:varchar(255);not null"`
-	Quantity    int       `json:"quantity" gorm:"type:integer;not null"`
-	ProductID   uuid.UUID `json:"productID" gorm:"type:char(36);not null"`
-	CreatedAt   time.Time `json:"createdAt" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+	ID                uuid.UUID      `json:"id" gorm:"type:char(36);primary_key"`
+	VariantName       string         `json:"variantName" gorm:"type:varchar(255);not null"`
+	Quantity          int            `json:"quantity" gorm:"type:integer;not null"`
+	Reserved          int            `json:"reserved" gorm:"type:integer;not null;default:0"`
+	Available         int            `json:"available" gorm:"-"`
+	OnOrder           int            `json:"onOrder" gorm:"-"`
+	LowStockThreshold *int           `json:"lowStockThreshold"`
+	ReorderPoint      *int           `json:"reorderPoint"`
+	Price             *int64         `json:"price"`
+	CompareAtPrice    *int64         `json:"compareAtPrice"`
+	SalePrice         *int64         `json:"salePrice"`
+	SaleStartsAt      *time.Time     `json:"saleStartsAt"`
+	SaleEndsAt        *time.Time     `json:"saleEndsAt"`
+	EffectivePrice    *ResolvedPrice `json:"effectivePrice,omitempty" gorm:"-"`
+	ProductID         uuid.UUID      `json:"productID" gorm:"type:char(36);not null"`
+	CreatedAt         time.Time      `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time      `json:"updatedAt" gorm:"autoUpdateTime"`
+	StockLevels       []StockLevel   `json:"stockLevels,omitempty" gorm:"foreignKey:VariantID"`
 }
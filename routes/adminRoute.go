@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"golang-final-project/controllers"
+	"golang-final-project/middlewares"
+	"golang-final-project/services"
+
+	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func AdminRoute(route *gin.Engine, db *gorm.DB, cld *cloudinary.Cloudinary) {
+	route.GET("/api/admins/me", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+		controllers.GetMyProfile(c, db)
+	})
+	route.PATCH("/api/admins/me", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+		controllers.UpdateMyProfile(c, db)
+	})
+	route.DELETE("/api/admins/me", middlewares.AuthenticateJWT(), middlewares.RequireMFA(), func(c *gin.Context) {
+		controllers.DeleteMyAccount(c, db, cld)
+	})
+	route.GET("/api/admins", middlewares.AuthenticateJWT(), middlewares.RequirePermission(services.PermissionAdminsManage), func(c *gin.Context) {
+		controllers.GetAllAdminsWithPagination(c, db)
+	})
+	route.PUT("/api/admins/:id/role", middlewares.AuthenticateJWT(), middlewares.RequirePermission(services.PermissionAdminsManage), func(c *gin.Context) {
+		controllers.UpdateAdminRole(c, db)
+	})
+	route.POST("/api/admins/:id/deactivate", middlewares.AuthenticateJWT(), middlewares.RequirePermission(services.PermissionAdminsManage), func(c *gin.Context) {
+		controllers.DeactivateAdmin(c, db)
+	})
+	route.POST("/api/admins/:id/reactivate", middlewares.AuthenticateJWT(), middlewares.RequirePermission(services.PermissionAdminsManage), func(c *gin.Context) {
+		controllers.ReactivateAdmin(c, db)
+	})
+}
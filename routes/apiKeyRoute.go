@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"golang-final-project/controllers"
+	"golang-final-project/middlewares"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// API keys are managed with a JWT only, so a leaked key cannot mint new ones.
+func APIKeyRoute(route *gin.Engine, db *gorm.DB) {
+	route.POST("/api/api-keys", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+		controllers.CreateAPIKey(c, db)
+	})
+	route.GET("/api/api-keys", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+		controllers.GetMyAPIKeys(c, db)
+	})
+	route.DELETE("/api/api-keys/:id", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+		controllers.RevokeAPIKey(c, db)
+	})
+}
@@ -2,6 +2,8 @@ package routes
 
 import (
 	"golang-final-project/controllers"
+	"golang-final-project/middlewares"
+	"golang-final-project/services"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -15,4 +17,40 @@ func AuthRoute(route *gin.Engine, db *gorm.DB) {
 	route.POST("api/auth/login", func(c *gin.Context) {
 		controllers.Login(c, db)
 	})
+
+	route.POST("/api/auth/verify-email", func(c *gin.Context) {
+		controllers.VerifyEmail(c, db)
+	})
+
+	route.POST("/api/auth/verify-email/resend", func(c *gin.Context) {
+		controllers.ResendVerificationEmail(c, db)
+	})
+
+	route.POST("/api/auth/invitations", middlewares.AuthenticateJWT(), middlewares.RequirePermission(services.PermissionAdminsManage), func(c *gin.Context) {
+		controllers.CreateInvitation(c, db)
+	})
+
+	route.POST("/api/auth/password/forgot", func(c *gin.Context) {
+		controllers.ForgotPassword(c, db)
+	})
+
+	route.POST("/api/auth/password/reset", func(c *gin.Context) {
+		controllers.ResetPassword(c, db)
+	})
+
+	route.PUT("/api/auth/password", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+		controllers.ChangePassword(c, db)
+	})
+
+	route.POST("/api/auth/refresh", func(c *gin.Context) {
+		controllers.Refresh(c, db)
+	})
+
+	route.POST("/api/auth/logout", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+		controllers.Logout(c, db)
+	})
+
+	route.POST("/api/auth/logout-all", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+		controllers.LogoutAll(c, db)
+	})
 }
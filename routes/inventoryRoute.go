@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"golang-final-project/controllers"
+	"golang-final-project/middlewares"
+	"golang-final-project/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func InventoryRoute(route *gin.Engine, db *gorm.DB) {
+	route.GET("/api/inventory/low-stock", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsRead), func(c *gin.Context) {
+		controllers.GetLowStockReport(c, db)
+	})
+}
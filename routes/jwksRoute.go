@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"golang-final-project/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func JWKSRoute(route *gin.Engine) {
+	route.GET("/.well-known/jwks.json", controllers.GetJWKS)
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"golang-final-project/controllers"
+	"golang-final-project/middlewares"
+	"golang-final-project/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func LocationRoute(route *gin.Engine, db *gorm.DB) {
+	route.POST("/api/inventory/locations", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionLocationsManage), func(c *gin.Context) {
+		controllers.CreateLocation(c, db)
+	})
+	route.GET("/api/inventory/locations", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsRead), func(c *gin.Context) {
+		controllers.GetAllLocations(c, db)
+	})
+	route.GET("/api/inventory/locations/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsRead), func(c *gin.Context) {
+		controllers.GetLocationByID(c, db)
+	})
+	route.PUT("/api/inventory/locations/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionLocationsManage), func(c *gin.Context) {
+		controllers.UpdateLocationByID(c, db)
+	})
+	route.DELETE("/api/inventory/locations/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionLocationsManage), func(c *gin.Context) {
+		controllers.DeleteLocationByID(c, db)
+	})
+}
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"golang-final-project/controllers"
+	"golang-final-project/middlewares"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func MFARoute(route *gin.Engine, db *gorm.DB) {
+	route.POST("/api/auth/mfa/totp/enroll", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+		controllers.EnrollTOTP(c, db)
+	})
+	route.POST("/api/auth/mfa/totp/confirm", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+		controllers.ConfirmTOTP(c, db)
+	})
+	route.POST("/api/auth/mfa/verify", func(c *gin.Context) {
+		controllers.VerifyMFA(c, db)
+	})
+	route.POST("/api/auth/mfa/recovery-codes", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+		controllers.RegenerateRecoveryCodes(c, db)
+	})
+	route.DELETE("/api/auth/mfa", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+		controllers.DisableMFA(c, db)
+	})
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"golang-final-project/controllers"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func OIDCRoute(route *gin.Engine, db *gorm.DB) {
+	route.GET("/api/auth/oidc/login", func(c *gin.Context) {
+		controllers.OIDCLogin(c, db)
+	})
+	route.POST("/api/auth/oidc/callback", func(c *gin.Context) {
+		controllers.OIDCCallback(c, db)
+	})
+	route.POST("/api/auth/oidc/link", func(c *gin.Context) {
+		controllers.OIDCLink(c, db)
+	})
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"golang-final-project/controllers"
+	"golang-final-project/middlewares"
+	"golang-final-project/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func OrderRoute(route *gin.Engine, db *gorm.DB) {
+	route.POST("/api/orders", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionOrdersWrite), func(c *gin.Context) {
+		controllers.CreateOrder(c, db)
+	})
+	route.GET("/api/orders", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionOrdersRead), func(c *gin.Context) {
+		controllers.GetAllOrders(c, db)
+	})
+	route.GET("/api/orders/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionOrdersRead), func(c *gin.Context) {
+		controllers.GetOrderByID(c, db)
+	})
+	route.POST("/api/orders/:id/confirm", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionOrdersWrite), func(c *gin.Context) {
+		controllers.ConfirmOrder(c, db)
+	})
+	route.POST("/api/orders/:id/fulfil", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionOrdersWrite), func(c *gin.Context) {
+		controllers.FulfilOrder(c, db)
+	})
+	route.POST("/api/orders/:id/cancel", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionOrdersWrite), func(c *gin.Context) {
+		controllers.CancelOrder(c, db)
+	})
+}
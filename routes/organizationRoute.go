@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"golang-final-project/controllers"
+	"golang-final-project/middlewares"
+	"golang-final-project/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func OrganizationRoute(route *gin.Engine, db *gorm.DB) {
+	route.POST("/api/organizations", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+		controllers.CreateOrganization(c, db)
+	})
+	route.GET("/api/organizations", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+		controllers.GetMyOrganizations(c, db)
+	})
+	route.GET("/api/organizations/:id", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+		controllers.GetOrganizationByID(c, db)
+	})
+	route.PUT("/api/organizations/:id/stock-alerts", middlewares.AuthenticateJWT(), middlewares.RequirePermission(services.PermissionAdminsManage), func(c *gin.Context) {
+		controllers.UpdateOrganizationStockAlerts(c, db)
+	})
+	route.POST("/api/organizations/:id/members", middlewares.AuthenticateJWT(), middlewares.RequirePermission(services.PermissionAdminsManage), func(c *gin.Context) {
+		controllers.AddOrganizationMember(c, db)
+	})
+	route.POST("/api/invitations/accept", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+		controllers.AcceptOrganizationInvitation(c, db)
+	})
+	route.DELETE("/api/organizations/:id/members/:adminID", middlewares.AuthenticateJWT(), middlewares.RequirePermission(services.PermissionAdminsManage), func(c *gin.Context) {
+		controllers.RemoveOrganizationMember(c, db)
+	})
+}
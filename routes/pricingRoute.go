@@ -0,0 +1,51 @@
+package routes
+
+import (
+	"golang-final-project/controllers"
+	"golang-final-project/middlewares"
+	"golang-final-project/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func PricingRoute(route *gin.Engine, db *gorm.DB) {
+	route.POST("/api/customer-groups", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionProductsWrite), func(c *gin.Context) {
+		controllers.CreateCustomerGroup(c, db)
+	})
+	route.GET("/api/customer-groups", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionProductsRead), func(c *gin.Context) {
+		controllers.GetAllCustomerGroups(c, db)
+	})
+	route.GET("/api/customer-groups/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionProductsRead), func(c *gin.Context) {
+		controllers.GetCustomerGroupByID(c, db)
+	})
+	route.PUT("/api/customer-groups/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionProductsWrite), func(c *gin.Context) {
+		controllers.UpdateCustomerGroupByID(c, db)
+	})
+	route.DELETE("/api/customer-groups/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionProductsWrite), func(c *gin.Context) {
+		controllers.DeleteCustomerGroupByID(c, db)
+	})
+
+	route.POST("/api/price-lists", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionProductsWrite), func(c *gin.Context) {
+		controllers.CreatePriceList(c, db)
+	})
+	route.GET("/api/price-lists", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionProductsRead), func(c *gin.Context) {
+		controllers.GetAllPriceLists(c, db)
+	})
+	route.GET("/api/price-lists/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionProductsRead), func(c *gin.Context) {
+		controllers.GetPriceListByID(c, db)
+	})
+	route.PUT("/api/price-lists/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionProductsWrite), func(c *gin.Context) {
+		controllers.UpdatePriceList(c, db)
+	})
+	route.DELETE("/api/price-lists/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionProductsWrite), func(c *gin.Context) {
+		controllers.DeletePriceList(c, db)
+	})
+	route.PUT("/api/price-lists/:id/prices", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionProductsWrite), func(c *gin.Context) {
+		controllers.SetPriceListPrices(c, db)
+	})
+
+	route.GET("/api/products/variants/:id/price", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsRead), func(c *gin.Context) {
+		controllers.ResolveVariantPrice(c, db)
+	})
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"golang-final-project/controllers"
 	"golang-final-project/middlewares"
+	"golang-final-project/services"
 
 	"github.com/cloudinary/cloudinary-go/v2"
 	"github.com/gin-gonic/gin"
@@ -10,19 +11,25 @@ import (
 )
 
 func ProductRoute(route *gin.Engine, db *gorm.DB, cld *cloudinary.Cloudinary) {
-	route.POST("/api/products", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+	route.POST("/api/products", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionProductsWrite), func(c *gin.Context) {
 		controllers.CreateProduct(c, db, cld)
 	})
-	route.GET("/api/products", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+	route.GET("/api/products", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionProductsRead), func(c *gin.Context) {
 		controllers.GetAllProductsWithPagination(c, db)
 	})
-	route.GET("/api/products/:id", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+	route.GET("/api/products/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionProductsRead), func(c *gin.Context) {
 		controllers.GetProductByID(c, db)
 	})
-	route.PUT("/api/products/:id", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+	route.PUT("/api/products/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionProductsWrite), func(c *gin.Context) {
 		controllers.UpdateProductByID(c, db, cld)
 	})
-	route.DELETE("/api/products/:id", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+	route.DELETE("/api/products/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionProductsWrite), middlewares.RequireMFA(), func(c *gin.Context) {
 		controllers.DeleteProductByID(c, db, cld)
 	})
+	route.PUT("/api/products/:id/thresholds", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionProductsWrite), func(c *gin.Context) {
+		controllers.UpdateProductThresholds(c, db)
+	})
+	route.PUT("/api/products/:id/price", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionProductsWrite), func(c *gin.Context) {
+		controllers.UpdateProductPrice(c, db)
+	})
 }
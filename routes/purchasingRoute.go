@@ -0,0 +1,53 @@
+package routes
+
+import (
+	"golang-final-project/controllers"
+	"golang-final-project/middlewares"
+	"golang-final-project/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func PurchasingRoute(route *gin.Engine, db *gorm.DB) {
+	route.POST("/api/suppliers", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionPurchasingWrite), func(c *gin.Context) {
+		controllers.CreateSupplier(c, db)
+	})
+	route.GET("/api/suppliers", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsRead), func(c *gin.Context) {
+		controllers.GetAllSuppliers(c, db)
+	})
+	route.GET("/api/suppliers/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsRead), func(c *gin.Context) {
+		controllers.GetSupplierByID(c, db)
+	})
+	route.PUT("/api/suppliers/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionPurchasingWrite), func(c *gin.Context) {
+		controllers.UpdateSupplierByID(c, db)
+	})
+	route.DELETE("/api/suppliers/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionPurchasingWrite), func(c *gin.Context) {
+		controllers.DeleteSupplierByID(c, db)
+	})
+
+	route.POST("/api/purchase-orders", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionPurchasingWrite), func(c *gin.Context) {
+		controllers.CreatePurchaseOrder(c, db)
+	})
+	route.GET("/api/purchase-orders", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsRead), func(c *gin.Context) {
+		controllers.GetAllPurchaseOrders(c, db)
+	})
+	route.GET("/api/purchase-orders/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsRead), func(c *gin.Context) {
+		controllers.GetPurchaseOrderByID(c, db)
+	})
+	route.PUT("/api/purchase-orders/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionPurchasingWrite), func(c *gin.Context) {
+		controllers.UpdatePurchaseOrder(c, db)
+	})
+	route.POST("/api/purchase-orders/:id/submit", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionPurchasingWrite), func(c *gin.Context) {
+		controllers.SubmitPurchaseOrder(c, db)
+	})
+	route.POST("/api/purchase-orders/:id/cancel", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionPurchasingWrite), func(c *gin.Context) {
+		controllers.CancelPurchaseOrder(c, db)
+	})
+	route.POST("/api/purchase-orders/:id/close", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionPurchasingWrite), func(c *gin.Context) {
+		controllers.ClosePurchaseOrder(c, db)
+	})
+	route.POST("/api/purchase-orders/:id/receive", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionInventoryAdjust), func(c *gin.Context) {
+		controllers.ReceivePurchaseOrder(c, db)
+	})
+}
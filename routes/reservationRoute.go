@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"golang-final-project/controllers"
+	"golang-final-project/middlewares"
+	"golang-final-project/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func ReservationRoute(route *gin.Engine, db *gorm.DB) {
+	route.POST("/api/inventory/reservations", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionInventoryAdjust), func(c *gin.Context) {
+		controllers.CreateReservation(c, db)
+	})
+	route.GET("/api/inventory/reservations/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsRead), func(c *gin.Context) {
+		controllers.GetReservationByID(c, db)
+	})
+	route.POST("/api/inventory/reservations/:id/confirm", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionInventoryAdjust), func(c *gin.Context) {
+		controllers.ConfirmReservation(c, db)
+	})
+	route.POST("/api/inventory/reservations/:id/release", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionInventoryAdjust), func(c *gin.Context) {
+		controllers.ReleaseReservation(c, db)
+	})
+}
@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"golang-final-project/controllers"
+	"golang-final-project/middlewares"
+	"golang-final-project/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func StockTakeRoute(route *gin.Engine, db *gorm.DB) {
+	route.POST("/api/inventory/stock-takes", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionInventoryAdjust), func(c *gin.Context) {
+		controllers.OpenStockTake(c, db)
+	})
+	route.GET("/api/inventory/stock-takes", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsRead), func(c *gin.Context) {
+		controllers.GetAllStockTakes(c, db)
+	})
+	route.GET("/api/inventory/stock-takes/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsRead), func(c *gin.Context) {
+		controllers.GetStockTakeByID(c, db)
+	})
+	route.POST("/api/inventory/stock-takes/:id/counts", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionInventoryAdjust), func(c *gin.Context) {
+		controllers.SubmitStockCounts(c, db)
+	})
+	route.GET("/api/inventory/stock-takes/:id/report", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsRead), func(c *gin.Context) {
+		controllers.GetStockTakeReport(c, db)
+	})
+	route.POST("/api/inventory/stock-takes/:id/post", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionInventoryAdjust), func(c *gin.Context) {
+		controllers.PostStockTake(c, db)
+	})
+	route.POST("/api/inventory/stock-takes/:id/cancel", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionInventoryAdjust), func(c *gin.Context) {
+		controllers.CancelStockTake(c, db)
+	})
+}
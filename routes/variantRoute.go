@@ -3,25 +3,44 @@ package routes
 import (
 	"golang-final-project/controllers"
 	"golang-final-project/middlewares"
+	"golang-final-project/services"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 func VariantRoutes(route *gin.Engine, db *gorm.DB) {
-	route.POST("/api/products/variants", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+	route.POST("/api/products/variants", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsWrite), func(c *gin.Context) {
 		controllers.CreateVariant(c, db)
 	})
-	route.GET("/api/products/variants", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+	route.GET("/api/products/variants", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsRead), func(c *gin.Context) {
 		controllers.GetAllVariantsWithPagination(c, db)
 	})
-	route.GET("/api/products/variants/:id", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+	route.GET("/api/products/variants/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsRead), func(c *gin.Context) {
 		controllers.GetVariantByID(c, db)
 	})
-	route.PUT("/api/products/variants/:id", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+	route.PUT("/api/products/variants/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsWrite), func(c *gin.Context) {
 		controllers.UpdateVariantByID(c, db)
 	})
-	route.DELETE("/api/products/variants/:id", middlewares.AuthenticateJWT(), func(c *gin.Context) {
+	route.DELETE("/api/products/variants/:id", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsWrite), middlewares.RequireMFA(), func(c *gin.Context) {
 		controllers.DeleteVariantByID(c, db)
 	})
+	route.PUT("/api/products/variants/:id/thresholds", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsWrite), func(c *gin.Context) {
+		controllers.UpdateVariantThresholds(c, db)
+	})
+	route.PUT("/api/products/variants/:id/price", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsWrite), func(c *gin.Context) {
+		controllers.UpdateVariantPrice(c, db)
+	})
+	route.POST("/api/products/variants/:id/movements", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionInventoryAdjust), func(c *gin.Context) {
+		controllers.CreateStockMovement(c, db)
+	})
+	route.POST("/api/products/variants/:id/stock/adjust", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionInventoryAdjust), func(c *gin.Context) {
+		controllers.AdjustStock(c, db)
+	})
+	route.POST("/api/products/variants/:id/transfers", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionInventoryAdjust), func(c *gin.Context) {
+		controllers.TransferStock(c, db)
+	})
+	route.GET("/api/products/variants/:id/movements", middlewares.Authenticate(db), middlewares.RequirePermission(services.PermissionVariantsRead), func(c *gin.Context) {
+		controllers.GetStockMovements(c, db)
+	})
 }
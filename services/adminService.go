@@ -1,18 +1,336 @@
 package services
 
 import (
+	"errors"
 	"golang-final-project/models"
+	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrAdminDeactivated      = errors.New("admin account is deactivated")
+	ErrAdminOwnsProducts     = errors.New("admin still owns products")
+	ErrInvalidProductPolicy  = errors.New("invalid product policy")
+	ErrInvalidTransferTarget = errors.New("invalid product transfer target")
+	ErrLastOwner             = errors.New("cannot remove the last active owner")
+)
+
 func CreateAdmin(db *gorm.DB, admin *models.Admin) error {
 	return db.Create(&admin).Error
 }
 
+func GetAdminByID(db *gorm.DB, id uuid.UUID) (*models.Admin, error) {
+	var admin models.Admin
+	err := db.First(&admin, id).Error
+
+	return &admin, err
+}
+
+// GetTenantAdminByID returns gorm.ErrRecordNotFound for admins outside the
+// tenant's organizations.
+func GetTenantAdminByID(db *gorm.DB, tenant Tenant, id uuid.UUID) (*models.Admin, error) {
+	var admin models.Admin
+	err := db.Scopes(AdminTenantScope(tenant)).Where("admins.id = ?", id).First(&admin).Error
+
+	return &admin, err
+}
+
+func CountAdmins(db *gorm.DB) (int64, error) {
+	var count int64
+	err := db.Model(&models.Admin{}).Count(&count).Error
+
+	return count, err
+}
+
+func UpdateAdminRole(db *gorm.DB, id uuid.UUID, role string) error {
+	return db.Model(&models.Admin{}).Where("id = ?", id).Update("role", role).Error
+}
+
+func UpdateAdminPassword(db *gorm.DB, id uuid.UUID, encryptedPassword string) error {
+	return db.Model(&models.Admin{}).Where("id = ?", id).Update("password", encryptedPassword).Error
+}
+
+// ChangeAdminPassword stores the new password and signs out every session in
+// one transaction, so a session opened with the old password cannot outlive it.
+func ChangeAdminPassword(db *gorm.DB, id uuid.UUID, encryptedPassword string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := UpdateAdminPassword(tx, id, encryptedPassword); err != nil {
+			return err
+		}
+
+		return RevokeAdminSessions(tx, id)
+	})
+}
+
+// EnsureOwner promotes the earliest registered admin to owner when no owner
+// exists yet, so that somebody can manage roles after the upgrade.
+func EnsureOwner(db *gorm.DB) error {
+	var owners int64
+	if err := db.Model(&models.Admin{}).Where("role = ?", RoleOwner).Count(&owners).Error; err != nil {
+		return err
+	}
+	if owners > 0 {
+		return nil
+	}
+
+	var admin models.Admin
+	err := db.Order("created_at").First(&admin).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return UpdateAdminRole(db, admin.ID, RoleOwner)
+}
+
 func GetAdminByEmail(db *gorm.DB, email string) (*models.Admin, error) {
 	var admin models.Admin
 	err := db.Where("email = ?", email).First(&admin).Error
 
 	return &admin, err
 }
+
+func GetAllAdminsWithPaginationAndSearch(db *gorm.DB, tenant Tenant, page, pageSize int, search string) ([]models.Admin, error) {
+	var admins []models.Admin
+
+	offset := (page - 1) * pageSize
+
+	query := db.Scopes(AdminTenantScope(tenant)).Order("created_at").Offset(offset).Limit(pageSize)
+
+	if search != "" {
+		query = query.Where("name LIKE ? OR email LIKE ?", "%"+search+"%", "%"+search+"%")
+	}
+
+	if err := query.Find(&admins).Error; err != nil {
+		return nil, err
+	}
+
+	return admins, nil
+}
+
+func UpdateAdminName(db *gorm.DB, id uuid.UUID, name string) error {
+	return db.Model(&models.Admin{}).Where("id = ?", id).Update("name", name).Error
+}
+
+// DeactivateAdmin blocks the admin from logging in and signs out all their
+// sessions. Their API keys stop working until the admin is reactivated. The
+// last active owner cannot be deactivated.
+func DeactivateAdmin(db *gorm.DB, admin *models.Admin) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := checkLastOwner(tx, admin); err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Admin{}).Where("id = ? AND deactivated_at IS NULL", admin.ID).Update("deactivated_at", time.Now()).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return RevokeAdminSessions(db, admin.ID)
+}
+
+// checkLastOwner returns ErrLastOwner when removing the admin would leave
+// other admins without an active owner.
+func checkLastOwner(tx *gorm.DB, admin *models.Admin) error {
+	if admin.Role != RoleOwner {
+		return nil
+	}
+
+	var others, owners int64
+	if err := tx.Model(&models.Admin{}).Where("id <> ?", admin.ID).Count(&others).Error; err != nil {
+		return err
+	}
+	err := tx.Model(&models.Admin{}).
+		Where("id <> ? AND role = ? AND deactivated_at IS NULL", admin.ID, RoleOwner).
+		Count(&owners).Error
+	if err != nil {
+		return err
+	}
+	if others > 0 && owners == 0 {
+		return ErrLastOwner
+	}
+
+	return nil
+}
+
+func ReactivateAdmin(db *gorm.DB, id uuid.UUID) error {
+	return db.Model(&models.Admin{}).Where("id = ?", id).Update("deactivated_at", nil).Error
+}
+
+// What happens to the products an admin created when their account is
+// deleted.
+const (
+	ProductPolicyBlock    = "block"
+	ProductPolicyTransfer = "transfer"
+	ProductPolicyCascade  = "cascade"
+)
+
+func IsValidProductPolicy(policy string) bool {
+	switch policy {
+	case ProductPolicyBlock, ProductPolicyTransfer, ProductPolicyCascade:
+		return true
+	}
+	return false
+}
+
+// DeleteAdminAccount deletes the admin and everything tied to their login.
+// Products they created are handled according to policy:
+//   - block refuses to delete an admin who still has products
+//   - transfer hands them to transferTo, who joins their organizations if
+//     needed
+//   - cascade deletes them with their variants
+//
+// The image URLs of cascaded products are returned so the caller can remove
+// them from storage. Organizations the admin was the last member of are
+// deleted when empty and otherwise block the deletion.
+func DeleteAdminAccount(db *gorm.DB, admin *models.Admin, policy string, transferTo *uuid.UUID) ([]string, error) {
+	if !IsValidProductPolicy(policy) {
+		return nil, ErrInvalidProductPolicy
+	}
+
+	var imageURLs []string
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := checkLastOwner(tx, admin); err != nil {
+			return err
+		}
+
+		var products []models.Product
+		if err := tx.Where("admin_id = ?", admin.ID).Find(&products).Error; err != nil {
+			return err
+		}
+
+		if len(products) > 0 {
+			switch policy {
+			case ProductPolicyBlock:
+				return ErrAdminOwnsProducts
+			case ProductPolicyTransfer:
+				if err := transferProducts(tx, admin.ID, transferTo, products); err != nil {
+					return err
+				}
+			case ProductPolicyCascade:
+				productIDs := tx.Model(&models.Product{}).Select("id").Where("admin_id = ?", admin.ID)
+				variantIDs := tx.Model(&models.Variant{}).Select("id").Where("product_id IN (?)", productIDs)
+				if err := deleteVariantRecords(tx, variantIDs); err != nil {
+					return err
+				}
+				if err := tx.Where("product_id IN (?)", productIDs).Delete(&models.Variant{}).Error; err != nil {
+					return err
+				}
+				if err := tx.Where("admin_id = ?", admin.ID).Delete(&models.Product{}).Error; err != nil {
+					return err
+				}
+				for _, product := range products {
+					imageURLs = append(imageURLs, product.ImageUrl)
+				}
+			}
+		}
+
+		if err := leaveOrganizations(tx, admin.ID); err != nil {
+			return err
+		}
+
+		err := tx.Where("invited_by_id = ? AND accepted_at IS NULL", admin.ID).Delete(&models.Invitation{}).Error
+		if err != nil {
+			return err
+		}
+
+		for _, model := range []interface{}{
+			&models.RefreshToken{},
+			&models.APIKey{},
+			&models.RecoveryCode{},
+			&models.EmailVerification{},
+			&models.PasswordReset{},
+		} {
+			if err := tx.Where("admin_id = ?", admin.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(&models.Admin{}, admin.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := TokenRevocations.RevokeAdminTokens(admin.ID, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return imageURLs, nil
+}
+
+func transferProducts(tx *gorm.DB, adminID uuid.UUID, transferTo *uuid.UUID, products []models.Product) error {
+	if transferTo == nil || *transferTo == adminID {
+		return ErrInvalidTransferTarget
+	}
+
+	target, err := GetAdminByID(tx, *transferTo)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidTransferTarget
+	}
+	if err != nil {
+		return err
+	}
+	if target.DeactivatedAt != nil {
+		return ErrInvalidTransferTarget
+	}
+
+	joined := make(map[uuid.UUID]bool)
+	for _, product := range products {
+		if joined[product.OrganizationID] {
+			continue
+		}
+		joined[product.OrganizationID] = true
+
+		isMember, err := IsOrganizationMember(tx, product.OrganizationID, target.ID)
+		if err != nil {
+			return err
+		}
+		if !isMember {
+			if err := AddOrganizationMember(tx, product.OrganizationID, target.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Model(&models.Product{}).Where("admin_id = ?", adminID).Update("admin_id", target.ID).Error
+}
+
+func leaveOrganizations(tx *gorm.DB, adminID uuid.UUID) error {
+	var memberships []models.Membership
+	if err := tx.Where("admin_id = ?", adminID).Find(&memberships).Error; err != nil {
+		return err
+	}
+
+	for _, membership := range memberships {
+		var members, products int64
+		if err := tx.Model(&models.Membership{}).Where("organization_id = ?", membership.OrganizationID).Count(&members).Error; err != nil {
+			return err
+		}
+		if members > 1 {
+			continue
+		}
+
+		if err := tx.Model(&models.Product{}).Where("organization_id = ?", membership.OrganizationID).Count(&products).Error; err != nil {
+			return err
+		}
+		if products > 0 {
+			return ErrLastOrganizationMember
+		}
+
+		if err := tx.Where("organization_id = ?", membership.OrganizationID).Delete(&models.Invitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Organization{}, membership.OrganizationID).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Where("admin_id = ?", adminID).Delete(&models.Membership{}).Error
+}
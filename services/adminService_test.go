@@ -0,0 +1,152 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func createTestAdmin(t *testing.T, db *gorm.DB, email, role string) *models.Admin {
+	t.Helper()
+
+	admin := &models.Admin{Name: email, Email: email, Password: "x", Role: role}
+	if err := CreateAdmin(db, admin); err != nil {
+		t.Fatalf("CreateAdmin: %v", err)
+	}
+	if _, err := CreatePersonalOrganization(db, admin); err != nil {
+		t.Fatalf("CreatePersonalOrganization: %v", err)
+	}
+
+	return admin
+}
+
+func TestAdminTenantScope(t *testing.T) {
+	db := openTestDB(t)
+
+	caller := createTestAdmin(t, db, "caller@example.com", RoleOwner)
+	colleague := createTestAdmin(t, db, "colleague@example.com", RoleEditor)
+	stranger := createTestAdmin(t, db, "stranger@example.com", RoleOwner)
+
+	organizations, err := GetOrganizationsByAdminID(db, caller.ID)
+	if err != nil || len(organizations) != 1 {
+		t.Fatalf("organizations = %v, %v", organizations, err)
+	}
+	if err := AddOrganizationMember(db, organizations[0].ID, colleague.ID); err != nil {
+		t.Fatalf("AddOrganizationMember: %v", err)
+	}
+
+	tenant := Tenant{AdminID: caller.ID}
+
+	admins, err := GetAllAdminsWithPaginationAndSearch(db, tenant, 1, 10, "example.com")
+	if err != nil {
+		t.Fatalf("GetAllAdminsWithPaginationAndSearch: %v", err)
+	}
+	if len(admins) != 2 {
+		t.Fatalf("listed %d admins, want the caller and their colleague", len(admins))
+	}
+	for _, admin := range admins {
+		if admin.ID == stranger.ID {
+			t.Fatalf("listed an admin from another organization")
+		}
+	}
+
+	if _, err := GetTenantAdminByID(db, tenant, stranger.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("GetTenantAdminByID for a stranger error = %v, want gorm.ErrRecordNotFound", err)
+	}
+
+	crossTenant := Tenant{AdminID: caller.ID, CrossTenant: true}
+	if _, err := GetTenantAdminByID(db, crossTenant, stranger.ID); err != nil {
+		t.Fatalf("cross-tenant GetTenantAdminByID: %v", err)
+	}
+}
+
+func TestDeactivateAdminKeepsAnActiveOwner(t *testing.T) {
+	db := openTestDB(t)
+
+	owner := createTestAdmin(t, db, "owner@example.com", RoleOwner)
+	createTestAdmin(t, db, "editor@example.com", RoleEditor)
+
+	if err := DeactivateAdmin(db, owner); !errors.Is(err, ErrLastOwner) {
+		t.Fatalf("DeactivateAdmin error = %v, want ErrLastOwner", err)
+	}
+
+	createTestAdmin(t, db, "second-owner@example.com", RoleOwner)
+
+	if err := DeactivateAdmin(db, owner); err != nil {
+		t.Fatalf("DeactivateAdmin with a second owner: %v", err)
+	}
+
+	deactivated, err := GetAdminByID(db, owner.ID)
+	if err != nil || deactivated.DeactivatedAt == nil {
+		t.Fatalf("owner not deactivated: %v, %v", deactivated, err)
+	}
+}
+
+func TestChangeAdminPasswordKeepsTheNewSession(t *testing.T) {
+	previous := TokenRevocations
+	t.Cleanup(func() { TokenRevocations = previous })
+
+	for _, store := range []string{"memory", "gorm"} {
+		t.Run(store, func(t *testing.T) {
+			db := openTestDB(t)
+			TokenRevocations = NewMemoryRevocationStore()
+			if store == "gorm" {
+				TokenRevocations = NewGormRevocationStore(db)
+			}
+
+			admin := createTestAdmin(t, db, "owner@example.com", RoleOwner)
+
+			oldToken, _, err := IssueSession(db, admin, AuthMethodPassword)
+			if err != nil {
+				t.Fatalf("IssueSession: %v", err)
+			}
+			time.Sleep(2 * time.Millisecond)
+
+			// Repeat so that the change and the new token land in the same
+			// millisecond at least some of the time
+			for i := 0; i < 20; i++ {
+				if err := ChangeAdminPassword(db, admin.ID, "new-hash"); err != nil {
+					t.Fatalf("ChangeAdminPassword: %v", err)
+				}
+
+				token, _, err := IssueSession(db, admin, AuthMethodPassword)
+				if err != nil {
+					t.Fatalf("IssueSession: %v", err)
+				}
+				if testTokenRevoked(t, token) {
+					t.Fatalf("the token issued after the password change is revoked")
+				}
+			}
+
+			if !testTokenRevoked(t, oldToken) {
+				t.Fatalf("the token issued before the password change is still valid")
+			}
+
+			var refreshTokens int64
+			db.Model(&models.RefreshToken{}).Where("admin_id = ? AND revoked_at IS NULL", admin.ID).Count(&refreshTokens)
+			if refreshTokens != 1 {
+				t.Fatalf("%d refresh tokens left, want only the newest", refreshTokens)
+			}
+		})
+	}
+}
+
+// testTokenRevoked checks an access token the way the authentication
+// middleware does.
+func testTokenRevoked(t *testing.T, tokenString string) bool {
+	t.Helper()
+
+	claims := &Claims{}
+	if _, err := ParseToken(tokenString, claims); err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+
+	revoked, err := IsClaimsRevoked(claims)
+	if err != nil {
+		t.Fatalf("IsClaimsRevoked: %v", err)
+	}
+	return revoked
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"golang-final-project/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	APIKeyHeader     = "X-API-Key"
+	AuthMethodAPIKey = "api_key"
+
+	apiKeyPrefix = "btk_"
+	// last_used_at is only written when it is older than this, so busy keys
+	// don't cause a write on every request
+	apiKeyLastUsedResolution = time.Minute
+)
+
+const (
+	APIKeyScopeRead      = "read"
+	APIKeyScopeInventory = "inventory"
+	APIKeyScopeWrite     = "write"
+)
+
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// API keys never get admins:manage, whatever the owner's role.
+var apiKeyScopePermissions = map[string][]string{
+	APIKeyScopeRead: {
+		PermissionProductsRead,
+		PermissionVariantsRead,
+		PermissionOrdersRead,
+	},
+	APIKeyScopeInventory: {
+		PermissionVariantsRead,
+		PermissionInventoryAdjust,
+	},
+	APIKeyScopeWrite: {
+		PermissionProductsRead,
+		PermissionProductsWrite,
+		PermissionVariantsRead,
+		PermissionVariantsWrite,
+		PermissionInventoryAdjust,
+		PermissionLocationsManage,
+		PermissionPurchasingWrite,
+		PermissionOrdersRead,
+		PermissionOrdersWrite,
+	},
+}
+
+func IsValidAPIKeyScope(scope string) bool {
+	_, ok := apiKeyScopePermissions[scope]
+	return ok
+}
+
+func APIKeyScopes(apiKey *models.APIKey) []string {
+	return strings.Split(apiKey.Scopes, ",")
+}
+
+// ScopesAllow reports whether any of the scopes grants the permission.
+func ScopesAllow(scopes []string, permission string) bool {
+	for _, scope := range scopes {
+		for _, p := range apiKeyScopePermissions[scope] {
+			if p == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CreateAPIKey returns the stored key and the plaintext key, which is shown
+// to the admin once and never stored.
+func CreateAPIKey(db *gorm.DB, adminID uuid.UUID, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, string, error) {
+	prefixBytes := make([]byte, 6)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return nil, "", err
+	}
+	prefix := apiKeyPrefix + strings.ToLower(totpEncoding.EncodeToString(prefixBytes))
+
+	secret, err := GenerateOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	key := prefix + "_" + secret
+
+	apiKey := models.APIKey{
+		AdminID:   adminID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   HashToken(key),
+		Scopes:    strings.Join(scopes, ","),
+		ExpiresAt: expiresAt,
+	}
+
+	if err := db.Create(&apiKey).Error; err != nil {
+		return nil, "", err
+	}
+
+	return &apiKey, key, nil
+}
+
+func GetAPIKeysByAdminID(db *gorm.DB, adminID uuid.UUID) ([]models.APIKey, error) {
+	var apiKeys []models.APIKey
+
+	if err := db.Where("admin_id = ?", adminID).Order("created_at DESC").Find(&apiKeys).Error; err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+func RevokeAPIKey(db *gorm.DB, adminID, id uuid.UUID) error {
+	res := db.Model(&models.APIKey{}).
+		Where("id = ? AND admin_id = ? AND revoked_at IS NULL", id, adminID).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// AuthenticateAPIKey looks the key up by its visible prefix and returns it
+// together with its admin.
+func AuthenticateAPIKey(db *gorm.DB, key string) (*models.APIKey, *models.Admin, error) {
+	prefix, _, found := strings.Cut(strings.TrimPrefix(key, apiKeyPrefix), "_")
+	if !strings.HasPrefix(key, apiKeyPrefix) || !found {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	var apiKey models.APIKey
+	if err := db.Where("prefix = ?", apiKeyPrefix+prefix).First(&apiKey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(HashToken(key))) != 1 ||
+		apiKey.RevokedAt != nil ||
+		(apiKey.ExpiresAt != nil && now.After(*apiKey.ExpiresAt)) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	admin, err := GetAdminByID(db, apiKey.AdminID)
+	if err != nil || admin.DeactivatedAt != nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyLastUsedResolution {
+		if err := db.Model(&models.APIKey{}).Where("id = ?", apiKey.ID).Update("last_used_at", now).Error; err != nil {
+			return nil, nil, err
+		}
+		apiKey.LastUsedAt = &now
+	}
+
+	return &apiKey, admin, nil
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"golang-final-project/models"
+	"log"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	AuditLoginLockout = "login.lockout"
+)
+
+// RecordAuditEvent stores the event and mirrors it to the log, so it is not
+// lost when the database write fails.
+func RecordAuditEvent(db *gorm.DB, eventType string, adminID *uuid.UUID, ip, detail string) error {
+	log.Printf("audit: %s admin=%v ip=%s %s", eventType, adminID, ip, detail)
+
+	event := models.AuditEvent{
+		Type:    eventType,
+		AdminID: adminID,
+		IP:      ip,
+		Detail:  detail,
+	}
+
+	return db.Create(&event).Error
+}
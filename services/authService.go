@@ -1,67 +1,90 @@
 package services
 
 import (
-	"fmt"
-	"os"
-	"strings"
+	"errors"
+	"golang-final-project/models"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
-var JwtKey = []byte(os.Getenv("JWT_SECRET"))
+func init() {
+	// Access tokens carry a millisecond iat so that a token issued right after
+	// "sign out everywhere" can be told apart from the ones it revoked. The
+	// claims are encoded with microseconds: parsing goes through a float64 and
+	// truncates, which could otherwise land a millisecond early.
+	jwt.TimePrecision = time.Microsecond
+}
+
+const (
+	AuthMethodPassword = "password"
+	AuthMethodMFA      = "mfa"
+)
+
+// PurposeMFAPending marks the short-lived token handed out between the
+// password and the TOTP step. It is not an access token.
+const PurposeMFAPending = "mfa_pending"
+
+var ErrInvalidMFAToken = errors.New("invalid or expired mfa token")
 
 type Claims struct {
-	AdminID uuid.UUID `json:"adminID"`
+	AdminID        uuid.UUID `json:"adminID"`
+	Role           string    `json:"role"`
+	OrganizationID uuid.UUID `json:"organizationID,omitempty"`
+	Superadmin     bool      `json:"superadmin,omitempty"`
+	AuthMethod     string    `json:"authMethod,omitempty"`
+	Purpose        string    `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(adminID uuid.UUID) (string, error) {
-	expirationTime := time.Now().Add(30 * time.Minute)
+func GenerateJWT(admin *models.Admin, organizationID uuid.UUID, authMethod string) (string, error) {
+	now := time.Now()
+	expirationTime := now.Add(30 * time.Minute)
 	claims := &Claims{
-		AdminID: adminID,
+		AdminID:        admin.ID,
+		Role:           admin.Role,
+		OrganizationID: organizationID,
+		Superadmin:     admin.Superadmin,
+		AuthMethod:     authMethod,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			IssuedAt:  jwt.NewNumericDate(now.Truncate(time.Millisecond)),
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(JwtKey)
+	return Keys.Sign(claims)
 }
 
-func ExtractAdminID(c *gin.Context) (string, error) {
-	tokenString := extractToken(c)
+func GenerateMFAPendingToken(admin *models.Admin) (string, error) {
+	now := time.Now()
+	claims := &Claims{
+		AdminID: admin.ID,
+		Purpose: PurposeMFAPending,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		},
+	}
+
+	return Keys.Sign(claims)
+}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return JwtKey, nil
-	})
+func ParseMFAPendingToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
 
-	if err != nil {
-		return "", err
-	}
+	token, err := ParseToken(tokenString, claims)
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		adminID, ok := claims["adminID"].(string)
-		if !ok {
-			return "", fmt.Errorf("adminID not found in token")
-		}
-		return adminID, nil
+	if err != nil || !token.Valid || claims.Purpose != PurposeMFAPending {
+		return nil, ErrInvalidMFAToken
 	}
 
-	return "", fmt.Errorf("invalid token")
+	return claims, nil
 }
 
-func extractToken(c *gin.Context) string {
-	bearerToken := c.GetHeader("Authorization")
-
-	strArr := strings.Split(bearerToken, " ")
-	if len(strArr) == 2 {
-		return strArr[1]
-	}
-	return ""
+// ParseToken verifies a token we issued against the keyset.
+func ParseToken(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, Keys.Keyfunc, jwt.WithValidMethods(Keys.ValidMethods()))
 }
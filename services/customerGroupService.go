@@ -0,0 +1,79 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrCustomerGroupCodeConflict = errors.New("customer group code is already in use")
+
+func CreateCustomerGroup(db *gorm.DB, group *models.CustomerGroup) error {
+	var count int64
+	err := db.Model(&models.CustomerGroup{}).
+		Where("organization_id = ? AND code = ?", group.OrganizationID, group.Code).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrCustomerGroupCodeConflict
+	}
+
+	return db.Create(group).Error
+}
+
+func GetAllCustomerGroups(db *gorm.DB, tenant Tenant) ([]models.CustomerGroup, error) {
+	var groups []models.CustomerGroup
+
+	if err := db.Scopes(CustomerGroupTenantScope(tenant)).Order("organization_id, code").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+func GetCustomerGroupByID(db *gorm.DB, tenant Tenant, id uuid.UUID) (*models.CustomerGroup, error) {
+	var group models.CustomerGroup
+	err := db.Scopes(CustomerGroupTenantScope(tenant)).First(&group, id).Error
+	return &group, err
+}
+
+func UpdateCustomerGroupByID(db *gorm.DB, tenant Tenant, id uuid.UUID, name, code string) error {
+	group, err := GetCustomerGroupByID(db, tenant, id)
+	if err != nil {
+		return err
+	}
+
+	if code != group.Code {
+		var count int64
+		err := db.Model(&models.CustomerGroup{}).
+			Where("organization_id = ? AND code = ? AND id <> ?", group.OrganizationID, code, id).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrCustomerGroupCodeConflict
+		}
+	}
+
+	return db.Model(&models.CustomerGroup{}).Where("id = ?", id).Updates(models.CustomerGroup{Name: name, Code: code}).Error
+}
+
+// DeleteCustomerGroupByID also takes the group off its price lists.
+func DeleteCustomerGroupByID(db *gorm.DB, tenant Tenant, id uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if _, err := GetCustomerGroupByID(tx, tenant, id); err != nil {
+			return err
+		}
+
+		if err := tx.Where("customer_group_id = ?", id).Delete(&models.PriceListAssignment{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&models.CustomerGroup{}, id).Error
+	})
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const InvitationTTL = 7 * 24 * time.Hour
+
+var ErrInvalidInvitation = errors.New("invalid or expired invitation")
+
+func CreateInvitation(db *gorm.DB, invitation *models.Invitation) (string, error) {
+	token, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	invitation.Email = strings.ToLower(invitation.Email)
+	invitation.TokenHash = HashToken(token)
+	invitation.ExpiresAt = time.Now().Add(InvitationTTL)
+
+	if err := db.Create(invitation).Error; err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func SendInvitationEmail(email, inviterName, token string) error {
+	return DefaultMailer.Send(Email{
+		To:      email,
+		Subject: "You have been invited to Base Trade",
+		Body:    inviterName + " invited you to join their organization on Base Trade.\n\nRegister using this invitation:\n" + appLink("/register", token) + "\n\nThe invitation expires in 7 days.",
+	})
+}
+
+// SendOrganizationInvitationEmail invites an admin who is already registered
+// to join another organization.
+func SendOrganizationInvitationEmail(email, inviterName, organizationName, token string) error {
+	return DefaultMailer.Send(Email{
+		To:      email,
+		Subject: "You have been invited to " + organizationName + " on Base Trade",
+		Body:    inviterName + " invited you to join " + organizationName + " on Base Trade.\n\nSign in and accept the invitation:\n" + appLink("/invitations/accept", token) + "\n\nThe invitation expires in 7 days.",
+	})
+}
+
+// GetPendingInvitation finds an unexpired, unaccepted invitation for the
+// token that was issued to the given email.
+func GetPendingInvitation(db *gorm.DB, token, email string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := db.Where("token_hash = ? AND email = ? AND accepted_at IS NULL AND expires_at > ?",
+		HashToken(token), strings.ToLower(email), time.Now()).First(&invitation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidInvitation
+	}
+
+	return &invitation, err
+}
+
+func AcceptInvitation(db *gorm.DB, invitation *models.Invitation) error {
+	res := db.Model(&models.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL", invitation.ID).
+		Update("accepted_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrInvalidInvitation
+	}
+
+	return nil
+}
+
+// AcceptOrganizationInvitation adds a registered admin to the organization
+// they were invited to. Only the admin the invitation was sent to can accept
+// it, which is what keeps an owner from pulling someone into their
+// organization without asking.
+func AcceptOrganizationInvitation(db *gorm.DB, token string, admin *models.Admin) (*models.Invitation, error) {
+	var invitation *models.Invitation
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		invitation, err = GetPendingInvitation(tx, token, admin.Email)
+		if err != nil {
+			return err
+		}
+
+		if err := AcceptInvitation(tx, invitation); err != nil {
+			return err
+		}
+
+		isMember, err := IsOrganizationMember(tx, invitation.OrganizationID, admin.ID)
+		if err != nil || isMember {
+			return err
+		}
+
+		return AddOrganizationMember(tx, invitation.OrganizationID, admin.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"testing"
+)
+
+func TestAcceptOrganizationInvitation(t *testing.T) {
+	db := openTestDB(t)
+
+	owner := createTestAdmin(t, db, "owner@example.com", RoleOwner)
+	invitee := createTestAdmin(t, db, "invitee@example.com", RoleEditor)
+	stranger := createTestAdmin(t, db, "stranger@example.com", RoleOwner)
+
+	organizationID, err := GetDefaultOrganizationID(db, owner.ID)
+	if err != nil {
+		t.Fatalf("GetDefaultOrganizationID: %v", err)
+	}
+
+	invitation := models.Invitation{Email: "Invitee@Example.com", Role: invitee.Role, OrganizationID: organizationID, InvitedByID: owner.ID}
+	token, err := CreateInvitation(db, &invitation)
+	if err != nil {
+		t.Fatalf("CreateInvitation: %v", err)
+	}
+
+	// Inviting alone does not make the admin a member
+	if isMember, _ := IsOrganizationMember(db, organizationID, invitee.ID); isMember {
+		t.Fatalf("invitee joined before accepting")
+	}
+
+	if _, err := AcceptOrganizationInvitation(db, token, stranger); !errors.Is(err, ErrInvalidInvitation) {
+		t.Fatalf("accept by another admin error = %v, want ErrInvalidInvitation", err)
+	}
+
+	accepted, err := AcceptOrganizationInvitation(db, token, invitee)
+	if err != nil {
+		t.Fatalf("AcceptOrganizationInvitation: %v", err)
+	}
+	if accepted.OrganizationID != organizationID {
+		t.Fatalf("accepted invitation for %s, want %s", accepted.OrganizationID, organizationID)
+	}
+
+	if isMember, _ := IsOrganizationMember(db, organizationID, invitee.ID); !isMember {
+		t.Fatalf("invitee is not a member after accepting")
+	}
+	if isMember, _ := IsOrganizationMember(db, organizationID, stranger.ID); isMember {
+		t.Fatalf("another admin joined with the invitation")
+	}
+
+	if _, err := AcceptOrganizationInvitation(db, token, invitee); !errors.Is(err, ErrInvalidInvitation) {
+		t.Fatalf("second accept error = %v, want ErrInvalidInvitation", err)
+	}
+
+	reloaded, err := GetAdminByID(db, invitee.ID)
+	if err != nil || reloaded.Role != RoleEditor {
+		t.Fatalf("invitee role = %v, %v; joining must not change it", reloaded, err)
+	}
+}
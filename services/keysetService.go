@@ -0,0 +1,542 @@
+package services
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"golang-final-project/models"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	SigningAlgorithmRS256 = "RS256"
+	SigningAlgorithmEdDSA = "EdDSA"
+)
+
+var ErrUnknownSigningKey = errors.New("unknown signing key")
+
+// sealedPrivateKeyPrefix marks a private key stored encrypted with the key
+// encryption key. Rows without it hold a plaintext PEM from before keys were
+// encrypted and get sealed on the next load.
+const sealedPrivateKeyPrefix = "aes-gcm:"
+
+// keysetReloadInterval bounds how long an instance keeps using keys another
+// instance has rotated. It has to stay well below the key retention.
+const keysetReloadInterval = time.Minute
+
+// Keys signs and verifies every token we issue. The default keyset lives in
+// memory only; main replaces it with one persisted in the database so all
+// instances share keys and tokens survive restarts.
+var Keys = NewKeyset(nil, nil, SigningAlgorithmRS256, 30*24*time.Hour, 24*time.Hour)
+
+type signingKey struct {
+	kid       string
+	algorithm string
+	private   crypto.Signer
+	public    crypto.PublicKey
+	createdAt time.Time
+	retiredAt *time.Time
+	expiresAt *time.Time
+}
+
+// Keyset signs with its newest key and keeps retired keys around for
+// verification until they expire. Retention must be longer than the lifetime
+// of any token we issue.
+type Keyset struct {
+	mu sync.RWMutex
+	db *gorm.DB
+	// encryptionKey seals the private keys stored in the database. It is
+	// kept outside of it so that a database dump does not leak them.
+	encryptionKey []byte
+	algorithm     string
+	rotateEvery   time.Duration
+	retention     time.Duration
+	keys          []*signingKey
+	loadedAt      time.Time
+}
+
+// NewKeyset returns a keyset persisted in db, or kept in memory when db is
+// nil. A persisted keyset needs a 32 byte encryptionKey for AES-256-GCM.
+func NewKeyset(db *gorm.DB, encryptionKey []byte, algorithm string, rotateEvery, retention time.Duration) *Keyset {
+	return &Keyset{
+		db:            db,
+		encryptionKey: encryptionKey,
+		algorithm:     algorithm,
+		rotateEvery:   rotateEvery,
+		retention:     retention,
+	}
+}
+
+// LoadKeysetFromEnv builds a database backed keyset from JWT_SIGNING_ALG,
+// JWT_KEY_ROTATION_HOURS, JWT_KEY_RETENTION_HOURS and JWT_KEY_ENCRYPTION_KEY,
+// a base64 encoded 32 byte key.
+func LoadKeysetFromEnv(db *gorm.DB) (*Keyset, error) {
+	algorithm := os.Getenv("JWT_SIGNING_ALG")
+	if algorithm == "" {
+		algorithm = SigningAlgorithmRS256
+	}
+	if algorithm != SigningAlgorithmRS256 && algorithm != SigningAlgorithmEdDSA {
+		return nil, fmt.Errorf("unsupported JWT_SIGNING_ALG %q", algorithm)
+	}
+
+	encryptionKey, err := base64.StdEncoding.DecodeString(os.Getenv("JWT_KEY_ENCRYPTION_KEY"))
+	if err != nil || len(encryptionKey) != 32 {
+		return nil, errors.New("JWT_KEY_ENCRYPTION_KEY must be a base64 encoded 32 byte key")
+	}
+
+	rotateEvery := envHours("JWT_KEY_ROTATION_HOURS", 30*24*time.Hour)
+	retention := envHours("JWT_KEY_RETENTION_HOURS", 24*time.Hour)
+
+	keyset := NewKeyset(db, encryptionKey, algorithm, rotateEvery, retention)
+	if err := keyset.Load(); err != nil {
+		return nil, err
+	}
+
+	return keyset, nil
+}
+
+// Load reads the unexpired keys from the database and rotates when there is
+// no usable signing key yet.
+func (k *Keyset) Load() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.loadLocked(); err != nil {
+		return err
+	}
+
+	if active := k.activeLocked(); active == nil || active.algorithm != k.algorithm {
+		return k.rotateLocked()
+	}
+
+	return nil
+}
+
+func (k *Keyset) Rotate() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.rotateLocked()
+}
+
+// StartRotation reloads the keys in the background and rotates the signing
+// key once it is older than the rotation interval.
+func (k *Keyset) StartRotation() {
+	go func() {
+		for range time.Tick(keysetReloadInterval) {
+			if err := k.rotateIfDue(); err != nil {
+				log.Printf("Failed to rotate signing key: %v", err)
+			}
+		}
+	}()
+}
+
+// Sign refuses to use a key the database marks retired, since another
+// instance may have rotated since our last reload.
+func (k *Keyset) Sign(claims jwt.Claims) (string, error) {
+	k.mu.Lock()
+	if k.db != nil {
+		stale := time.Since(k.loadedAt) > keysetReloadInterval
+		if active := k.activeLocked(); !stale && active != nil {
+			var retired int64
+			err := k.db.Model(&models.SigningKey{}).Where("kid = ? AND retired_at IS NOT NULL", active.kid).Count(&retired).Error
+			if err != nil {
+				k.mu.Unlock()
+				return "", err
+			}
+			stale = retired > 0
+		}
+
+		if stale {
+			if err := k.loadLocked(); err != nil {
+				k.mu.Unlock()
+				return "", err
+			}
+		}
+	}
+
+	active := k.activeLocked()
+	if active == nil {
+		if err := k.rotateLocked(); err != nil {
+			k.mu.Unlock()
+			return "", err
+		}
+		active = k.activeLocked()
+	}
+	k.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(active.algorithm), claims)
+	token.Header["kid"] = active.kid
+
+	return token.SignedString(active.private)
+}
+
+// Keyfunc resolves the verification key from the token's kid header. An
+// unknown kid triggers a reload, since another instance may have rotated.
+func (k *Keyset) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, ErrUnknownSigningKey
+	}
+
+	key := k.find(kid)
+	if key == nil && k.db != nil {
+		k.mu.Lock()
+		var err error
+		// Don't let a flood of forged kids turn into a flood of queries
+		if time.Since(k.loadedAt) > 10*time.Second {
+			err = k.loadLocked()
+		}
+		k.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		key = k.find(kid)
+	}
+
+	if key == nil || (key.expiresAt != nil && time.Now().After(*key.expiresAt)) {
+		return nil, ErrUnknownSigningKey
+	}
+
+	if token.Method.Alg() != key.algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.public, nil
+}
+
+func (k *Keyset) ValidMethods() []string {
+	return []string{SigningAlgorithmRS256, SigningAlgorithmEdDSA}
+}
+
+// JWKS returns the public keys in JSON Web Key Set format.
+func (k *Keyset) JWKS() map[string]interface{} {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := time.Now()
+	keys := []map[string]string{}
+
+	for _, key := range k.keys {
+		if key.expiresAt != nil && now.After(*key.expiresAt) {
+			continue
+		}
+
+		jwk := map[string]string{
+			"kid": key.kid,
+			"alg": key.algorithm,
+			"use": "sig",
+		}
+
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk["kty"] = "RSA"
+			jwk["n"] = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk["kty"] = "OKP"
+			jwk["crv"] = "Ed25519"
+			jwk["x"] = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+
+		keys = append(keys, jwk)
+	}
+
+	return map[string]interface{}{"keys": keys}
+}
+
+func (k *Keyset) rotateIfDue() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.loadLocked(); err != nil {
+		return err
+	}
+
+	active := k.activeLocked()
+	if active != nil && time.Since(active.createdAt) < k.rotateEvery && active.algorithm == k.algorithm {
+		return nil
+	}
+
+	return k.rotateLocked()
+}
+
+func (k *Keyset) find(kid string) *signingKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if key.kid == kid {
+			return key
+		}
+	}
+	return nil
+}
+
+// activeLocked returns the newest key that has not been retired.
+func (k *Keyset) activeLocked() *signingKey {
+	var active *signingKey
+	for _, key := range k.keys {
+		if key.retiredAt == nil && (active == nil || key.createdAt.After(active.createdAt)) {
+			active = key
+		}
+	}
+	return active
+}
+
+func (k *Keyset) rotateLocked() error {
+	key, err := generateSigningKey(k.algorithm)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(k.retention)
+
+	if k.db != nil {
+		record, err := k.signingKeyRecord(key)
+		if err != nil {
+			return err
+		}
+
+		err = k.db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Model(&models.SigningKey{}).Where("retired_at IS NULL").
+				Updates(map[string]interface{}{"retired_at": now, "expires_at": expiresAt}).Error
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Where("expires_at < ?", now).Delete(&models.SigningKey{}).Error; err != nil {
+				return err
+			}
+
+			return tx.Create(record).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	keys := []*signingKey{key}
+	for _, old := range k.keys {
+		if old.retiredAt == nil {
+			old.retiredAt = &now
+			old.expiresAt = &expiresAt
+		}
+		if old.expiresAt == nil || old.expiresAt.After(now) {
+			keys = append(keys, old)
+		}
+	}
+	k.keys = keys
+
+	return nil
+}
+
+func (k *Keyset) loadLocked() error {
+	if k.db == nil {
+		return nil
+	}
+
+	var records []models.SigningKey
+	if err := k.db.Where("expires_at IS NULL OR expires_at > ?", time.Now()).Find(&records).Error; err != nil {
+		return err
+	}
+
+	keys := make([]*signingKey, 0, len(records))
+	for _, record := range records {
+		key, err := k.parseSigningKeyRecord(record)
+		if err != nil {
+			return err
+		}
+
+		if !strings.HasPrefix(record.PrivateKey, sealedPrivateKeyPrefix) {
+			if err := k.sealStoredPrivateKey(record); err != nil {
+				return err
+			}
+		}
+
+		keys = append(keys, key)
+	}
+	k.keys = keys
+	k.loadedAt = time.Now()
+
+	return nil
+}
+
+func generateSigningKey(algorithm string) (*signingKey, error) {
+	key := &signingKey{
+		kid:       uuid.NewString(),
+		algorithm: algorithm,
+		createdAt: time.Now(),
+	}
+
+	switch algorithm {
+	case SigningAlgorithmRS256:
+		private, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		key.private, key.public = private, &private.PublicKey
+	case SigningAlgorithmEdDSA:
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key.private, key.public = private, public
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+
+	return key, nil
+}
+
+func (k *Keyset) signingKeyRecord(key *signingKey) (*models.SigningKey, error) {
+	private, err := x509.MarshalPKCS8PrivateKey(key.private)
+	if err != nil {
+		return nil, err
+	}
+
+	public, err := x509.MarshalPKIXPublicKey(key.public)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := k.sealPrivateKey(key.kid, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private}))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SigningKey{
+		KID:        key.kid,
+		Algorithm:  key.algorithm,
+		PrivateKey: sealed,
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})),
+		CreatedAt:  key.createdAt,
+	}, nil
+}
+
+func (k *Keyset) parseSigningKeyRecord(record models.SigningKey) (*signingKey, error) {
+	privatePEM := []byte(record.PrivateKey)
+	if strings.HasPrefix(record.PrivateKey, sealedPrivateKeyPrefix) {
+		opened, err := k.openPrivateKey(record.KID, record.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", record.KID, err)
+		}
+		privatePEM = opened
+	}
+
+	block, _ := pem.Decode(privatePEM)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s: invalid PEM", record.KID)
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("signing key %s: %w", record.KID, err)
+	}
+
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("signing key %s: not a signing key", record.KID)
+	}
+
+	return &signingKey{
+		kid:       record.KID,
+		algorithm: record.Algorithm,
+		private:   signer,
+		public:    signer.Public(),
+		createdAt: record.CreatedAt,
+		retiredAt: record.RetiredAt,
+		expiresAt: record.ExpiresAt,
+	}, nil
+}
+
+// sealStoredPrivateKey replaces a plaintext private key in the database with
+// its sealed form.
+func (k *Keyset) sealStoredPrivateKey(record models.SigningKey) error {
+	sealed, err := k.sealPrivateKey(record.KID, []byte(record.PrivateKey))
+	if err != nil {
+		return err
+	}
+
+	return k.db.Model(&models.SigningKey{}).
+		Where("kid = ? AND private_key = ?", record.KID, record.PrivateKey).
+		Update("private_key", sealed).Error
+}
+
+// sealPrivateKey encrypts the PEM with AES-256-GCM, binding it to the kid so
+// that a sealed key cannot be moved to another row.
+func (k *Keyset) sealPrivateKey(kid string, privatePEM []byte) (string, error) {
+	aead, err := k.privateKeyCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, privatePEM, []byte(kid))
+	return sealedPrivateKeyPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (k *Keyset) openPrivateKey(kid, stored string) ([]byte, error) {
+	aead, err := k.privateKeyCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, sealedPrivateKeyPrefix))
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, errors.New("invalid sealed private key")
+	}
+
+	privatePEM, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(kid))
+	if err != nil {
+		return nil, errors.New("cannot decrypt private key, check JWT_KEY_ENCRYPTION_KEY")
+	}
+
+	return privatePEM, nil
+}
+
+func (k *Keyset) privateKeyCipher() (cipher.AEAD, error) {
+	if len(k.encryptionKey) != 32 {
+		return nil, errors.New("signing key encryption key must be 32 bytes")
+	}
+
+	block, err := aes.NewCipher(k.encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func envHours(key string, fallback time.Duration) time.Duration {
+	hours, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || hours <= 0 {
+		return fallback
+	}
+	return time.Duration(hours) * time.Hour
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"bytes"
+	"golang-final-project/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestKeysetEncryptsPrivateKeys(t *testing.T) {
+	db := openTestDB(t)
+	encryptionKey := bytes.Repeat([]byte{7}, 32)
+
+	keyset := NewKeyset(db, encryptionKey, SigningAlgorithmRS256, time.Hour, time.Hour)
+	if err := keyset.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	var record models.SigningKey
+	if err := db.First(&record).Error; err != nil {
+		t.Fatalf("load signing key: %v", err)
+	}
+	if !strings.HasPrefix(record.PrivateKey, sealedPrivateKeyPrefix) || strings.Contains(record.PrivateKey, "PRIVATE KEY") {
+		t.Fatalf("private key stored in plaintext")
+	}
+
+	token, err := keyset.Sign(jwt.RegisteredClaims{Subject: "ada"})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	// Another instance with the same key verifies the token
+	reloaded := NewKeyset(db, encryptionKey, SigningAlgorithmRS256, time.Hour, time.Hour)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if _, err := jwt.Parse(token, reloaded.Keyfunc, jwt.WithValidMethods(reloaded.ValidMethods())); err != nil {
+		t.Fatalf("verify with the reloaded keyset: %v", err)
+	}
+
+	wrongKey := NewKeyset(db, bytes.Repeat([]byte{8}, 32), SigningAlgorithmRS256, time.Hour, time.Hour)
+	if err := wrongKey.Load(); err == nil {
+		t.Fatalf("Load with the wrong encryption key succeeded")
+	}
+}
+
+func TestKeysetSealsPlaintextPrivateKeys(t *testing.T) {
+	db := openTestDB(t)
+	encryptionKey := bytes.Repeat([]byte{7}, 32)
+
+	keyset := NewKeyset(db, encryptionKey, SigningAlgorithmEdDSA, time.Hour, time.Hour)
+
+	// A row written before private keys were encrypted
+	key, err := generateSigningKey(SigningAlgorithmEdDSA)
+	if err != nil {
+		t.Fatalf("generateSigningKey: %v", err)
+	}
+	record, err := keyset.signingKeyRecord(key)
+	if err != nil {
+		t.Fatalf("signingKeyRecord: %v", err)
+	}
+	plaintext, err := keyset.openPrivateKey(record.KID, record.PrivateKey)
+	if err != nil {
+		t.Fatalf("openPrivateKey: %v", err)
+	}
+	record.PrivateKey = string(plaintext)
+	if err := db.Create(record).Error; err != nil {
+		t.Fatalf("create signing key: %v", err)
+	}
+
+	if err := keyset.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if keyset.find(key.kid) == nil {
+		t.Fatalf("plaintext signing key not loaded")
+	}
+
+	var stored models.SigningKey
+	if err := db.First(&stored, "kid = ?", key.kid).Error; err != nil {
+		t.Fatalf("load signing key: %v", err)
+	}
+	if !strings.HasPrefix(stored.PrivateKey, sealedPrivateKeyPrefix) {
+		t.Fatalf("plaintext private key left in the database")
+	}
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const DefaultLocationCode = "default"
+
+var (
+	ErrInvalidLocation      = errors.New("location does not belong to the variant's organization")
+	ErrLocationNotEmpty     = errors.New("location still holds stock")
+	ErrDefaultLocation      = errors.New("cannot delete the default location")
+	ErrInvalidTransfer      = errors.New("invalid stock transfer")
+	ErrLocationCodeConflict = errors.New("location code is already in use")
+)
+
+// CreateLocation adds a non-default location. The default location is only
+// created by EnsureDefaultLocation, so its code is reserved.
+func CreateLocation(db *gorm.DB, location *models.Location) error {
+	if location.Code == DefaultLocationCode {
+		return ErrLocationCodeConflict
+	}
+
+	location.IsDefault = false
+
+	var count int64
+	err := db.Model(&models.Location{}).
+		Where("organization_id = ? AND code = ?", location.OrganizationID, location.Code).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrLocationCodeConflict
+	}
+
+	return db.Create(location).Error
+}
+
+func GetAllLocations(db *gorm.DB, tenant Tenant) ([]models.Location, error) {
+	var locations []models.Location
+
+	if err := db.Scopes(LocationTenantScope(tenant)).Order("organization_id, code").Find(&locations).Error; err != nil {
+		return nil, err
+	}
+
+	return locations, nil
+}
+
+func GetLocationByID(db *gorm.DB, tenant Tenant, id uuid.UUID) (*models.Location, error) {
+	var location models.Location
+	err := db.Scopes(LocationTenantScope(tenant)).First(&location, id).Error
+	return &location, err
+}
+
+// UpdateLocationByID renames the location. The default location keeps its
+// reserved code.
+func UpdateLocationByID(db *gorm.DB, tenant Tenant, id uuid.UUID, name, code string) error {
+	location, err := GetLocationByID(db, tenant, id)
+	if err != nil {
+		return err
+	}
+
+	if code != location.Code {
+		if location.IsDefault || code == DefaultLocationCode {
+			return ErrLocationCodeConflict
+		}
+
+		var count int64
+		err := db.Model(&models.Location{}).
+			Where("organization_id = ? AND code = ? AND id <> ?", location.OrganizationID, code, id).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrLocationCodeConflict
+		}
+	}
+
+	return db.Model(&models.Location{}).Where("id = ?", id).Updates(models.Location{Name: name, Code: code}).Error
+}
+
+// DeleteLocationByID only deletes empty, non-default locations.
+func DeleteLocationByID(db *gorm.DB, tenant Tenant, id uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		location, err := GetLocationByID(tx, tenant, id)
+		if err != nil {
+			return err
+		}
+		if location.IsDefault {
+			return ErrDefaultLocation
+		}
+
+		var stocked int64
+		if err := tx.Model(&models.StockLevel{}).Where("location_id = ? AND quantity <> 0", id).Count(&stocked).Error; err != nil {
+			return err
+		}
+		if stocked > 0 {
+			return ErrLocationNotEmpty
+		}
+
+		if err := tx.Where("location_id = ?", id).Delete(&models.StockLevel{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&models.Location{}, id).Error
+	})
+}
+
+// EnsureDefaultLocation returns the organization's default location,
+// creating it on first use.
+func EnsureDefaultLocation(db *gorm.DB, organizationID uuid.UUID) (uuid.UUID, error) {
+	location := models.Location{
+		OrganizationID: organizationID,
+		Name:           "Main warehouse",
+		Code:           DefaultLocationCode,
+		IsDefault:      true,
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&location).Error; err != nil {
+		return uuid.Nil, err
+	}
+
+	var existing models.Location
+	err := db.Where("organization_id = ? AND code = ?", organizationID, DefaultLocationCode).First(&existing).Error
+
+	return existing.ID, err
+}
+
+func GetStockLevelsByVariantID(db *gorm.DB, variantID uuid.UUID) ([]models.StockLevel, error) {
+	var levels []models.StockLevel
+
+	if err := db.Preload("Location").Where("variant_id = ?", variantID).Find(&levels).Error; err != nil {
+		return nil, err
+	}
+
+	return levels, nil
+}
+
+// TransferStock moves quantity units between two locations of the variant's
+// organization as a pair of transfer movements. The total does not change.
+func TransferStock(db *gorm.DB, variantID, fromLocationID, toLocationID uuid.UUID, quantity int, input StockMovementInput) ([]models.StockMovement, error) {
+	if quantity <= 0 || fromLocationID == toLocationID {
+		return nil, ErrInvalidTransfer
+	}
+
+	if input.Reference == "" {
+		input.Reference = "transfer:" + uuid.NewString()
+	}
+	input.Type = MovementTransfer
+
+	var movements []models.StockMovement
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		out := input
+		out.Quantity = -quantity
+		out.LocationID = &fromLocationID
+
+		outMovement, err := recordStockMovement(tx, variantID, out)
+		if err != nil {
+			return err
+		}
+
+		in := input
+		in.Quantity = quantity
+		in.LocationID = &toLocationID
+
+		inMovement, err := recordStockMovement(tx, variantID, in)
+		if err != nil {
+			return err
+		}
+
+		movements = []models.StockMovement{*outMovement, *inMovement}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return movements, nil
+}
+
+// MigrateStockToLocations puts the quantity of every variant without stock
+// levels into its organization's default location. Running it again is a
+// no-op.
+func MigrateStockToLocations(db *gorm.DB) error {
+	var variants []models.Variant
+	err := db.Where("quantity <> 0 AND id NOT IN (?)", db.Model(&models.StockLevel{}).Select("variant_id")).
+		Find(&variants).Error
+	if err != nil {
+		return err
+	}
+
+	for _, variant := range variants {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			locationID, err := resolveLocation(tx, variant.ID, nil)
+			if err != nil {
+				return err
+			}
+
+			level := models.StockLevel{
+				VariantID:  variant.ID,
+				LocationID: locationID,
+				Quantity:   variant.Quantity,
+			}
+			return tx.Create(&level).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveLocation returns the location a movement applies to, defaulting to
+// the default location of the variant's organization.
+func resolveLocation(db *gorm.DB, variantID uuid.UUID, locationID *uuid.UUID) (uuid.UUID, error) {
+	var product models.Product
+	err := db.Select("products.organization_id").
+		Joins("JOIN variants ON variants.product_id = products.id").
+		Where("variants.id = ?", variantID).
+		First(&product).Error
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if locationID == nil {
+		return EnsureDefaultLocation(db, product.OrganizationID)
+	}
+
+	var location models.Location
+	if err := db.Select("organization_id").First(&location, *locationID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, ErrInvalidLocation
+		}
+		return uuid.Nil, err
+	}
+	if location.OrganizationID != product.OrganizationID {
+		return uuid.Nil, ErrInvalidLocation
+	}
+
+	return *locationID, nil
+}
+
+func getStockLevelQuantity(db *gorm.DB, variantID, locationID uuid.UUID) (int, error) {
+	var level models.StockLevel
+	err := db.Where("variant_id = ? AND location_id = ?", variantID, locationID).First(&level).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	return level.Quantity, err
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"golang-final-project/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Failures older than this no longer count towards a lockout.
+const loginFailureWindow = 24 * time.Hour
+
+type LoginAttemptState struct {
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+func (state LoginAttemptState) RetryAfter(now time.Time) time.Duration {
+	if state.LockedUntil.After(now) {
+		return state.LockedUntil.Sub(now)
+	}
+	return 0
+}
+
+type LoginAttemptStore interface {
+	Get(key string) (LoginAttemptState, error)
+	// CountAttempt atomically counts an attempt as a failure and sets the
+	// lockout that lockoutFor returns for the new count. A key that is
+	// locked out is left alone and counted is false.
+	CountAttempt(key string, now time.Time, lockoutFor func(failures int) time.Duration) (state LoginAttemptState, counted bool, err error)
+	// Forgive takes back one counted attempt, lifting the lockout if the
+	// remaining count no longer calls for one.
+	Forgive(key string, lockoutFor func(failures int) time.Duration) error
+	Reset(key string) error
+}
+
+var LoginAttempts LoginAttemptStore = NewMemoryLoginAttemptStore()
+
+// LoginThrottle allows FreeAttempts failures, then locks the key out for
+// BaseLockout, doubling with every further failure up to MaxLockout.
+type LoginThrottle struct {
+	FreeAttempts int
+	BaseLockout  time.Duration
+	MaxLockout   time.Duration
+}
+
+var (
+	AccountLoginThrottle = LoginThrottle{FreeAttempts: 5, BaseLockout: 30 * time.Second, MaxLockout: time.Hour}
+	IPLoginThrottle      = LoginThrottle{FreeAttempts: 20, BaseLockout: 30 * time.Second, MaxLockout: time.Hour}
+)
+
+func (throttle LoginThrottle) LockoutFor(failures int) time.Duration {
+	if failures <= throttle.FreeAttempts {
+		return 0
+	}
+
+	lockout := throttle.BaseLockout
+	for i := throttle.FreeAttempts + 1; i < failures && lockout < throttle.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > throttle.MaxLockout {
+		lockout = throttle.MaxLockout
+	}
+
+	return lockout
+}
+
+func AccountLoginKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func IPLoginKey(ip string) string {
+	return "ip:" + ip
+}
+
+type MemoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]LoginAttemptState
+}
+
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{attempts: make(map[string]LoginAttemptState)}
+}
+
+func (s *MemoryLoginAttemptStore) Get(key string) (LoginAttemptState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.attempts[key], nil
+}
+
+func (s *MemoryLoginAttemptStore) CountAttempt(key string, now time.Time, lockoutFor func(failures int) time.Duration) (LoginAttemptState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, state := range s.attempts {
+		if now.Sub(state.LastFailureAt) > loginFailureWindow && !state.LockedUntil.After(now) {
+			delete(s.attempts, k)
+		}
+	}
+
+	state := s.attempts[key]
+	if state.RetryAfter(now) > 0 {
+		return state, false, nil
+	}
+
+	state.Failures++
+	state.LastFailureAt = now
+	if lockout := lockoutFor(state.Failures); lockout > 0 {
+		state.LockedUntil = now.Add(lockout)
+	}
+
+	s.attempts[key] = state
+	return state, true, nil
+}
+
+func (s *MemoryLoginAttemptStore) Forgive(key string, lockoutFor func(failures int) time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.attempts[key]
+	if !ok {
+		return nil
+	}
+
+	if state.Failures > 0 {
+		state.Failures--
+	}
+	if lockoutFor(state.Failures) == 0 {
+		state.LockedUntil = time.Time{}
+	}
+
+	s.attempts[key] = state
+	return nil
+}
+
+func (s *MemoryLoginAttemptStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+type GormLoginAttemptStore struct {
+	db *gorm.DB
+}
+
+func NewGormLoginAttemptStore(db *gorm.DB) *GormLoginAttemptStore {
+	return &GormLoginAttemptStore{db: db}
+}
+
+func (s *GormLoginAttemptStore) Get(key string) (LoginAttemptState, error) {
+	var attempt models.LoginAttempt
+	err := s.db.Where("`key` = ?", key).First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return LoginAttemptState{}, nil
+	}
+	if err != nil {
+		return LoginAttemptState{}, err
+	}
+
+	return loginAttemptState(attempt), nil
+}
+
+func (s *GormLoginAttemptStore) CountAttempt(key string, now time.Time, lockoutFor func(failures int) time.Duration) (LoginAttemptState, bool, error) {
+	var state LoginAttemptState
+	counted := false
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Make sure the row exists so it can be locked
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.LoginAttempt{Key: key, LastFailureAt: now}).Error
+		if err != nil {
+			return err
+		}
+
+		var attempt models.LoginAttempt
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("`key` = ?", key).First(&attempt).Error; err != nil {
+			return err
+		}
+
+		state = loginAttemptState(attempt)
+		if state.RetryAfter(now) > 0 {
+			return nil
+		}
+
+		if now.Sub(attempt.LastFailureAt) > loginFailureWindow {
+			attempt.Failures = 0
+		}
+		attempt.Failures++
+		attempt.LastFailureAt = now
+		if lockout := lockoutFor(attempt.Failures); lockout > 0 {
+			lockedUntil := now.Add(lockout)
+			attempt.LockedUntil = &lockedUntil
+		}
+
+		state = loginAttemptState(attempt)
+		counted = true
+		return tx.Save(&attempt).Error
+	})
+
+	return state, counted, err
+}
+
+func (s *GormLoginAttemptStore) Forgive(key string, lockoutFor func(failures int) time.Duration) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var attempt models.LoginAttempt
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("`key` = ?", key).First(&attempt).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if attempt.Failures > 0 {
+			attempt.Failures--
+		}
+		if lockoutFor(attempt.Failures) == 0 {
+			attempt.LockedUntil = nil
+		}
+
+		return tx.Save(&attempt).Error
+	})
+}
+
+func (s *GormLoginAttemptStore) Reset(key string) error {
+	return s.db.Where("`key` = ?", key).Delete(&models.LoginAttempt{}).Error
+}
+
+func loginAttemptState(attempt models.LoginAttempt) LoginAttemptState {
+	state := LoginAttemptState{
+		Failures:      attempt.Failures,
+		LastFailureAt: attempt.LastFailureAt,
+	}
+	if attempt.LockedUntil != nil {
+		state.LockedUntil = *attempt.LockedUntil
+	}
+
+	return state
+}
+
+// LoginAttempt is an attempt counted by BeginLoginAttempt, with the state of
+// the account and the IP right after it was counted.
+type LoginAttempt struct {
+	Email   string
+	IP      string
+	AdminID *uuid.UUID
+
+	at      time.Time
+	account LoginAttemptState
+	address LoginAttemptState
+}
+
+// BeginLoginAttempt counts the attempt against the account and the IP before
+// any credential is checked, so concurrent attempts can never get past the
+// lockout. It returns how long to wait when either is locked out already; the
+// attempt is then not counted. The caller ends a counted attempt with
+// LoginAttemptSucceeded, CancelLoginAttempt or LoginAttemptFailed.
+func BeginLoginAttempt(email, ip string, adminID *uuid.UUID) (LoginAttempt, time.Duration, error) {
+	attempt := LoginAttempt{Email: email, IP: ip, AdminID: adminID, at: time.Now()}
+
+	account, counted, err := LoginAttempts.CountAttempt(AccountLoginKey(email), attempt.at, AccountLoginThrottle.LockoutFor)
+	if err != nil {
+		return attempt, 0, err
+	}
+	if !counted {
+		return attempt, account.RetryAfter(attempt.at), nil
+	}
+
+	address, counted, err := LoginAttempts.CountAttempt(IPLoginKey(ip), attempt.at, IPLoginThrottle.LockoutFor)
+	if err != nil {
+		return attempt, 0, err
+	}
+	if !counted {
+		if err := LoginAttempts.Forgive(AccountLoginKey(email), AccountLoginThrottle.LockoutFor); err != nil {
+			return attempt, 0, err
+		}
+		return attempt, address.RetryAfter(attempt.at), nil
+	}
+
+	attempt.account = account
+	attempt.address = address
+	return attempt, 0, nil
+}
+
+// LoginAttemptFailed leaves the attempt counted as a failure and raises an
+// audit event for every lockout it started. A successful attempt resets the
+// account instead, so it must not be reported as a lockout.
+func LoginAttemptFailed(db *gorm.DB, attempt LoginAttempt) error {
+	if attempt.account.RetryAfter(attempt.at) > 0 {
+		detail := fmt.Sprintf("account %s locked until %s after %d failed logins", attempt.Email, attempt.account.LockedUntil.Format(time.RFC3339), attempt.account.Failures)
+		if err := RecordAuditEvent(db, AuditLoginLockout, attempt.AdminID, attempt.IP, detail); err != nil {
+			return err
+		}
+	}
+
+	if attempt.address.RetryAfter(attempt.at) > 0 {
+		detail := fmt.Sprintf("ip %s locked until %s after %d failed logins", attempt.IP, attempt.address.LockedUntil.Format(time.RFC3339), attempt.address.Failures)
+		if err := RecordAuditEvent(db, AuditLoginLockout, nil, attempt.IP, detail); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoginAttemptSucceeded clears the account's failures and takes the attempt
+// back from the IP.
+func LoginAttemptSucceeded(email, ip string) error {
+	if err := LoginAttempts.Reset(AccountLoginKey(email)); err != nil {
+		return err
+	}
+
+	return LoginAttempts.Forgive(IPLoginKey(ip), IPLoginThrottle.LockoutFor)
+}
+
+// CancelLoginAttempt takes back an attempt that ended before a credential was
+// checked.
+func CancelLoginAttempt(email, ip string) error {
+	if err := LoginAttempts.Forgive(AccountLoginKey(email), AccountLoginThrottle.LockoutFor); err != nil {
+		return err
+	}
+
+	return LoginAttempts.Forgive(IPLoginKey(ip), IPLoginThrottle.LockoutFor)
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"golang-final-project/models"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestLoginThrottleBackoff(t *testing.T) {
+	throttle := LoginThrottle{FreeAttempts: 2, BaseLockout: 30 * time.Second, MaxLockout: 2 * time.Minute}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: 0},
+		{failures: 2, want: 0},
+		{failures: 3, want: 30 * time.Second},
+		{failures: 4, want: time.Minute},
+		{failures: 5, want: 2 * time.Minute},
+		{failures: 9, want: 2 * time.Minute},
+	}
+
+	for _, test := range tests {
+		if got := throttle.LockoutFor(test.failures); got != test.want {
+			t.Errorf("LockoutFor(%d) = %s, want %s", test.failures, got, test.want)
+		}
+	}
+}
+
+// useTestLoginThrottle swaps in a store of the given kind and a throttle
+// that locks the account out after two failures and the IP after four.
+func useTestLoginThrottle(t *testing.T, db *gorm.DB, store string) {
+	t.Helper()
+
+	previousStore, previousAccount, previousIP := LoginAttempts, AccountLoginThrottle, IPLoginThrottle
+	t.Cleanup(func() {
+		LoginAttempts, AccountLoginThrottle, IPLoginThrottle = previousStore, previousAccount, previousIP
+	})
+
+	LoginAttempts = NewMemoryLoginAttemptStore()
+	if store == "gorm" {
+		LoginAttempts = NewGormLoginAttemptStore(db)
+	}
+	AccountLoginThrottle = LoginThrottle{FreeAttempts: 2, BaseLockout: time.Minute, MaxLockout: time.Hour}
+	IPLoginThrottle = LoginThrottle{FreeAttempts: 4, BaseLockout: time.Minute, MaxLockout: time.Hour}
+}
+
+func countLockoutEvents(t *testing.T, db *gorm.DB) int64 {
+	t.Helper()
+
+	var events int64
+	if err := db.Model(&models.AuditEvent{}).Where("type = ?", AuditLoginLockout).Count(&events).Error; err != nil {
+		t.Fatalf("count audit events: %v", err)
+	}
+	return events
+}
+
+func TestLoginLockout(t *testing.T) {
+	for _, store := range []string{"memory", "gorm"} {
+		t.Run(store, func(t *testing.T) {
+			t.Run("the failure that crosses the threshold locks out", func(t *testing.T) {
+				db := openTestDB(t)
+				useTestLoginThrottle(t, db, store)
+
+				for i := 0; i < 3; i++ {
+					attempt, retryAfter, err := BeginLoginAttempt("ada@example.com", "10.0.0.1", nil)
+					if err != nil || retryAfter != 0 {
+						t.Fatalf("attempt %d: retryAfter %s, error %v", i+1, retryAfter, err)
+					}
+					if err := LoginAttemptFailed(db, attempt); err != nil {
+						t.Fatalf("LoginAttemptFailed: %v", err)
+					}
+				}
+
+				if events := countLockoutEvents(t, db); events != 1 {
+					t.Fatalf("%d lockout events, want 1", events)
+				}
+
+				_, retryAfter, err := BeginLoginAttempt("ada@example.com", "10.0.0.1", nil)
+				if err != nil || retryAfter <= 0 || retryAfter > time.Minute {
+					t.Fatalf("locked out attempt: retryAfter %s, error %v", retryAfter, err)
+				}
+
+				state, _ := LoginAttempts.Get(AccountLoginKey("ada@example.com"))
+				if state.Failures != 3 {
+					t.Fatalf("account failures = %d, want 3: an attempt during the lockout is not counted", state.Failures)
+				}
+			})
+
+			t.Run("a correct password at the threshold is no lockout", func(t *testing.T) {
+				db := openTestDB(t)
+				useTestLoginThrottle(t, db, store)
+
+				for i := 0; i < 2; i++ {
+					attempt, _, err := BeginLoginAttempt("ada@example.com", "10.0.0.1", nil)
+					if err != nil {
+						t.Fatalf("BeginLoginAttempt: %v", err)
+					}
+					if err := LoginAttemptFailed(db, attempt); err != nil {
+						t.Fatalf("LoginAttemptFailed: %v", err)
+					}
+				}
+
+				if _, _, err := BeginLoginAttempt("ada@example.com", "10.0.0.1", nil); err != nil {
+					t.Fatalf("BeginLoginAttempt: %v", err)
+				}
+				if err := LoginAttemptSucceeded("ada@example.com", "10.0.0.1"); err != nil {
+					t.Fatalf("LoginAttemptSucceeded: %v", err)
+				}
+
+				if events := countLockoutEvents(t, db); events != 0 {
+					t.Fatalf("%d lockout events after a successful login, want 0", events)
+				}
+
+				if _, retryAfter, err := BeginLoginAttempt("ada@example.com", "10.0.0.1", nil); err != nil || retryAfter != 0 {
+					t.Fatalf("attempt after the successful login: retryAfter %s, error %v", retryAfter, err)
+				}
+			})
+
+			t.Run("a locked out IP does not count against the account", func(t *testing.T) {
+				db := openTestDB(t)
+				useTestLoginThrottle(t, db, store)
+
+				// One failure for each of five accounts locks the IP out
+				for _, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"} {
+					attempt, _, err := BeginLoginAttempt(email, "10.0.0.1", nil)
+					if err != nil {
+						t.Fatalf("BeginLoginAttempt: %v", err)
+					}
+					if err := LoginAttemptFailed(db, attempt); err != nil {
+						t.Fatalf("LoginAttemptFailed: %v", err)
+					}
+				}
+
+				_, retryAfter, err := BeginLoginAttempt("ada@example.com", "10.0.0.1", nil)
+				if err != nil || retryAfter <= 0 {
+					t.Fatalf("attempt from the locked out IP: retryAfter %s, error %v", retryAfter, err)
+				}
+
+				state, _ := LoginAttempts.Get(AccountLoginKey("ada@example.com"))
+				if state.Failures != 0 {
+					t.Fatalf("account failures = %d, want 0", state.Failures)
+				}
+
+				if _, retryAfter, err := BeginLoginAttempt("ada@example.com", "10.0.0.2", nil); err != nil || retryAfter != 0 {
+					t.Fatalf("attempt from another IP: retryAfter %s, error %v", retryAfter, err)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+)
+
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(email Email) error
+}
+
+var DefaultMailer Mailer = &LogMailer{}
+
+// NewMailerFromEnv picks the mailer from MAIL_DRIVER ("smtp" or "log").
+func NewMailerFromEnv() Mailer {
+	if os.Getenv("MAIL_DRIVER") == "smtp" {
+		return &SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}
+	}
+
+	return &LogMailer{Path: os.Getenv("MAIL_LOG_PATH")}
+}
+
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(email Email) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		m.From, email.To, email.Subject, strings.ReplaceAll(email.Body, "\n", "\r\n"))
+
+	return smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{email.To}, []byte(message))
+}
+
+// LogMailer appends emails to the file at Path, or to the standard logger when
+// Path is empty. It is meant for local development and tests.
+type LogMailer struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (m *LogMailer) Send(email Email) error {
+	entry := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n---\n", email.To, email.Subject, email.Body)
+
+	if m.Path == "" {
+		log.Print(entry)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := os.OpenFile(m.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(entry)
+	return err
+}
+
+// appLink builds a link into the frontend from APP_URL. Without APP_URL the
+// token is sent as is.
+func appLink(path, token string) string {
+	appURL := strings.TrimRight(os.Getenv("APP_URL"), "/")
+	if appURL == "" {
+		return token
+	}
+
+	return appURL + path + "?token=" + token
+}
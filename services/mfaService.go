@@ -0,0 +1,185 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	totpIssuer        = "Base Trade"
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("multi-factor authentication is already enabled")
+	ErrMFANotEnrolled    = errors.New("multi-factor authentication is not enrolled")
+	ErrInvalidMFACode    = errors.New("invalid authentication code")
+)
+
+// StartTOTPEnrollment stores a new, not yet confirmed secret for the admin
+// and returns it with its otpauth:// URI.
+func StartTOTPEnrollment(db *gorm.DB, admin *models.Admin) (string, string, error) {
+	if admin.MFAEnabledAt != nil {
+		return "", "", ErrMFAAlreadyEnabled
+	}
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	err = db.Model(&models.Admin{}).Where("id = ?", admin.ID).Updates(map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_last_step": 0,
+	}).Error
+	if err != nil {
+		return "", "", err
+	}
+
+	return secret, TOTPURI(totpIssuer, admin.Email, secret), nil
+}
+
+// ConfirmTOTPEnrollment enables MFA once the admin proves the authenticator
+// works, and returns a fresh set of recovery codes.
+func ConfirmTOTPEnrollment(db *gorm.DB, admin *models.Admin, code string) ([]string, error) {
+	if admin.MFAEnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if admin.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+
+	var codes []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := useTOTPCode(tx, admin, code); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Admin{}).Where("id = ?", admin.ID).Update("mfa_enabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, admin.ID)
+		return err
+	})
+
+	return codes, err
+}
+
+// VerifyMFACode accepts either a TOTP code or an unused recovery code.
+func VerifyMFACode(db *gorm.DB, admin *models.Admin, code, recoveryCode string) error {
+	if admin.MFAEnabledAt == nil {
+		return ErrMFANotEnrolled
+	}
+
+	if recoveryCode != "" {
+		return useRecoveryCode(db, admin.ID, recoveryCode)
+	}
+
+	return useTOTPCode(db, admin, code)
+}
+
+func RegenerateRecoveryCodes(db *gorm.DB, admin *models.Admin, code string) ([]string, error) {
+	if admin.MFAEnabledAt == nil {
+		return nil, ErrMFANotEnrolled
+	}
+
+	var codes []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := useTOTPCode(tx, admin, code); err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, admin.ID)
+		return err
+	})
+
+	return codes, err
+}
+
+func DisableMFA(db *gorm.DB, admin *models.Admin, code string) error {
+	if admin.MFAEnabledAt == nil {
+		return ErrMFANotEnrolled
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := useTOTPCode(tx, admin, code); err != nil {
+			return err
+		}
+
+		err := tx.Model(&models.Admin{}).Where("id = ?", admin.ID).Updates(map[string]interface{}{
+			"totp_secret":    "",
+			"totp_last_step": 0,
+			"mfa_enabled_at": nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("admin_id = ?", admin.ID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// useTOTPCode validates the code and records its time step, so the same code
+// cannot be replayed, even by a concurrent request.
+func useTOTPCode(db *gorm.DB, admin *models.Admin, code string) error {
+	step, ok := ValidateTOTP(admin.TOTPSecret, code, time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	res := db.Model(&models.Admin{}).
+		Where("id = ? AND totp_last_step < ?", admin.ID, step).
+		Update("totp_last_step", step)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+func useRecoveryCode(db *gorm.DB, adminID uuid.UUID, code string) error {
+	res := db.Model(&models.RecoveryCode{}).
+		Where("admin_id = ? AND code_hash = ? AND used_at IS NULL", adminID, HashToken(strings.ToLower(strings.TrimSpace(code)))).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+func replaceRecoveryCodes(db *gorm.DB, adminID uuid.UUID) ([]string, error) {
+	if err := db.Where("admin_id = ?", adminID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes, err := GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes := make([]models.RecoveryCode, len(codes))
+	for i, code := range codes {
+		recoveryCodes[i] = models.RecoveryCode{AdminID: adminID, CodeHash: HashToken(code)}
+	}
+
+	if err := db.Create(&recoveryCodes).Error; err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
@@ -0,0 +1,529 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang-final-project/models"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	AuthMethodOIDC = "oidc"
+
+	// PurposeOIDCLink marks the token handed out when an identity matches an
+	// admin who has to confirm the link with their password.
+	PurposeOIDCLink = "oidc_link"
+
+	oidcStateTTL = 10 * time.Minute
+)
+
+var (
+	ErrInvalidOIDCState       = errors.New("invalid or expired oidc state")
+	ErrInvalidIDToken         = errors.New("invalid id token")
+	ErrOIDCAdminNotFound      = errors.New("no admin linked to this identity")
+	ErrOIDCEmailUnverified    = errors.New("identity provider did not verify the email")
+	ErrOIDCLinkRequired       = errors.New("the admin has to confirm the link with their password")
+	ErrInvalidOIDCLinkToken   = errors.New("invalid or expired oidc link token")
+	ErrOIDCSubjectAlreadyUsed = errors.New("the admin or the identity is already linked")
+)
+
+// OIDCLinkRequiredError is returned when the identity's verified email
+// belongs to an admin who is not linked yet. It matches ErrOIDCLinkRequired
+// with errors.Is.
+type OIDCLinkRequiredError struct {
+	AdminID uuid.UUID
+}
+
+func (e *OIDCLinkRequiredError) Error() string {
+	return ErrOIDCLinkRequired.Error()
+}
+
+func (e *OIDCLinkRequiredError) Is(target error) bool {
+	return target == ErrOIDCLinkRequired
+}
+
+// OIDC is nil unless OIDC_ISSUER is configured.
+var OIDC *OIDCProvider
+
+// OIDCProvider runs the authorization code flow with PKCE against a single
+// identity provider, discovered from its issuer URL.
+type OIDCProvider struct {
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	AutoProvision bool
+	// TrustMFA lets a second factor at the provider stand in for ours
+	TrustMFA   bool
+	HTTPClient *http.Client
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+type OIDCIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	// MFA is set when the provider reports a second factor in the amr claim
+	// and TrustMFA is on
+	MFA bool
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcIDTokenClaims struct {
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"`
+	Name          string      `json:"name"`
+	Nonce         string      `json:"nonce"`
+	AMR           []string    `json:"amr"`
+	jwt.RegisteredClaims
+}
+
+// NewOIDCProviderFromEnv reads the OIDC_* variables and returns nil when
+// OIDC_ISSUER is empty.
+func NewOIDCProviderFromEnv() *OIDCProvider {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return nil
+	}
+
+	scopes := []string{"openid", "email", "profile"}
+	if extra := os.Getenv("OIDC_SCOPES"); extra != "" {
+		scopes = strings.Fields(extra)
+	}
+
+	return &OIDCProvider{
+		Issuer:        strings.TrimRight(issuer, "/"),
+		ClientID:      os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:        scopes,
+		AutoProvision: envBool("OIDC_AUTO_PROVISION", false),
+		TrustMFA:      envBool("OIDC_TRUST_MFA", false),
+		HTTPClient:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// AuthorizationURL stores a fresh state, nonce and PKCE verifier and returns
+// the URL to send the browser to along with the state, which the caller binds
+// to the browser.
+func (p *OIDCProvider) AuthorizationURL(db *gorm.DB) (string, string, error) {
+	discovery, err := p.getDiscovery()
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := db.Where("expires_at < ?", time.Now()).Delete(&models.OIDCLoginState{}).Error; err != nil {
+		return "", "", err
+	}
+
+	loginState := models.OIDCLoginState{
+		StateHash:    HashToken(state),
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	}
+	if err := db.Create(&loginState).Error; err != nil {
+		return "", "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.ClientID)
+	query.Set("redirect_uri", p.RedirectURL)
+	query.Set("scope", strings.Join(p.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), state, nil
+}
+
+// Exchange consumes the state, redeems the code and verifies the id token.
+func (p *OIDCProvider) Exchange(db *gorm.DB, code, state string) (*OIDCIdentity, error) {
+	var loginState models.OIDCLoginState
+	err := db.Where("state_hash = ? AND expires_at > ?", HashToken(state), time.Now()).First(&loginState).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Each state can only be redeemed once
+	res := db.Where("state_hash = ?", loginState.StateHash).Delete(&models.OIDCLoginState{})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrInvalidOIDCState
+	}
+
+	discovery, err := p.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("client_id", p.ClientID)
+	form.Set("code_verifier", loginState.CodeVerifier)
+
+	request, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if p.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+
+	response, err := p.HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc token endpoint returned %s", response.Status)
+	}
+
+	var tokenResponse struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&tokenResponse); err != nil {
+		return nil, err
+	}
+	if tokenResponse.IDToken == "" {
+		return nil, ErrInvalidIDToken
+	}
+
+	return p.verifyIDToken(tokenResponse.IDToken, loginState.Nonce, discovery.Issuer)
+}
+
+func (p *OIDCProvider) verifyIDToken(idToken, nonce, issuer string) (*OIDCIdentity, error) {
+	claims := &oidcIDTokenClaims{}
+
+	token, err := jwt.ParseWithClaims(idToken, claims, p.keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384"}),
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if claims.Nonce != nonce || claims.Subject == "" {
+		return nil, ErrInvalidIDToken
+	}
+
+	identity := &OIDCIdentity{
+		Subject: claims.Subject,
+		Email:   strings.ToLower(claims.Email),
+		Name:    claims.Name,
+	}
+
+	switch verified := claims.EmailVerified.(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+
+	for _, method := range claims.AMR {
+		if method == "mfa" && p.TrustMFA {
+			identity.MFA = true
+		}
+	}
+
+	return identity, nil
+}
+
+func (p *OIDCProvider) getDiscovery() (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	if err := p.getJSON(p.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimRight(discovery.Issuer, "/") != p.Issuer {
+		return nil, fmt.Errorf("oidc discovery issuer %q does not match %q", discovery.Issuer, p.Issuer)
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// keyfunc looks the id token's kid up in the provider's JWKS, refetching it
+// at most once a minute when the kid is unknown.
+func (p *OIDCProvider) keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	if time.Since(p.keysFetchedAt) < time.Minute {
+		return nil, ErrUnknownSigningKey
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(p.discovery.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{})
+	for _, jwk := range jwks.Keys {
+		switch jwk.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch jwk.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			default:
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[jwk.Kid] = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownSigningKey
+}
+
+func (p *OIDCProvider) getJSON(url string, target interface{}) error {
+	response, err := p.HTTPClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %s", url, response.Status)
+	}
+
+	return json.NewDecoder(response.Body).Decode(target)
+}
+
+// ResolveOIDCAdmin finds the admin linked to the identity or provisions a new
+// admin when AutoProvision is on. An existing admin with the same verified
+// email is never linked here: the provider vouching for an email is not the
+// admin agreeing to it, so an OIDCLinkRequiredError asks for their password
+// first (see LinkOIDCSubject).
+func ResolveOIDCAdmin(db *gorm.DB, provider *OIDCProvider, identity *OIDCIdentity) (*models.Admin, error) {
+	var admin models.Admin
+	err := db.Where("oidc_subject = ?", identity.Subject).First(&admin).Error
+	if err == nil {
+		return &admin, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrOIDCEmailUnverified
+	}
+
+	existing, err := GetAdminByEmail(db, identity.Email)
+	if err == nil {
+		if existing.OIDCSubject != nil {
+			return nil, ErrOIDCAdminNotFound
+		}
+
+		return nil, &OIDCLinkRequiredError{AdminID: existing.ID}
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if !provider.AutoProvision {
+		return nil, ErrOIDCAdminNotFound
+	}
+
+	// SSO admins never log in with a password, so give them one nobody knows
+	randomPassword, err := GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	encryptedPassword, err := EncryptPassword(randomPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	name := identity.Name
+	if name == "" {
+		name = identity.Email
+	}
+
+	now := time.Now()
+	admin = models.Admin{
+		Name:            name,
+		Email:           identity.Email,
+		Password:        encryptedPassword,
+		Role:            RoleEditor,
+		EmailVerifiedAt: &now,
+		OIDCSubject:     &identity.Subject,
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := CreateAdmin(tx, &admin); err != nil {
+			return err
+		}
+
+		_, err := CreatePersonalOrganization(tx, &admin)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &admin, nil
+}
+
+// GenerateOIDCLinkToken hands the browser a short-lived proof of the identity
+// the provider vouched for, to be redeemed together with the admin's
+// password.
+func GenerateOIDCLinkToken(adminID uuid.UUID, subject string) (string, error) {
+	now := time.Now()
+	claims := &Claims{
+		AdminID: adminID,
+		Purpose: PurposeOIDCLink,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(oidcStateTTL)),
+		},
+	}
+
+	return Keys.Sign(claims)
+}
+
+func ParseOIDCLinkToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+
+	token, err := ParseToken(tokenString, claims)
+
+	if err != nil || !token.Valid || claims.Purpose != PurposeOIDCLink || claims.Subject == "" {
+		return nil, ErrInvalidOIDCLinkToken
+	}
+
+	return claims, nil
+}
+
+// LinkOIDCSubject links the identity to the admin once they have confirmed it.
+// An admin already linked to another identity, or an identity already linked
+// to another admin, is left alone.
+func LinkOIDCSubject(db *gorm.DB, adminID uuid.UUID, subject string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var linked int64
+		if err := tx.Model(&models.Admin{}).Where("oidc_subject = ?", subject).Count(&linked).Error; err != nil {
+			return err
+		}
+		if linked > 0 {
+			return ErrOIDCSubjectAlreadyUsed
+		}
+
+		res := tx.Model(&models.Admin{}).
+			Where("id = ? AND oidc_subject IS NULL", adminID).
+			Update("oidc_subject", subject)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrOIDCSubjectAlreadyUsed
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,358 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"golang-final-project/models"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// mockIdP serves discovery, a JWKS and a token endpoint that checks the PKCE
+// verifier and answers with whatever id token claims the test sets up.
+type mockIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// signer signs the id token; it defaults to key, the one in the JWKS
+	signer *rsa.PrivateKey
+	claims func(nonce string) jwt.MapClaims
+
+	challenges map[string]string
+	nonces     map[string]string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate idp key: %v", err)
+	}
+
+	idp := &mockIdP{
+		key:        key,
+		signer:     key,
+		challenges: map[string]string{},
+		nonces:     map[string]string{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "idp-key",
+				"kty": "RSA",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		code := r.PostForm.Get("code")
+		challenge, ok := idp.challenges[code]
+		verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != challenge {
+			http.Error(w, "invalid_grant", http.StatusBadRequest)
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, idp.claims(idp.nonces[code]))
+		token.Header["kid"] = "idp-key"
+		idToken, err := token.SignedString(idp.signer)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idToken})
+	})
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	idp.claims = idp.defaultClaims
+	return idp
+}
+
+func (idp *mockIdP) provider() *OIDCProvider {
+	return &OIDCProvider{
+		Issuer:      idp.server.URL,
+		ClientID:    "inventory",
+		RedirectURL: "https://inventory.example/callback",
+		Scopes:      []string{"openid", "email"},
+		HTTPClient:  idp.server.Client(),
+	}
+}
+
+func (idp *mockIdP) defaultClaims(nonce string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"aud":            "inventory",
+		"sub":            "subject-1",
+		"email":          "Ada@Example.com",
+		"email_verified": true,
+		"name":           "Ada",
+		"nonce":          nonce,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Minute).Unix(),
+	}
+}
+
+// authorize plays the browser's trip to the provider and returns the code
+// and state it redirects back with.
+func (idp *mockIdP) authorize(t *testing.T, db *gorm.DB, provider *OIDCProvider) (string, string) {
+	t.Helper()
+
+	authorizationURL, state, err := provider.AuthorizationURL(db)
+	if err != nil {
+		t.Fatalf("AuthorizationURL: %v", err)
+	}
+
+	parsed, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatalf("parse authorization url: %v", err)
+	}
+	query := parsed.Query()
+
+	if query.Get("state") != state {
+		t.Fatalf("authorization url state = %q, want %q", query.Get("state"), state)
+	}
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("code_challenge_method = %q, want S256", query.Get("code_challenge_method"))
+	}
+
+	code := "code-" + state
+	idp.challenges[code] = query.Get("code_challenge")
+	idp.nonces[code] = query.Get("nonce")
+
+	return code, state
+}
+
+func TestOIDCExchange(t *testing.T) {
+	db := openTestDB(t)
+	idp := newMockIdP(t)
+	provider := idp.provider()
+
+	code, state := idp.authorize(t, db, provider)
+
+	identity, err := provider.Exchange(db, code, state)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	if identity.Subject != "subject-1" || identity.Email != "ada@example.com" || !identity.EmailVerified {
+		t.Fatalf("identity = %+v", identity)
+	}
+
+	if _, err := provider.Exchange(db, code, state); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("second Exchange error = %v, want ErrInvalidOIDCState", err)
+	}
+
+	if _, err := provider.Exchange(db, code, "unknown-state"); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("unknown state error = %v, want ErrInvalidOIDCState", err)
+	}
+}
+
+func TestOIDCExchangeRejectsBadIDTokens(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(claims jwt.MapClaims)
+		signer *rsa.PrivateKey
+	}{
+		{name: "wrong nonce", mutate: func(claims jwt.MapClaims) { claims["nonce"] = "replayed" }},
+		{name: "wrong audience", mutate: func(claims jwt.MapClaims) { claims["aud"] = "someone-else" }},
+		{name: "wrong issuer", mutate: func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example" }},
+		{name: "expired", mutate: func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{name: "no expiry", mutate: func(claims jwt.MapClaims) { delete(claims, "exp") }},
+		{name: "no subject", mutate: func(claims jwt.MapClaims) { delete(claims, "sub") }},
+		{name: "bad signature", signer: otherKey},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := openTestDB(t)
+			idp := newMockIdP(t)
+			provider := idp.provider()
+
+			idp.claims = func(nonce string) jwt.MapClaims {
+				claims := idp.defaultClaims(nonce)
+				if test.mutate != nil {
+					test.mutate(claims)
+				}
+				return claims
+			}
+			if test.signer != nil {
+				idp.signer = test.signer
+			}
+
+			code, state := idp.authorize(t, db, provider)
+
+			if _, err := provider.Exchange(db, code, state); !errors.Is(err, ErrInvalidIDToken) {
+				t.Fatalf("Exchange error = %v, want ErrInvalidIDToken", err)
+			}
+		})
+	}
+}
+
+func TestOIDCExchangeTrustsMFAOnlyWhenConfigured(t *testing.T) {
+	for _, trust := range []bool{false, true} {
+		db := openTestDB(t)
+		idp := newMockIdP(t)
+		provider := idp.provider()
+		provider.TrustMFA = trust
+
+		idp.claims = func(nonce string) jwt.MapClaims {
+			claims := idp.defaultClaims(nonce)
+			claims["amr"] = []string{"pwd", "mfa"}
+			return claims
+		}
+
+		code, state := idp.authorize(t, db, provider)
+
+		identity, err := provider.Exchange(db, code, state)
+		if err != nil {
+			t.Fatalf("Exchange: %v", err)
+		}
+		if identity.MFA != trust {
+			t.Fatalf("TrustMFA %v: identity.MFA = %v", trust, identity.MFA)
+		}
+	}
+}
+
+func TestResolveOIDCAdmin(t *testing.T) {
+	t.Run("links an existing admin only once they confirm", func(t *testing.T) {
+		db := openTestDB(t)
+		existing := models.Admin{Name: "Ada", Email: "ada@example.com", Password: "x"}
+		if err := CreateAdmin(db, &existing); err != nil {
+			t.Fatalf("CreateAdmin: %v", err)
+		}
+
+		identity := &OIDCIdentity{Subject: "subject-1", Email: "ada@example.com", EmailVerified: true}
+		_, err := ResolveOIDCAdmin(db, &OIDCProvider{AutoProvision: true}, identity)
+		var linkRequired *OIDCLinkRequiredError
+		if !errors.As(err, &linkRequired) || linkRequired.AdminID != existing.ID {
+			t.Fatalf("ResolveOIDCAdmin error = %v, want OIDCLinkRequiredError for the existing admin", err)
+		}
+
+		var linked int64
+		db.Model(&models.Admin{}).Where("oidc_subject IS NOT NULL").Count(&linked)
+		if linked != 0 {
+			t.Fatalf("%d admins linked before confirming, want 0", linked)
+		}
+
+		linkToken, err := GenerateOIDCLinkToken(existing.ID, identity.Subject)
+		if err != nil {
+			t.Fatalf("GenerateOIDCLinkToken: %v", err)
+		}
+		claims, err := ParseOIDCLinkToken(linkToken)
+		if err != nil || claims.AdminID != existing.ID || claims.Subject != "subject-1" {
+			t.Fatalf("ParseOIDCLinkToken = %+v, %v", claims, err)
+		}
+		if err := LinkOIDCSubject(db, claims.AdminID, claims.Subject); err != nil {
+			t.Fatalf("LinkOIDCSubject: %v", err)
+		}
+
+		// The link holds even once the email changes at the provider
+		identity = &OIDCIdentity{Subject: "subject-1", Email: "ada@elsewhere.example"}
+		admin, err := ResolveOIDCAdmin(db, &OIDCProvider{}, identity)
+		if err != nil || admin.ID != existing.ID {
+			t.Fatalf("ResolveOIDCAdmin by subject = %v, %v", admin, err)
+		}
+
+		// An already linked admin is not taken over by another subject
+		identity = &OIDCIdentity{Subject: "subject-2", Email: "ada@example.com", EmailVerified: true}
+		if _, err := ResolveOIDCAdmin(db, &OIDCProvider{}, identity); !errors.Is(err, ErrOIDCAdminNotFound) {
+			t.Fatalf("second subject error = %v, want ErrOIDCAdminNotFound", err)
+		}
+		if err := LinkOIDCSubject(db, existing.ID, "subject-2"); !errors.Is(err, ErrOIDCSubjectAlreadyUsed) {
+			t.Fatalf("relinking error = %v, want ErrOIDCSubjectAlreadyUsed", err)
+		}
+	})
+
+	t.Run("does not take an access token as a link token", func(t *testing.T) {
+		db := openTestDB(t)
+		admin := models.Admin{Name: "Ada", Email: "ada@example.com", Password: "x"}
+		if err := CreateAdmin(db, &admin); err != nil {
+			t.Fatalf("CreateAdmin: %v", err)
+		}
+
+		token, err := GenerateJWT(&admin, admin.ID, AuthMethodPassword)
+		if err != nil {
+			t.Fatalf("GenerateJWT: %v", err)
+		}
+		if _, err := ParseOIDCLinkToken(token); !errors.Is(err, ErrInvalidOIDCLinkToken) {
+			t.Fatalf("ParseOIDCLinkToken error = %v, want ErrInvalidOIDCLinkToken", err)
+		}
+	})
+
+	t.Run("refuses an unverified email", func(t *testing.T) {
+		db := openTestDB(t)
+		existing := models.Admin{Name: "Ada", Email: "ada@example.com", Password: "x"}
+		if err := CreateAdmin(db, &existing); err != nil {
+			t.Fatalf("CreateAdmin: %v", err)
+		}
+
+		identity := &OIDCIdentity{Subject: "subject-1", Email: "ada@example.com"}
+		if _, err := ResolveOIDCAdmin(db, &OIDCProvider{AutoProvision: true}, identity); !errors.Is(err, ErrOIDCEmailUnverified) {
+			t.Fatalf("error = %v, want ErrOIDCEmailUnverified", err)
+		}
+
+		var linked int64
+		db.Model(&models.Admin{}).Where("oidc_subject IS NOT NULL").Count(&linked)
+		if linked != 0 {
+			t.Fatalf("%d admins linked, want 0", linked)
+		}
+	})
+
+	t.Run("provisions only when enabled", func(t *testing.T) {
+		db := openTestDB(t)
+		identity := &OIDCIdentity{Subject: "subject-1", Email: "grace@example.com", EmailVerified: true, Name: "Grace"}
+
+		if _, err := ResolveOIDCAdmin(db, &OIDCProvider{}, identity); !errors.Is(err, ErrOIDCAdminNotFound) {
+			t.Fatalf("error = %v, want ErrOIDCAdminNotFound", err)
+		}
+
+		admin, err := ResolveOIDCAdmin(db, &OIDCProvider{AutoProvision: true}, identity)
+		if err != nil {
+			t.Fatalf("ResolveOIDCAdmin: %v", err)
+		}
+		if admin.Email != "grace@example.com" || admin.Role != RoleEditor || admin.EmailVerifiedAt == nil {
+			t.Fatalf("provisioned admin = %+v", admin)
+		}
+
+		organizations, err := GetOrganizationsByAdminID(db, admin.ID)
+		if err != nil || len(organizations) != 1 {
+			t.Fatalf("organizations = %v, %v; want one personal organization", organizations, err)
+		}
+	})
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	OrderPending   = "pending"
+	OrderConfirmed = "confirmed"
+	OrderFulfilled = "fulfilled"
+	OrderCancelled = "cancelled"
+)
+
+var (
+	ErrInvalidOrderLines   = errors.New("order lines need distinct variants, positive quantities and non-negative prices")
+	ErrOrderNotPending     = errors.New("order is not pending")
+	ErrOrderNotFulfillable = errors.New("order can not be fulfilled")
+	ErrOrderNotCancellable = errors.New("order can no longer be cancelled")
+)
+
+// OrderLineError tells which line of an order failed.
+type OrderLineError struct {
+	VariantID uuid.UUID
+	Err       error
+}
+
+func (e *OrderLineError) Error() string {
+	return "variant " + e.VariantID.String() + ": " + e.Err.Error()
+}
+
+func (e *OrderLineError) Unwrap() error {
+	return e.Err
+}
+
+// OrderLineInput is a line of a new order. Without a UnitPrice the line is
+// charged the variant's current price for the order's customer group.
+type OrderLineInput struct {
+	VariantID uuid.UUID
+	Quantity  int
+	UnitPrice *int64
+}
+
+// CreateOrder creates a pending order. Its variants must belong to the
+// order's organization and any variant with a price must be priced in the
+// order's currency; no stock is taken until the order is confirmed.
+func CreateOrder(db *gorm.DB, order *models.Order, lines []OrderLineInput) error {
+	if len(lines) == 0 {
+		return ErrInvalidOrderLines
+	}
+
+	seen := make(map[uuid.UUID]bool, len(lines))
+	variantIDs := make([]uuid.UUID, 0, len(lines))
+	for _, line := range lines {
+		if line.Quantity <= 0 || (line.UnitPrice != nil && *line.UnitPrice < 0) || seen[line.VariantID] {
+			return ErrInvalidOrderLines
+		}
+		seen[line.VariantID] = true
+		variantIDs = append(variantIDs, line.VariantID)
+	}
+
+	inOrganization, err := variantsInOrganization(db, order.OrganizationID, variantIDs)
+	if err != nil {
+		return err
+	}
+	if !inOrganization {
+		return ErrVariantNotInOrganization
+	}
+
+	now := time.Now()
+	order.Lines = make([]models.OrderLine, 0, len(lines))
+	order.TotalAmount = 0
+
+	for _, line := range lines {
+		price, err := ResolveCustomerPrice(db, line.VariantID, line.Quantity, order.CustomerGroupID, now)
+		if err != nil && !errors.Is(err, ErrVariantNotPriced) {
+			return err
+		}
+		if price != nil && price.Currency != order.Currency {
+			return ErrCurrencyMismatch
+		}
+
+		var unitPrice int64
+		switch {
+		case line.UnitPrice != nil:
+			unitPrice = *line.UnitPrice
+		case price != nil:
+			unitPrice = price.UnitPrice
+		default:
+			return &OrderLineError{VariantID: line.VariantID, Err: ErrVariantNotPriced}
+		}
+
+		order.Lines = append(order.Lines, models.OrderLine{
+			VariantID: line.VariantID,
+			Quantity:  line.Quantity,
+			UnitPrice: unitPrice,
+			Status:    OrderPending,
+		})
+		order.TotalAmount += unitPrice * int64(line.Quantity)
+	}
+
+	order.Status = OrderPending
+
+	return db.Create(order).Error
+}
+
+func GetAllOrders(db *gorm.DB, tenant Tenant, status string, page, pageSize int) ([]models.Order, error) {
+	var orders []models.Order
+
+	offset := (page - 1) * pageSize
+
+	query := db.Scopes(OrderTenantScope(tenant)).Order("created_at DESC").Offset(offset).Limit(pageSize)
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Find(&orders).Error; err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+func GetOrderByID(db *gorm.DB, tenant Tenant, id uuid.UUID) (*models.Order, error) {
+	var order models.Order
+	err := db.Scopes(OrderTenantScope(tenant)).
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+		First(&order, id).Error
+	return &order, err
+}
+
+// ConfirmOrder takes the stock of every line from the order's location. If
+// any variant is short nothing is taken and the order stays pending.
+func ConfirmOrder(db *gorm.DB, id uuid.UUID, input StockMovementInput) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		order, err := lockOrder(tx, id)
+		if err != nil {
+			return err
+		}
+		if order.Status != OrderPending {
+			return ErrOrderNotPending
+		}
+
+		if err := moveOrderStock(tx, order, MovementSell, input); err != nil {
+			return err
+		}
+
+		return setOrderStatus(tx, order, OrderConfirmed, map[string]interface{}{"confirmed_at": time.Now()})
+	})
+}
+
+// FulfilOrder ships a confirmed order. A pending order is confirmed on the
+// way, taking its stock all or nothing like ConfirmOrder.
+func FulfilOrder(db *gorm.DB, id uuid.UUID, input StockMovementInput) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		order, err := lockOrder(tx, id)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		updates := map[string]interface{}{"fulfilled_at": now}
+
+		switch order.Status {
+		case OrderPending:
+			if err := moveOrderStock(tx, order, MovementSell, input); err != nil {
+				return err
+			}
+			updates["confirmed_at"] = now
+		case OrderConfirmed:
+		default:
+			return ErrOrderNotFulfillable
+		}
+
+		return setOrderStatus(tx, order, OrderFulfilled, updates)
+	})
+}
+
+// CancelOrder cancels a pending or confirmed order. The stock a confirmed
+// order took goes back to its location.
+func CancelOrder(db *gorm.DB, id uuid.UUID, input StockMovementInput) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		order, err := lockOrder(tx, id)
+		if err != nil {
+			return err
+		}
+
+		switch order.Status {
+		case OrderPending:
+		case OrderConfirmed:
+			if err := moveOrderStock(tx, order, MovementReturn, input); err != nil {
+				return err
+			}
+		default:
+			return ErrOrderNotCancellable
+		}
+
+		return setOrderStatus(tx, order, OrderCancelled, map[string]interface{}{"cancelled_at": time.Now()})
+	})
+}
+
+func lockOrder(tx *gorm.DB, id uuid.UUID) (*models.Order, error) {
+	var order models.Order
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Lines").First(&order, id).Error
+	return &order, err
+}
+
+// moveOrderStock records a sell or return movement for every line. Lines are
+// handled in variant order so that concurrent orders lock variants in the
+// same order.
+func moveOrderStock(tx *gorm.DB, order *models.Order, movementType string, input StockMovementInput) error {
+	lines := append([]models.OrderLine(nil), order.Lines...)
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i].VariantID.String() < lines[j].VariantID.String()
+	})
+
+	input.Type = movementType
+	input.LocationID = &order.LocationID
+	if input.Reference == "" {
+		input.Reference = "order:" + order.ID.String()
+	}
+
+	for _, line := range lines {
+		input.Quantity = line.Quantity
+		if movementType == MovementSell {
+			input.Quantity = -line.Quantity
+		}
+
+		if _, err := RecordStockMovement(tx, line.VariantID, input); err != nil {
+			return &OrderLineError{VariantID: line.VariantID, Err: err}
+		}
+	}
+
+	return nil
+}
+
+func setOrderStatus(tx *gorm.DB, order *models.Order, status string, updates map[string]interface{}) error {
+	updates["status"] = status
+	if err := tx.Model(&models.Order{}).Where("id = ?", order.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.OrderLine{}).Where("order_id = ?", order.ID).Update("status", status).Error
+}
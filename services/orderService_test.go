@@ -0,0 +1,198 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// createTestOrder creates a pending order at the organization's default
+// location for quantity units of each variant, priced at 100 apiece.
+func createTestOrder(t *testing.T, db *gorm.DB, admin *models.Admin, quantities map[*models.Variant]int) *models.Order {
+	t.Helper()
+
+	organizationID, err := GetDefaultOrganizationID(db, admin.ID)
+	if err != nil {
+		t.Fatalf("GetDefaultOrganizationID: %v", err)
+	}
+	locationID, err := EnsureDefaultLocation(db, organizationID)
+	if err != nil {
+		t.Fatalf("EnsureDefaultLocation: %v", err)
+	}
+
+	unitPrice := int64(100)
+	lines := make([]OrderLineInput, 0, len(quantities))
+	for variant, quantity := range quantities {
+		lines = append(lines, OrderLineInput{VariantID: variant.ID, Quantity: quantity, UnitPrice: &unitPrice})
+	}
+
+	order := &models.Order{OrganizationID: organizationID, LocationID: locationID, Currency: "EUR", CreatedByID: admin.ID}
+	if err := CreateOrder(db, order, lines); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	return order
+}
+
+func loadTestOrder(t *testing.T, db *gorm.DB, order *models.Order) models.Order {
+	t.Helper()
+
+	var loaded models.Order
+	if err := db.Preload("Lines").First(&loaded, order.ID).Error; err != nil {
+		t.Fatalf("load order: %v", err)
+	}
+	return loaded
+}
+
+func countTestMovements(t *testing.T, db *gorm.DB, reference string) int64 {
+	t.Helper()
+
+	var movements int64
+	if err := db.Model(&models.StockMovement{}).Where("reference = ?", reference).Count(&movements).Error; err != nil {
+		t.Fatalf("count stock movements: %v", err)
+	}
+	return movements
+}
+
+func TestConfirmOrderTakesAllOrNothing(t *testing.T) {
+	db := openTestDB(t)
+	admin, stocked := createTestVariant(t, db, 10)
+
+	short := models.Variant{VariantName: "Small", ProductID: stocked.ProductID}
+	if err := CreateVariant(db, &short); err != nil {
+		t.Fatalf("CreateVariant: %v", err)
+	}
+	if _, err := RecordStockMovement(db, short.ID, StockMovementInput{Type: MovementReceive, Quantity: 1, ReasonCode: ReasonInitial}); err != nil {
+		t.Fatalf("RecordStockMovement: %v", err)
+	}
+
+	order := createTestOrder(t, db, admin, map[*models.Variant]int{stocked: 4, &short: 2})
+	reference := "order:" + order.ID.String()
+
+	err := ConfirmOrder(db, order.ID, StockMovementInput{})
+	var lineErr *OrderLineError
+	if !errors.As(err, &lineErr) || lineErr.VariantID != short.ID || !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("confirm with a short line error = %v, want ErrInsufficientStock for %s", err, short.ID)
+	}
+
+	// Neither line moved, not even the one with enough stock
+	if movements := countTestMovements(t, db, reference); movements != 0 {
+		t.Fatalf("%d stock movements after the refused confirm, want 0", movements)
+	}
+	if got := loadTestVariant(t, db, stocked.ID); got.Quantity != 10 {
+		t.Fatalf("stocked quantity = %d, want 10", got.Quantity)
+	}
+	if got := loadTestVariant(t, db, short.ID); got.Quantity != 1 {
+		t.Fatalf("short quantity = %d, want 1", got.Quantity)
+	}
+
+	got := loadTestOrder(t, db, order)
+	if got.Status != OrderPending || got.ConfirmedAt != nil {
+		t.Fatalf("status %s confirmed at %v, want pending", got.Status, got.ConfirmedAt)
+	}
+	for _, line := range got.Lines {
+		if line.Status != OrderPending {
+			t.Fatalf("line status %s, want pending", line.Status)
+		}
+	}
+
+	if err := FulfilOrder(db, order.ID, StockMovementInput{}); !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("fulfil with a short line error = %v, want ErrInsufficientStock", err)
+	}
+	if got := loadTestOrder(t, db, order); got.Status != OrderPending {
+		t.Fatalf("status after the refused fulfil = %s, want pending", got.Status)
+	}
+
+	if _, err := RecordStockMovement(db, short.ID, StockMovementInput{Type: MovementReceive, Quantity: 1}); err != nil {
+		t.Fatalf("restock: %v", err)
+	}
+	if err := ConfirmOrder(db, order.ID, StockMovementInput{}); err != nil {
+		t.Fatalf("ConfirmOrder: %v", err)
+	}
+	if movements := countTestMovements(t, db, reference); movements != 2 {
+		t.Fatalf("%d stock movements after confirming, want 2", movements)
+	}
+	if err := ConfirmOrder(db, order.ID, StockMovementInput{}); !errors.Is(err, ErrOrderNotPending) {
+		t.Fatalf("second confirm error = %v, want ErrOrderNotPending", err)
+	}
+}
+
+func TestFulfilPendingOrder(t *testing.T) {
+	db := openTestDB(t)
+	admin, variant := createTestVariant(t, db, 10)
+	order := createTestOrder(t, db, admin, map[*models.Variant]int{variant: 3})
+
+	if err := FulfilOrder(db, order.ID, StockMovementInput{}); err != nil {
+		t.Fatalf("FulfilOrder: %v", err)
+	}
+
+	got := loadTestOrder(t, db, order)
+	if got.Status != OrderFulfilled || got.ConfirmedAt == nil || got.FulfilledAt == nil {
+		t.Fatalf("status %s confirmed at %v fulfilled at %v, want fulfilled", got.Status, got.ConfirmedAt, got.FulfilledAt)
+	}
+	if got.Lines[0].Status != OrderFulfilled {
+		t.Fatalf("line status %s, want fulfilled", got.Lines[0].Status)
+	}
+	if stocked := loadTestVariant(t, db, variant.ID); stocked.Quantity != 7 {
+		t.Fatalf("quantity = %d, want 7", stocked.Quantity)
+	}
+
+	if err := FulfilOrder(db, order.ID, StockMovementInput{}); !errors.Is(err, ErrOrderNotFulfillable) {
+		t.Fatalf("second fulfil error = %v, want ErrOrderNotFulfillable", err)
+	}
+	if err := CancelOrder(db, order.ID, StockMovementInput{}); !errors.Is(err, ErrOrderNotCancellable) {
+		t.Fatalf("cancel a fulfilled order error = %v, want ErrOrderNotCancellable", err)
+	}
+	if stocked := loadTestVariant(t, db, variant.ID); stocked.Quantity != 7 {
+		t.Fatalf("quantity after the refused calls = %d, want 7", stocked.Quantity)
+	}
+}
+
+func TestCancelConfirmedOrderReturnsStock(t *testing.T) {
+	db := openTestDB(t)
+	admin, variant := createTestVariant(t, db, 10)
+	order := createTestOrder(t, db, admin, map[*models.Variant]int{variant: 4})
+
+	if err := ConfirmOrder(db, order.ID, StockMovementInput{}); err != nil {
+		t.Fatalf("ConfirmOrder: %v", err)
+	}
+	if stocked := loadTestVariant(t, db, variant.ID); stocked.Quantity != 6 {
+		t.Fatalf("quantity after confirming = %d, want 6", stocked.Quantity)
+	}
+
+	if err := CancelOrder(db, order.ID, StockMovementInput{}); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+
+	got := loadTestOrder(t, db, order)
+	if got.Status != OrderCancelled || got.CancelledAt == nil {
+		t.Fatalf("status %s cancelled at %v, want cancelled", got.Status, got.CancelledAt)
+	}
+	if stocked := loadTestVariant(t, db, variant.ID); stocked.Quantity != 10 {
+		t.Fatalf("quantity after cancelling = %d, want 10", stocked.Quantity)
+	}
+
+	var returned models.StockMovement
+	err := db.Where("variant_id = ? AND type = ?", variant.ID, MovementReturn).First(&returned).Error
+	if err != nil || returned.Quantity != 4 || returned.LocationID == nil || *returned.LocationID != order.LocationID {
+		t.Fatalf("return movement = %+v, %v; want 4 units back at the order's location", returned, err)
+	}
+
+	if err := CancelOrder(db, order.ID, StockMovementInput{}); !errors.Is(err, ErrOrderNotCancellable) {
+		t.Fatalf("cancel twice error = %v, want ErrOrderNotCancellable", err)
+	}
+	if err := FulfilOrder(db, order.ID, StockMovementInput{}); !errors.Is(err, ErrOrderNotFulfillable) {
+		t.Fatalf("fulfil a cancelled order error = %v, want ErrOrderNotFulfillable", err)
+	}
+
+	// A pending order cancels without touching stock
+	pending := createTestOrder(t, db, admin, map[*models.Variant]int{variant: 2})
+	if err := CancelOrder(db, pending.ID, StockMovementInput{}); err != nil {
+		t.Fatalf("cancel a pending order: %v", err)
+	}
+	if movements := countTestMovements(t, db, "order:"+pending.ID.String()); movements != 0 {
+		t.Fatalf("%d stock movements for a cancelled pending order, want 0", movements)
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrLastOrganizationMember = errors.New("cannot remove the last member of an organization")
+	ErrInvalidWebhookURL      = errors.New("invalid webhook url")
+)
+
+// CreateOrganization creates the organization with the given admin as its
+// first member.
+func CreateOrganization(db *gorm.DB, organization *models.Organization, adminID uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(organization).Error; err != nil {
+			return err
+		}
+
+		return AddOrganizationMember(tx, organization.ID, adminID)
+	})
+}
+
+func CreatePersonalOrganization(db *gorm.DB, admin *models.Admin) (*models.Organization, error) {
+	organization := models.Organization{Name: admin.Name + "'s Organization"}
+	err := CreateOrganization(db, &organization, admin.ID)
+
+	return &organization, err
+}
+
+func GetOrganizationByID(db *gorm.DB, id uuid.UUID) (*models.Organization, error) {
+	var organization models.Organization
+	err := db.Preload("Memberships.Admin").First(&organization, id).Error
+
+	return &organization, err
+}
+
+func GetOrganizationsByAdminID(db *gorm.DB, adminID uuid.UUID) ([]models.Organization, error) {
+	var organizations []models.Organization
+
+	err := db.Joins("JOIN memberships ON memberships.organization_id = organizations.id").
+		Where("memberships.admin_id = ?", adminID).
+		Order("memberships.created_at").
+		Find(&organizations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return organizations, nil
+}
+
+// GetDefaultOrganizationID returns the organization the admin joined first,
+// which is where new products go unless another organization is requested.
+func GetDefaultOrganizationID(db *gorm.DB, adminID uuid.UUID) (uuid.UUID, error) {
+	var membership models.Membership
+	err := db.Where("admin_id = ?", adminID).Order("created_at").First(&membership).Error
+
+	return membership.OrganizationID, err
+}
+
+func IsOrganizationMember(db *gorm.DB, organizationID, adminID uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Model(&models.Membership{}).
+		Where("organization_id = ? AND admin_id = ?", organizationID, adminID).
+		Count(&count).Error
+
+	return count > 0, err
+}
+
+func AddOrganizationMember(db *gorm.DB, organizationID, adminID uuid.UUID) error {
+	membership := models.Membership{
+		OrganizationID: organizationID,
+		AdminID:        adminID,
+	}
+
+	return db.Create(&membership).Error
+}
+
+// RemoveOrganizationMember returns gorm.ErrRecordNotFound when the admin is
+// not a member.
+func RemoveOrganizationMember(db *gorm.DB, organizationID, adminID uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("organization_id = ? AND admin_id = ?", organizationID, adminID).Delete(&models.Membership{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		var count int64
+		if err := tx.Model(&models.Membership{}).Where("organization_id = ?", organizationID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrLastOrganizationMember
+		}
+
+		return nil
+	})
+}
+
+// MigrateProductsToOrganizations gives every admin without a membership a
+// personal organization and moves the products they own into it. Running it
+// again is a no-op.
+func MigrateProductsToOrganizations(db *gorm.DB) error {
+	var admins []models.Admin
+	err := db.Where("id NOT IN (?)", db.Model(&models.Membership{}).Select("admin_id")).Find(&admins).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range admins {
+		if _, err := CreatePersonalOrganization(db, &admins[i]); err != nil {
+			return err
+		}
+	}
+
+	return db.Exec(`UPDATE products SET organization_id = (
+		SELECT memberships.organization_id FROM memberships
+		WHERE memberships.admin_id = products.admin_id
+		ORDER BY memberships.created_at LIMIT 1
+	) WHERE organization_id IS NULL OR organization_id = ''`).Error
+}
+
+// UpdateOrganizationStockAlerts sets where the organization's stock alerts
+// go. Empty values turn a channel off; emailTo is a comma separated list. A
+// webhook host that resolves to a loopback, private or link-local address is
+// refused with ErrWebhookAddressNotAllowed.
+func UpdateOrganizationStockAlerts(db *gorm.DB, id uuid.UUID, webhookURL, webhookSecret, emailTo string) error {
+	if webhookURL != "" {
+		parsed, err := url.Parse(webhookURL)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return ErrInvalidWebhookURL
+		}
+
+		if err := checkWebhookHost(parsed.Hostname()); err != nil {
+			return err
+		}
+	}
+
+	return db.Model(&models.Organization{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"stock_alert_webhook_url":    webhookURL,
+		"stock_alert_webhook_secret": webhookSecret,
+		"stock_alert_email_to":       strings.TrimSpace(emailTo),
+	}).Error
+}
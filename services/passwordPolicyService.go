@@ -0,0 +1,167 @@
+package services
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed data/common-passwords.txt
+var commonPasswordList string
+
+var (
+	breachedPasswords     map[string]struct{}
+	breachedPasswordsOnce sync.Once
+)
+
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyFromEnv reads the PASSWORD_* variables. By default passwords
+// need 10 characters with lower case, upper case and digits.
+func PasswordPolicyFromEnv() PasswordPolicy {
+	policy := PasswordPolicy{
+		MinLength:     10,
+		RequireUpper:  envBool("PASSWORD_REQUIRE_UPPER", true),
+		RequireLower:  envBool("PASSWORD_REQUIRE_LOWER", true),
+		RequireDigit:  envBool("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol: envBool("PASSWORD_REQUIRE_SYMBOL", false),
+	}
+
+	if minLength, err := strconv.Atoi(os.Getenv("PASSWORD_MIN_LENGTH")); err == nil && minLength > 0 {
+		policy.MinLength = minLength
+	}
+
+	return policy
+}
+
+// Validate returns every rule the password breaks, reported against field.
+func (policy PasswordPolicy) Validate(field, password, email, name string) []FieldError {
+	var errs []FieldError
+
+	if len([]rune(password)) < policy.MinLength {
+		errs = append(errs, FieldError{field, "too_short", fmt.Sprintf("Password must be at least %d characters", policy.MinLength)})
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+
+	if policy.RequireUpper && !hasUpper {
+		errs = append(errs, FieldError{field, "missing_upper", "Password must contain an upper case letter"})
+	}
+	if policy.RequireLower && !hasLower {
+		errs = append(errs, FieldError{field, "missing_lower", "Password must contain a lower case letter"})
+	}
+	if policy.RequireDigit && !hasDigit {
+		errs = append(errs, FieldError{field, "missing_digit", "Password must contain a digit"})
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		errs = append(errs, FieldError{field, "missing_symbol", "Password must contain a symbol"})
+	}
+
+	if containsPersonalInfo(password, email, name) {
+		errs = append(errs, FieldError{field, "contains_personal_info", "Password must not contain your email or name"})
+	}
+
+	if IsBreachedPassword(password) {
+		errs = append(errs, FieldError{field, "breached", "Password is too common or has appeared in a data breach"})
+	}
+
+	return errs
+}
+
+func containsPersonalInfo(password, email, name string) bool {
+	lowered := strings.ToLower(password)
+
+	email = strings.ToLower(email)
+	if email != "" && strings.Contains(lowered, email) {
+		return true
+	}
+	if localPart, _, found := strings.Cut(email, "@"); found && len(localPart) >= 3 && strings.Contains(lowered, localPart) {
+		return true
+	}
+
+	name = strings.ToLower(name)
+	if compact := strings.Join(strings.Fields(name), ""); len(compact) >= 3 && strings.Contains(lowered, compact) {
+		return true
+	}
+	for _, part := range strings.Fields(name) {
+		if len(part) >= 4 && strings.Contains(lowered, part) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsBreachedPassword checks the bundled list of common passwords plus the
+// optional file at PASSWORD_BREACHED_LIST (one password per line).
+func IsBreachedPassword(password string) bool {
+	breachedPasswordsOnce.Do(loadBreachedPasswords)
+
+	_, found := breachedPasswords[strings.ToLower(password)]
+	return found
+}
+
+func loadBreachedPasswords() {
+	breachedPasswords = make(map[string]struct{})
+	addPasswords := func(scanner *bufio.Scanner) {
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				breachedPasswords[strings.ToLower(line)] = struct{}{}
+			}
+		}
+	}
+
+	addPasswords(bufio.NewScanner(strings.NewReader(commonPasswordList)))
+
+	path := os.Getenv("PASSWORD_BREACHED_LIST")
+	if path == "" {
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("Failed to load breached password list %s: %v", path, err)
+		return
+	}
+	defer file.Close()
+
+	addPasswords(bufio.NewScanner(file))
+}
+
+func envBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
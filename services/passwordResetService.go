@@ -0,0 +1,112 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const PasswordResetTTL = time.Hour
+
+var ErrInvalidPasswordResetToken = errors.New("invalid or expired password reset token")
+
+// CreatePasswordReset issues a reset token and invalidates any earlier
+// tokens of the admin, so only the latest email works.
+func CreatePasswordReset(db *gorm.DB, admin *models.Admin) (string, error) {
+	token, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.PasswordReset{}).
+			Where("admin_id = ? AND used_at IS NULL", admin.ID).
+			Update("used_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+
+		passwordReset := models.PasswordReset{
+			AdminID:   admin.ID,
+			TokenHash: HashToken(token),
+			ExpiresAt: time.Now().Add(PasswordResetTTL),
+		}
+
+		return tx.Create(&passwordReset).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func SendPasswordResetEmail(email, name, token string) error {
+	return DefaultMailer.Send(Email{
+		To:      email,
+		Subject: "Reset your password",
+		Body:    "Hi " + name + ",\n\nYou can reset your password using this link:\n" + appLink("/reset-password", token) + "\n\nThe link expires in 1 hour. If you did not ask for a password reset, you can ignore this email.",
+	})
+}
+
+// GetPasswordResetAdmin returns the admin a pending reset token belongs to,
+// without consuming it.
+func GetPasswordResetAdmin(db *gorm.DB, token string) (*models.Admin, error) {
+	var passwordReset models.PasswordReset
+	err := db.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", HashToken(token), time.Now()).
+		First(&passwordReset).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidPasswordResetToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return GetAdminByID(db, passwordReset.AdminID)
+}
+
+// ResetPassword consumes the token, sets the new password and signs out every
+// session of the admin.
+func ResetPassword(db *gorm.DB, token, password string) (uuid.UUID, error) {
+	encryptedPassword, err := EncryptPassword(password)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var passwordReset models.PasswordReset
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", HashToken(token), time.Now()).
+			First(&passwordReset).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidPasswordResetToken
+		}
+		if err != nil {
+			return err
+		}
+
+		res := tx.Model(&models.PasswordReset{}).
+			Where("id = ? AND used_at IS NULL", passwordReset.ID).
+			Update("used_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInvalidPasswordResetToken
+		}
+
+		if err := UpdateAdminPassword(tx, passwordReset.AdminID, encryptedPassword); err != nil {
+			return err
+		}
+
+		return RevokeAdminSessions(tx, passwordReset.AdminID)
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return passwordReset.AdminID, nil
+}
@@ -0,0 +1,69 @@
+package services
+
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+const (
+	PermissionProductsRead    = "products:read"
+	PermissionProductsWrite   = "products:write"
+	PermissionVariantsRead    = "variants:read"
+	PermissionVariantsWrite   = "variants:write"
+	PermissionInventoryAdjust = "inventory:adjust"
+	PermissionLocationsManage = "locations:manage"
+	PermissionPurchasingWrite = "purchasing:write"
+	PermissionOrdersRead      = "orders:read"
+	PermissionOrdersWrite     = "orders:write"
+	PermissionAdminsManage    = "admins:manage"
+)
+
+var rolePermissions = map[string][]string{
+	RoleOwner: {
+		PermissionProductsRead,
+		PermissionProductsWrite,
+		PermissionVariantsRead,
+		PermissionVariantsWrite,
+		PermissionInventoryAdjust,
+		PermissionLocationsManage,
+		PermissionPurchasingWrite,
+		PermissionOrdersRead,
+		PermissionOrdersWrite,
+		PermissionAdminsManage,
+	},
+	RoleEditor: {
+		PermissionProductsRead,
+		PermissionProductsWrite,
+		PermissionVariantsRead,
+		PermissionVariantsWrite,
+		PermissionInventoryAdjust,
+		PermissionLocationsManage,
+		PermissionPurchasingWrite,
+		PermissionOrdersRead,
+		PermissionOrdersWrite,
+	},
+	RoleViewer: {
+		PermissionProductsRead,
+		PermissionVariantsRead,
+		PermissionOrdersRead,
+	},
+}
+
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func RolePermissions(role string) []string {
+	return rolePermissions[role]
+}
+
+func HasPermission(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
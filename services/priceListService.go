@@ -0,0 +1,453 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"golang-final-project/models"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	PriceRuleBase      = "base"
+	PriceRuleSale      = "sale"
+	PriceRulePriceList = "price_list"
+)
+
+var (
+	ErrInvalidPriceListWindow = errors.New("a price list must end after it starts")
+	ErrInvalidPriceTiers      = errors.New("price tiers need a minimum quantity of at least one and must not repeat")
+	ErrInvalidCustomerGroup   = errors.New("customer group does not belong to the organization")
+	ErrInvalidPriceQuantity   = errors.New("quantity must be at least one")
+)
+
+type PriceTierInput struct {
+	VariantID   uuid.UUID
+	MinQuantity int
+	Amount      int64
+}
+
+// PriceRule is one way a variant could be priced. Applied marks the rule
+// that won; Reason says why a rule won or lost.
+type PriceRule struct {
+	Type          string     `json:"type"`
+	PriceListID   *uuid.UUID `json:"priceListID,omitempty"`
+	PriceListName string     `json:"priceListName,omitempty"`
+	Priority      int        `json:"priority"`
+	MinQuantity   int        `json:"minQuantity"`
+	Amount        int64      `json:"amount"`
+	Applied       bool       `json:"applied"`
+	Reason        string     `json:"reason"`
+}
+
+type PriceResolution struct {
+	VariantID       uuid.UUID   `json:"variantID"`
+	Quantity        int         `json:"quantity"`
+	CustomerGroupID *uuid.UUID  `json:"customerGroupID,omitempty"`
+	Currency        string      `json:"currency"`
+	UnitPrice       int64       `json:"unitPrice"`
+	Total           int64       `json:"total"`
+	Rule            PriceRule   `json:"rule"`
+	Candidates      []PriceRule `json:"candidates"`
+}
+
+func validatePriceList(db *gorm.DB, priceList *models.PriceList, customerGroupIDs []uuid.UUID) error {
+	if !isCurrencyCode(priceList.Currency) {
+		return ErrInvalidCurrency
+	}
+
+	if priceList.StartsAt != nil && priceList.EndsAt != nil && !priceList.EndsAt.After(*priceList.StartsAt) {
+		return ErrInvalidPriceListWindow
+	}
+
+	if len(customerGroupIDs) == 0 {
+		return nil
+	}
+
+	var groups int64
+	err := db.Model(&models.CustomerGroup{}).
+		Where("id IN ? AND organization_id = ?", customerGroupIDs, priceList.OrganizationID).
+		Count(&groups).Error
+	if err != nil {
+		return err
+	}
+	if groups != int64(len(customerGroupIDs)) {
+		return ErrInvalidCustomerGroup
+	}
+
+	return nil
+}
+
+func assignPriceList(tx *gorm.DB, priceListID uuid.UUID, customerGroupIDs []uuid.UUID) error {
+	if err := tx.Where("price_list_id = ?", priceListID).Delete(&models.PriceListAssignment{}).Error; err != nil {
+		return err
+	}
+
+	for _, customerGroupID := range customerGroupIDs {
+		assignment := models.PriceListAssignment{PriceListID: priceListID, CustomerGroupID: customerGroupID}
+		if err := tx.Create(&assignment).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CreatePriceList creates the price list and assigns it to the customer
+// groups, which must be distinct and belong to its organization.
+func CreatePriceList(db *gorm.DB, priceList *models.PriceList, customerGroupIDs []uuid.UUID) error {
+	if err := validatePriceList(db, priceList, customerGroupIDs); err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(priceList).Error; err != nil {
+			return err
+		}
+
+		return assignPriceList(tx, priceList.ID, customerGroupIDs)
+	})
+}
+
+func GetAllPriceLists(db *gorm.DB, tenant Tenant) ([]models.PriceList, error) {
+	var priceLists []models.PriceList
+
+	err := db.Scopes(PriceListTenantScope(tenant)).
+		Preload("Assignments.CustomerGroup").
+		Order("priority DESC, name").
+		Find(&priceLists).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return priceLists, nil
+}
+
+func GetPriceListByID(db *gorm.DB, tenant Tenant, id uuid.UUID) (*models.PriceList, error) {
+	var priceList models.PriceList
+	err := db.Scopes(PriceListTenantScope(tenant)).
+		Preload("Assignments.CustomerGroup").
+		Preload("Prices", func(db *gorm.DB) *gorm.DB { return db.Order("variant_id, min_quantity") }).
+		First(&priceList, id).Error
+	return &priceList, err
+}
+
+// UpdatePriceList replaces the list's settings and customer groups. The
+// currency can only change while the list has no prices.
+func UpdatePriceList(db *gorm.DB, tenant Tenant, id uuid.UUID, update *models.PriceList, customerGroupIDs []uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var priceList models.PriceList
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(PriceListTenantScope(tenant)).First(&priceList, id).Error; err != nil {
+			return err
+		}
+
+		update.OrganizationID = priceList.OrganizationID
+		if err := validatePriceList(tx, update, customerGroupIDs); err != nil {
+			return err
+		}
+
+		if update.Currency != priceList.Currency {
+			var prices int64
+			if err := tx.Model(&models.PriceListPrice{}).Where("price_list_id = ?", id).Count(&prices).Error; err != nil {
+				return err
+			}
+			if prices > 0 {
+				return ErrCurrencyMismatch
+			}
+		}
+
+		err := tx.Model(&models.PriceList{}).Where("id = ?", id).
+			Select("name", "currency", "priority", "starts_at", "ends_at").
+			Updates(update).Error
+		if err != nil {
+			return err
+		}
+
+		return assignPriceList(tx, id, customerGroupIDs)
+	})
+}
+
+func DeletePriceListByID(db *gorm.DB, tenant Tenant, id uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if _, err := GetPriceListByID(tx, tenant, id); err != nil {
+			return err
+		}
+
+		if err := tx.Where("price_list_id = ?", id).Delete(&models.PriceListPrice{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("price_list_id = ?", id).Delete(&models.PriceListAssignment{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&models.PriceList{}, id).Error
+	})
+}
+
+// SetPriceListPrices replaces the list's quantity-break tiers. Each variant
+// must belong to the list's organization and be priced in the list's
+// currency.
+func SetPriceListPrices(db *gorm.DB, id uuid.UUID, tiers []PriceTierInput) error {
+	type tierKey struct {
+		variantID   uuid.UUID
+		minQuantity int
+	}
+
+	seen := make(map[tierKey]bool, len(tiers))
+	variants := make(map[uuid.UUID]bool)
+	variantIDs := make([]uuid.UUID, 0, len(tiers))
+	for _, tier := range tiers {
+		if tier.Amount < 0 {
+			return ErrNegativePrice
+		}
+
+		key := tierKey{tier.VariantID, tier.MinQuantity}
+		if tier.MinQuantity < 1 || seen[key] {
+			return ErrInvalidPriceTiers
+		}
+		seen[key] = true
+
+		if !variants[tier.VariantID] {
+			variants[tier.VariantID] = true
+			variantIDs = append(variantIDs, tier.VariantID)
+		}
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var priceList models.PriceList
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&priceList, id).Error; err != nil {
+			return err
+		}
+
+		if len(variantIDs) > 0 {
+			inOrganization, err := variantsInOrganization(tx, priceList.OrganizationID, variantIDs)
+			if err != nil {
+				return err
+			}
+			if !inOrganization {
+				return ErrVariantNotInOrganization
+			}
+
+			var otherCurrency int64
+			err = tx.Model(&models.Variant{}).
+				Joins("JOIN products ON products.id = variants.product_id").
+				Where("variants.id IN ? AND products.currency <> ?", variantIDs, priceList.Currency).
+				Count(&otherCurrency).Error
+			if err != nil {
+				return err
+			}
+			if otherCurrency > 0 {
+				return ErrCurrencyMismatch
+			}
+		}
+
+		if err := tx.Where("price_list_id = ?", id).Delete(&models.PriceListPrice{}).Error; err != nil {
+			return err
+		}
+
+		for _, tier := range tiers {
+			price := models.PriceListPrice{
+				PriceListID: id,
+				VariantID:   tier.VariantID,
+				MinQuantity: tier.MinQuantity,
+				Amount:      tier.Amount,
+			}
+			if err := tx.Create(&price).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ResolveCustomerPrice works out the unit price of a variant for a quantity
+// and an optional customer group, and explains the outcome. Among the
+// group's active price lists in the product's currency, the highest priority
+// list wins, then the lowest price; within a list the highest tier the
+// quantity reaches applies. Without a matching list the catalog price from
+// ResolvePrice applies.
+func ResolveCustomerPrice(db *gorm.DB, variantID uuid.UUID, quantity int, customerGroupID *uuid.UUID, at time.Time) (*PriceResolution, error) {
+	if quantity < 1 {
+		return nil, ErrInvalidPriceQuantity
+	}
+
+	var variant models.Variant
+	if err := db.First(&variant, variantID).Error; err != nil {
+		return nil, err
+	}
+
+	var product models.Product
+	if err := db.First(&product, variant.ProductID).Error; err != nil {
+		return nil, err
+	}
+
+	resolution := &PriceResolution{
+		VariantID:       variantID,
+		Quantity:        quantity,
+		CustomerGroupID: customerGroupID,
+		Currency:        product.Currency,
+		Candidates:      []PriceRule{},
+	}
+
+	catalog := -1
+	if price := ResolvePrice(&product, &variant, at); price != nil {
+		rule := PriceRule{Type: PriceRuleBase, Amount: price.Amount, Reason: "Catalog price"}
+		if price.OnSale {
+			rule.Type = PriceRuleSale
+			rule.Reason = "Catalog sale price"
+		}
+		resolution.Candidates = append(resolution.Candidates, rule)
+		catalog = 0
+	}
+
+	winner := catalog
+	if customerGroupID != nil {
+		var group models.CustomerGroup
+		if err := db.First(&group, *customerGroupID).Error; err != nil || group.OrganizationID != product.OrganizationID {
+			return nil, ErrInvalidCustomerGroup
+		}
+
+		tiers, err := customerGroupTiers(db, *customerGroupID, variantID)
+		if err != nil {
+			return nil, err
+		}
+
+		if listWinner := explainPriceListTiers(resolution, tiers, product.Currency, at); listWinner >= 0 {
+			winner = listWinner
+			if catalog >= 0 {
+				resolution.Candidates[catalog].Reason = "Overridden by the customer group's price list"
+			}
+		}
+	}
+
+	if winner < 0 {
+		return nil, ErrVariantNotPriced
+	}
+
+	resolution.Candidates[winner].Applied = true
+	resolution.Rule = resolution.Candidates[winner]
+	resolution.UnitPrice = resolution.Rule.Amount
+	resolution.Total = resolution.UnitPrice * int64(quantity)
+
+	return resolution, nil
+}
+
+type priceListTier struct {
+	PriceListID uuid.UUID
+	Name        string
+	Currency    string
+	Priority    int
+	StartsAt    *time.Time
+	EndsAt      *time.Time
+	MinQuantity int
+	Amount      int64
+}
+
+func customerGroupTiers(db *gorm.DB, customerGroupID, variantID uuid.UUID) ([]priceListTier, error) {
+	var tiers []priceListTier
+	err := db.Model(&models.PriceListPrice{}).
+		Select("price_list_prices.price_list_id, price_lists.name, price_lists.currency, price_lists.priority, price_lists.starts_at, price_lists.ends_at, price_list_prices.min_quantity, price_list_prices.amount").
+		Joins("JOIN price_lists ON price_lists.id = price_list_prices.price_list_id").
+		Joins("JOIN price_list_assignments ON price_list_assignments.price_list_id = price_lists.id").
+		Where("price_list_assignments.customer_group_id = ? AND price_list_prices.variant_id = ?", customerGroupID, variantID).
+		Scan(&tiers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(tiers, func(i, j int) bool {
+		if tiers[i].Priority != tiers[j].Priority {
+			return tiers[i].Priority > tiers[j].Priority
+		}
+		if tiers[i].PriceListID != tiers[j].PriceListID {
+			return tiers[i].PriceListID.String() < tiers[j].PriceListID.String()
+		}
+		return tiers[i].MinQuantity > tiers[j].MinQuantity
+	})
+
+	return tiers, nil
+}
+
+// explainPriceListTiers adds a candidate for every tier and returns the index
+// of the winning one, or -1 when no tier applies.
+func explainPriceListTiers(resolution *PriceResolution, tiers []priceListTier, currency string, at time.Time) int {
+	winner := -1
+	bestOfList := make(map[uuid.UUID]int)
+	var eligible []int
+
+	for _, tier := range tiers {
+		priceListID := tier.PriceListID
+		rule := PriceRule{
+			Type:          PriceRulePriceList,
+			PriceListID:   &priceListID,
+			PriceListName: tier.Name,
+			Priority:      tier.Priority,
+			MinQuantity:   tier.MinQuantity,
+			Amount:        tier.Amount,
+		}
+
+		active := (tier.StartsAt == nil || !at.Before(*tier.StartsAt)) && (tier.EndsAt == nil || at.Before(*tier.EndsAt))
+
+		switch {
+		case tier.Currency != currency:
+			rule.Reason = fmt.Sprintf("Price list is in %s, the product in %s", tier.Currency, currency)
+		case !active:
+			rule.Reason = "Price list is not active at this time"
+		case tier.MinQuantity > resolution.Quantity:
+			rule.Reason = fmt.Sprintf("Needs at least %d units", tier.MinQuantity)
+		default:
+			index := len(resolution.Candidates)
+			eligible = append(eligible, index)
+			if best, ok := bestOfList[priceListID]; !ok || tier.MinQuantity > resolution.Candidates[best].MinQuantity {
+				bestOfList[priceListID] = index
+			}
+		}
+
+		resolution.Candidates = append(resolution.Candidates, rule)
+	}
+
+	for _, index := range eligible {
+		if bestOfList[*resolution.Candidates[index].PriceListID] != index {
+			resolution.Candidates[index].Reason = "A higher tier of the same price list applies"
+		}
+	}
+
+	for _, index := range bestOfList {
+		candidate := resolution.Candidates[index]
+		if winner < 0 {
+			winner = index
+			continue
+		}
+
+		best := resolution.Candidates[winner]
+		if candidate.Priority > best.Priority || (candidate.Priority == best.Priority && candidate.Amount < best.Amount) ||
+			(candidate.Priority == best.Priority && candidate.Amount == best.Amount && index < winner) {
+			winner = index
+		}
+	}
+
+	for _, index := range bestOfList {
+		candidate := &resolution.Candidates[index]
+		best := resolution.Candidates[winner]
+		switch {
+		case index == winner && candidate.MinQuantity > 1:
+			candidate.Reason = fmt.Sprintf("Highest-priority price list of the customer group, tier from %d units", candidate.MinQuantity)
+		case index == winner:
+			candidate.Reason = "Highest-priority price list of the customer group"
+		case candidate.Priority < best.Priority:
+			candidate.Reason = fmt.Sprintf("Lower priority than %s", best.PriceListName)
+		case candidate.Amount == best.Amount:
+			candidate.Reason = fmt.Sprintf("%s comes first at the same priority and price", best.PriceListName)
+		default:
+			candidate.Reason = fmt.Sprintf("%s is cheaper at the same priority", best.PriceListName)
+		}
+	}
+
+	return winner
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestExplainPriceListTiers(t *testing.T) {
+	at := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	before, after := at.Add(-time.Hour), at.Add(time.Hour)
+	listA, listB := uuid.New(), uuid.New()
+
+	tier := func(priceListID uuid.UUID, name string, priority, minQuantity int, amount int64) priceListTier {
+		return priceListTier{PriceListID: priceListID, Name: name, Currency: "EUR", Priority: priority, MinQuantity: minQuantity, Amount: amount}
+	}
+	expired := tier(listA, "Spring", 5, 1, 500)
+	expired.EndsAt = &at
+	upcoming := tier(listA, "Summer", 5, 1, 500)
+	upcoming.StartsAt = &after
+	running := tier(listB, "Trade", 0, 1, 900)
+	running.StartsAt, running.EndsAt = &before, &after
+	dollars := tier(listA, "Export", 5, 1, 500)
+	dollars.Currency = "USD"
+
+	tests := []struct {
+		name     string
+		tiers    []priceListTier
+		quantity int
+		winner   int
+		reasons  []string
+	}{
+		{
+			name:     "highest tier the quantity reaches",
+			tiers:    []priceListTier{tier(listA, "Trade", 0, 50, 700), tier(listA, "Trade", 0, 10, 800), tier(listA, "Trade", 0, 1, 900)},
+			quantity: 12,
+			winner:   1,
+			reasons: []string{
+				"Needs at least 50 units",
+				"Highest-priority price list of the customer group, tier from 10 units",
+				"A higher tier of the same price list applies",
+			},
+		},
+		{
+			name:     "quantity exactly at a tier",
+			tiers:    []priceListTier{tier(listA, "Trade", 0, 10, 800), tier(listA, "Trade", 0, 1, 900)},
+			quantity: 10,
+			winner:   0,
+			reasons: []string{
+				"Highest-priority price list of the customer group, tier from 10 units",
+				"A higher tier of the same price list applies",
+			},
+		},
+		{
+			name:     "below every tier",
+			tiers:    []priceListTier{tier(listA, "Trade", 0, 10, 800)},
+			quantity: 9,
+			winner:   -1,
+			reasons:  []string{"Needs at least 10 units"},
+		},
+		{
+			name:     "inactive windows",
+			tiers:    []priceListTier{expired, upcoming, running},
+			quantity: 1,
+			winner:   2,
+			reasons: []string{
+				"Price list is not active at this time",
+				"Price list is not active at this time",
+				"Highest-priority price list of the customer group",
+			},
+		},
+		{
+			name:     "currency mismatch",
+			tiers:    []priceListTier{dollars, tier(listB, "Trade", 0, 1, 900)},
+			quantity: 1,
+			winner:   1,
+			reasons: []string{
+				"Price list is in USD, the product in EUR",
+				"Highest-priority price list of the customer group",
+			},
+		},
+		{
+			name:     "higher priority over a lower price",
+			tiers:    []priceListTier{tier(listA, "Contract", 1, 1, 900), tier(listB, "Promo", 0, 1, 500)},
+			quantity: 1,
+			winner:   0,
+			reasons: []string{
+				"Highest-priority price list of the customer group",
+				"Lower priority than Contract",
+			},
+		},
+		{
+			name:     "lower price at equal priority",
+			tiers:    []priceListTier{tier(listA, "Contract", 1, 1, 900), tier(listB, "Promo", 1, 1, 800)},
+			quantity: 1,
+			winner:   1,
+			reasons: []string{
+				"Promo is cheaper at the same priority",
+				"Highest-priority price list of the customer group",
+			},
+		},
+		{
+			name:     "first list at equal priority and price",
+			tiers:    []priceListTier{tier(listA, "Contract", 1, 1, 800), tier(listB, "Promo", 1, 1, 800)},
+			quantity: 1,
+			winner:   0,
+			reasons: []string{
+				"Highest-priority price list of the customer group",
+				"Contract comes first at the same priority and price",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// The lists are kept in a map; repeat so its iteration order can not
+		// decide the outcome
+		for i := 0; i < 20; i++ {
+			resolution := &PriceResolution{Quantity: test.quantity}
+			winner := explainPriceListTiers(resolution, test.tiers, "EUR", at)
+			if winner != test.winner {
+				t.Fatalf("%s: winner = %d, want %d", test.name, winner, test.winner)
+			}
+			if len(resolution.Candidates) != len(test.reasons) {
+				t.Fatalf("%s: %d candidates, want %d", test.name, len(resolution.Candidates), len(test.reasons))
+			}
+			for j, candidate := range resolution.Candidates {
+				if candidate.Type != PriceRulePriceList || candidate.Reason != test.reasons[j] {
+					t.Fatalf("%s: candidate %d = %s %q, want %q", test.name, j, candidate.Type, candidate.Reason, test.reasons[j])
+				}
+			}
+		}
+	}
+}
+
+func TestResolveCustomerPrice(t *testing.T) {
+	db := openTestDB(t)
+	admin, variant := createTestVariant(t, db, 0)
+	tenant := Tenant{AdminID: admin.ID}
+	at := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	organizationID, err := GetDefaultOrganizationID(db, admin.ID)
+	if err != nil {
+		t.Fatalf("GetDefaultOrganizationID: %v", err)
+	}
+
+	if err := UpdateProductPrice(db, tenant, variant.ProductID, PriceInput{Currency: "EUR", Price: testAmount(1000)}); err != nil {
+		t.Fatalf("UpdateProductPrice: %v", err)
+	}
+
+	// A product with a currency but no catalog price
+	unpriced := models.Product{Name: "Hat", AdminID: admin.ID, OrganizationID: organizationID, Currency: "EUR"}
+	if err := CreateProduct(db, &unpriced); err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+	hat := models.Variant{VariantName: "One size", ProductID: unpriced.ID}
+	if err := CreateVariant(db, &hat); err != nil {
+		t.Fatalf("CreateVariant: %v", err)
+	}
+
+	createGroup := func(code string) uuid.UUID {
+		group := models.CustomerGroup{OrganizationID: organizationID, Name: code, Code: code}
+		if err := CreateCustomerGroup(db, &group); err != nil {
+			t.Fatalf("CreateCustomerGroup: %v", err)
+		}
+		return group.ID
+	}
+	wholesale, retail, outsiders := createGroup("wholesale"), createGroup("retail"), createGroup("outsiders")
+
+	createPriceList := func(priceList models.PriceList, groupID uuid.UUID, tiers ...PriceTierInput) uuid.UUID {
+		priceList.OrganizationID = organizationID
+		if priceList.Currency == "" {
+			priceList.Currency = "EUR"
+		}
+		if err := CreatePriceList(db, &priceList, []uuid.UUID{groupID}); err != nil {
+			t.Fatalf("CreatePriceList: %v", err)
+		}
+		if err := SetPriceListPrices(db, priceList.ID, tiers); err != nil {
+			t.Fatalf("SetPriceListPrices: %v", err)
+		}
+		return priceList.ID
+	}
+
+	ended := at.Add(-time.Hour)
+	createPriceList(models.PriceList{Name: "Wholesale"}, wholesale,
+		PriceTierInput{VariantID: variant.ID, MinQuantity: 1, Amount: 900},
+		PriceTierInput{VariantID: variant.ID, MinQuantity: 10, Amount: 800},
+		PriceTierInput{VariantID: hat.ID, MinQuantity: 1, Amount: 400},
+	)
+	createPriceList(models.PriceList{Name: "Clearance", Priority: 5, StartsAt: &ended, EndsAt: &at}, wholesale,
+		PriceTierInput{VariantID: variant.ID, MinQuantity: 1, Amount: 100},
+	)
+
+	// A list left in another currency after the product's currency changed;
+	// SetPriceListPrices refuses such tiers
+	export := createPriceList(models.PriceList{Name: "Export", Currency: "USD", Priority: 5}, retail)
+	if err := db.Create(&models.PriceListPrice{PriceListID: export, VariantID: variant.ID, MinQuantity: 1, Amount: 200}).Error; err != nil {
+		t.Fatalf("create price list price: %v", err)
+	}
+	createPriceList(models.PriceList{Name: "Bulk only"}, outsiders,
+		PriceTierInput{VariantID: variant.ID, MinQuantity: 100, Amount: 600},
+	)
+
+	tests := []struct {
+		name      string
+		variantID uuid.UUID
+		quantity  int
+		group     *uuid.UUID
+		rule      string
+		unitPrice int64
+		reason    string
+	}{
+		{"no customer group", variant.ID, 1, nil, PriceRuleBase, 1000, "Catalog price"},
+		{"first tier", variant.ID, 9, &wholesale, PriceRulePriceList, 900, "Highest-priority price list of the customer group"},
+		{"tier threshold", variant.ID, 10, &wholesale, PriceRulePriceList, 800, "Highest-priority price list of the customer group, tier from 10 units"},
+		{"currency mismatch", variant.ID, 1, &retail, PriceRuleBase, 1000, "Catalog price"},
+		{"below every tier", variant.ID, 99, &outsiders, PriceRuleBase, 1000, "Catalog price"},
+		{"missing catalog price", hat.ID, 3, &wholesale, PriceRulePriceList, 400, "Highest-priority price list of the customer group"},
+	}
+
+	for _, test := range tests {
+		resolution, err := ResolveCustomerPrice(db, test.variantID, test.quantity, test.group, at)
+		if err != nil {
+			t.Errorf("%s: ResolveCustomerPrice: %v", test.name, err)
+			continue
+		}
+		if resolution.Rule.Type != test.rule || resolution.UnitPrice != test.unitPrice || resolution.Rule.Reason != test.reason || !resolution.Rule.Applied {
+			t.Errorf("%s: rule %s %d %q, want %s %d %q", test.name, resolution.Rule.Type, resolution.UnitPrice, resolution.Rule.Reason, test.rule, test.unitPrice, test.reason)
+		}
+		if resolution.Total != test.unitPrice*int64(test.quantity) || resolution.Currency != "EUR" {
+			t.Errorf("%s: total %s %d, want EUR %d", test.name, resolution.Currency, resolution.Total, test.unitPrice*int64(test.quantity))
+		}
+
+		applied := 0
+		for _, candidate := range resolution.Candidates {
+			if candidate.Applied {
+				applied++
+			}
+		}
+		if applied != 1 {
+			t.Errorf("%s: %d candidates applied, want 1", test.name, applied)
+		}
+	}
+
+	// The catalog candidate says why the price list beat it
+	resolution, err := ResolveCustomerPrice(db, variant.ID, 10, &wholesale, at)
+	if err != nil {
+		t.Fatalf("ResolveCustomerPrice: %v", err)
+	}
+	if catalog := resolution.Candidates[0]; catalog.Type != PriceRuleBase || catalog.Reason != "Overridden by the customer group's price list" {
+		t.Fatalf("catalog candidate = %s %q", catalog.Type, catalog.Reason)
+	}
+	if len(resolution.Candidates) != 4 {
+		t.Fatalf("%d candidates, want the catalog price and three tiers", len(resolution.Candidates))
+	}
+
+	if _, err := ResolveCustomerPrice(db, hat.ID, 1, nil, at); !errors.Is(err, ErrVariantNotPriced) {
+		t.Fatalf("no catalog price and no group error = %v, want ErrVariantNotPriced", err)
+	}
+	if _, err := ResolveCustomerPrice(db, hat.ID, 1, &retail, at); !errors.Is(err, ErrVariantNotPriced) {
+		t.Fatalf("no catalog price and no applicable list error = %v, want ErrVariantNotPriced", err)
+	}
+	if _, err := ResolveCustomerPrice(db, variant.ID, 0, nil, at); !errors.Is(err, ErrInvalidPriceQuantity) {
+		t.Fatalf("quantity 0 error = %v, want ErrInvalidPriceQuantity", err)
+	}
+
+	other := createTestAdmin(t, db, "other@example.com", RoleOwner)
+	otherOrganizationID, _ := GetDefaultOrganizationID(db, other.ID)
+	foreign := models.CustomerGroup{OrganizationID: otherOrganizationID, Name: "Foreign", Code: "foreign"}
+	if err := CreateCustomerGroup(db, &foreign); err != nil {
+		t.Fatalf("CreateCustomerGroup: %v", err)
+	}
+	if _, err := ResolveCustomerPrice(db, variant.ID, 1, &foreign.ID, at); !errors.Is(err, ErrInvalidCustomerGroup) {
+		t.Fatalf("another organization's group error = %v, want ErrInvalidCustomerGroup", err)
+	}
+}
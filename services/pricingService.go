@@ -0,0 +1,224 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNegativePrice     = errors.New("prices must not be negative")
+	ErrInvalidCurrency   = errors.New("invalid currency")
+	ErrCurrencyRequired  = errors.New("a currency is required with a price")
+	ErrCurrencyMismatch  = errors.New("prices must be in the product's currency")
+	ErrInvalidSaleWindow = errors.New("a sale must end after it starts")
+	ErrSaleWithoutPrice  = errors.New("a sale price needs a regular price")
+	ErrVariantNotPriced  = errors.New("variant has no price")
+)
+
+// PriceInput holds amounts in minor units of Currency. A nil amount clears
+// it; on a variant that means falling back to the product's amount.
+type PriceInput struct {
+	Currency       string
+	Price          *int64
+	CompareAtPrice *int64
+	SalePrice      *int64
+	SaleStartsAt   *time.Time
+	SaleEndsAt     *time.Time
+}
+
+func (input PriceInput) hasAmounts() bool {
+	return input.Price != nil || input.CompareAtPrice != nil || input.SalePrice != nil
+}
+
+func (input PriceInput) validate() error {
+	for _, amount := range []*int64{input.Price, input.CompareAtPrice, input.SalePrice} {
+		if amount != nil && *amount < 0 {
+			return ErrNegativePrice
+		}
+	}
+
+	if input.Currency != "" && !isCurrencyCode(input.Currency) {
+		return ErrInvalidCurrency
+	}
+
+	if input.SaleStartsAt != nil && input.SaleEndsAt != nil && !input.SaleEndsAt.After(*input.SaleStartsAt) {
+		return ErrInvalidSaleWindow
+	}
+
+	return nil
+}
+
+func (input PriceInput) updates() map[string]interface{} {
+	return map[string]interface{}{
+		"price":            input.Price,
+		"compare_at_price": input.CompareAtPrice,
+		"sale_price":       input.SalePrice,
+		"sale_starts_at":   input.SaleStartsAt,
+		"sale_ends_at":     input.SaleEndsAt,
+	}
+}
+
+// isCurrencyCode only checks the shape of an ISO-4217 code; the request
+// binding checks it against the list of codes.
+func isCurrencyCode(currency string) bool {
+	if len(currency) != 3 {
+		return false
+	}
+	for _, r := range currency {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// UpdateProductPrice sets the product's base price. The currency can only
+// change while no variant overrides the price, so overrides never end up in
+// another currency than the product.
+func UpdateProductPrice(db *gorm.DB, tenant Tenant, id uuid.UUID, input PriceInput) error {
+	if err := input.validate(); err != nil {
+		return err
+	}
+	if input.hasAmounts() && input.Currency == "" {
+		return ErrCurrencyRequired
+	}
+	if input.SalePrice != nil && input.Price == nil {
+		return ErrSaleWithoutPrice
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(ProductTenantScope(tenant)).First(&product, id).Error; err != nil {
+			return err
+		}
+
+		if product.Currency != "" && product.Currency != input.Currency {
+			var overrides int64
+			err := tx.Model(&models.Variant{}).
+				Where("product_id = ? AND (price IS NOT NULL OR compare_at_price IS NOT NULL OR sale_price IS NOT NULL)", id).
+				Count(&overrides).Error
+			if err != nil {
+				return err
+			}
+			if overrides > 0 {
+				return ErrCurrencyMismatch
+			}
+		}
+
+		updates := input.updates()
+		updates["currency"] = input.Currency
+
+		return tx.Model(&models.Product{}).Where("id = ?", id).Updates(updates).Error
+	})
+}
+
+// UpdateVariantPrice overrides the product's price for one variant. The
+// amounts are in the product's currency; a currency in the input must match
+// it.
+func UpdateVariantPrice(db *gorm.DB, tenant Tenant, id uuid.UUID, input PriceInput) error {
+	if err := input.validate(); err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var variant models.Variant
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(VariantTenantScope(tenant)).First(&variant, id).Error; err != nil {
+			return err
+		}
+
+		var product models.Product
+		if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).First(&product, variant.ProductID).Error; err != nil {
+			return err
+		}
+
+		if input.hasAmounts() && product.Currency == "" {
+			return ErrCurrencyRequired
+		}
+		if input.Currency != "" && input.Currency != product.Currency {
+			return ErrCurrencyMismatch
+		}
+		if input.SalePrice != nil && input.Price == nil && product.Price == nil {
+			return ErrSaleWithoutPrice
+		}
+
+		return tx.Model(&models.Variant{}).Where("id = ?", id).Updates(input.updates()).Error
+	})
+}
+
+// ResolvePrice works out what the variant sells for at the given time: its
+// own price or else the product's, replaced by the sale price while the sale
+// runs. A variant's sale price comes with its own sale window. It returns nil
+// when there is no price.
+func ResolvePrice(product *models.Product, variant *models.Variant, at time.Time) *models.ResolvedPrice {
+	base := variant.Price
+	if base == nil {
+		base = product.Price
+	}
+	if base == nil || product.Currency == "" {
+		return nil
+	}
+
+	salePrice, saleStartsAt, saleEndsAt := variant.SalePrice, variant.SaleStartsAt, variant.SaleEndsAt
+	if salePrice == nil {
+		salePrice, saleStartsAt, saleEndsAt = product.SalePrice, product.SaleStartsAt, product.SaleEndsAt
+	}
+
+	compareAtPrice := variant.CompareAtPrice
+	if compareAtPrice == nil {
+		compareAtPrice = product.CompareAtPrice
+	}
+
+	price := &models.ResolvedPrice{
+		Currency:       product.Currency,
+		Amount:         *base,
+		CompareAtPrice: compareAtPrice,
+	}
+
+	saleRunning := (saleStartsAt == nil || !at.Before(*saleStartsAt)) && (saleEndsAt == nil || at.Before(*saleEndsAt))
+	if salePrice != nil && saleRunning {
+		price.Amount = *salePrice
+		price.OnSale = true
+		if price.CompareAtPrice == nil {
+			price.CompareAtPrice = base
+		}
+	}
+
+	return price
+}
+
+// resolveVariantPrices resolves the price of each variant, loading their
+// products in one query.
+func resolveVariantPrices(db *gorm.DB, variants []models.Variant, at time.Time) (map[uuid.UUID]*models.ResolvedPrice, error) {
+	prices := make(map[uuid.UUID]*models.ResolvedPrice, len(variants))
+	if len(variants) == 0 {
+		return prices, nil
+	}
+
+	productIDs := make([]uuid.UUID, 0, len(variants))
+	for _, variant := range variants {
+		productIDs = append(productIDs, variant.ProductID)
+	}
+
+	var products []models.Product
+	if err := db.Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+		return nil, err
+	}
+
+	productsByID := make(map[uuid.UUID]*models.Product, len(products))
+	for i := range products {
+		productsByID[products[i].ID] = &products[i]
+	}
+
+	for i := range variants {
+		if product, ok := productsByID[variants[i].ProductID]; ok {
+			prices[variants[i].ID] = ResolvePrice(product, &variants[i], at)
+		}
+	}
+
+	return prices, nil
+}
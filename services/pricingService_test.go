@@ -0,0 +1,174 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func testAmount(amount int64) *int64 {
+	return &amount
+}
+
+func loadTestProduct(t *testing.T, db *gorm.DB, id uuid.UUID) models.Product {
+	t.Helper()
+
+	var product models.Product
+	if err := db.First(&product, id).Error; err != nil {
+		t.Fatalf("load product: %v", err)
+	}
+	return product
+}
+
+func TestUpdatePriceRefusesInvalidInput(t *testing.T) {
+	db := openTestDB(t)
+	admin, variant := createTestVariant(t, db, 0)
+	tenant := Tenant{AdminID: admin.ID}
+
+	if err := UpdateProductPrice(db, tenant, variant.ProductID, PriceInput{Currency: "EUR", Price: testAmount(1000)}); err != nil {
+		t.Fatalf("UpdateProductPrice: %v", err)
+	}
+
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	productTests := []struct {
+		name  string
+		input PriceInput
+		want  error
+	}{
+		{"negative price", PriceInput{Currency: "EUR", Price: testAmount(-1)}, ErrNegativePrice},
+		{"negative compare at price", PriceInput{Currency: "EUR", Price: testAmount(100), CompareAtPrice: testAmount(-1)}, ErrNegativePrice},
+		{"negative sale price", PriceInput{Currency: "EUR", Price: testAmount(100), SalePrice: testAmount(-1)}, ErrNegativePrice},
+		{"lower case currency", PriceInput{Currency: "eur", Price: testAmount(100)}, ErrInvalidCurrency},
+		{"price without currency", PriceInput{Price: testAmount(100)}, ErrCurrencyRequired},
+		{"sale without price", PriceInput{Currency: "EUR", SalePrice: testAmount(50)}, ErrSaleWithoutPrice},
+		{"sale ends before it starts", PriceInput{Currency: "EUR", Price: testAmount(100), SaleStartsAt: &start, SaleEndsAt: &start}, ErrInvalidSaleWindow},
+	}
+
+	for _, test := range productTests {
+		if err := UpdateProductPrice(db, tenant, variant.ProductID, test.input); !errors.Is(err, test.want) {
+			t.Errorf("UpdateProductPrice with %s error = %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	variantTests := []struct {
+		name  string
+		input PriceInput
+		want  error
+	}{
+		{"negative price", PriceInput{Price: testAmount(-1)}, ErrNegativePrice},
+		{"negative sale price", PriceInput{SalePrice: testAmount(-5)}, ErrNegativePrice},
+		{"another currency than the product", PriceInput{Currency: "USD", Price: testAmount(100)}, ErrCurrencyMismatch},
+	}
+
+	for _, test := range variantTests {
+		if err := UpdateVariantPrice(db, tenant, variant.ID, test.input); !errors.Is(err, test.want) {
+			t.Errorf("UpdateVariantPrice with %s error = %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	// Nothing of the refused updates was stored
+	product := loadTestProduct(t, db, variant.ProductID)
+	if product.Currency != "EUR" || product.Price == nil || *product.Price != 1000 || product.SalePrice != nil {
+		t.Fatalf("product price = %s %v sale %v, want EUR 1000 without a sale", product.Currency, product.Price, product.SalePrice)
+	}
+	if got := loadTestVariant(t, db, variant.ID); got.Price != nil || got.SalePrice != nil {
+		t.Fatalf("variant price = %v sale %v, want no override", got.Price, got.SalePrice)
+	}
+
+	// A variant of an unpriced product has no currency to price in
+	unpriced := models.Product{Name: "Hat", AdminID: admin.ID, OrganizationID: product.OrganizationID}
+	if err := CreateProduct(db, &unpriced); err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+	hat := models.Variant{VariantName: "One size", ProductID: unpriced.ID}
+	if err := CreateVariant(db, &hat); err != nil {
+		t.Fatalf("CreateVariant: %v", err)
+	}
+	if err := UpdateVariantPrice(db, tenant, hat.ID, PriceInput{Price: testAmount(100)}); !errors.Is(err, ErrCurrencyRequired) {
+		t.Fatalf("price a variant of an unpriced product error = %v, want ErrCurrencyRequired", err)
+	}
+}
+
+func TestChangeProductCurrencyWithOverrides(t *testing.T) {
+	db := openTestDB(t)
+	admin, variant := createTestVariant(t, db, 0)
+	tenant := Tenant{AdminID: admin.ID}
+
+	if err := UpdateProductPrice(db, tenant, variant.ProductID, PriceInput{Currency: "EUR", Price: testAmount(1000)}); err != nil {
+		t.Fatalf("UpdateProductPrice: %v", err)
+	}
+	if err := UpdateVariantPrice(db, tenant, variant.ID, PriceInput{Currency: "EUR", SalePrice: testAmount(800)}); err != nil {
+		t.Fatalf("UpdateVariantPrice: %v", err)
+	}
+
+	if err := UpdateProductPrice(db, tenant, variant.ProductID, PriceInput{Currency: "USD", Price: testAmount(1100)}); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Fatalf("change currency with an override error = %v, want ErrCurrencyMismatch", err)
+	}
+	if product := loadTestProduct(t, db, variant.ProductID); product.Currency != "EUR" || *product.Price != 1000 {
+		t.Fatalf("product price = %s %d, want EUR 1000", product.Currency, *product.Price)
+	}
+
+	// The same currency can still be repriced
+	if err := UpdateProductPrice(db, tenant, variant.ProductID, PriceInput{Currency: "EUR", Price: testAmount(1200)}); err != nil {
+		t.Fatalf("reprice in the same currency: %v", err)
+	}
+
+	// Once the override is cleared the currency can change
+	if err := UpdateVariantPrice(db, tenant, variant.ID, PriceInput{}); err != nil {
+		t.Fatalf("clear the override: %v", err)
+	}
+	if err := UpdateProductPrice(db, tenant, variant.ProductID, PriceInput{Currency: "USD", Price: testAmount(1100)}); err != nil {
+		t.Fatalf("change currency without overrides: %v", err)
+	}
+	if product := loadTestProduct(t, db, variant.ProductID); product.Currency != "USD" || *product.Price != 1100 {
+		t.Fatalf("product price = %s %d, want USD 1100", product.Currency, *product.Price)
+	}
+}
+
+func TestResolvePriceSaleWindow(t *testing.T) {
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(7 * 24 * time.Hour)
+
+	product := &models.Product{Currency: "EUR", Price: testAmount(1000), SalePrice: testAmount(700), SaleStartsAt: &start, SaleEndsAt: &end}
+
+	tests := []struct {
+		name           string
+		variant        models.Variant
+		at             time.Time
+		amount         int64
+		onSale         bool
+		compareAtPrice *int64
+	}{
+		{"before the sale", models.Variant{}, start.Add(-time.Second), 1000, false, nil},
+		{"when the sale starts", models.Variant{}, start, 700, true, testAmount(1000)},
+		{"during the sale", models.Variant{}, start.Add(time.Hour), 700, true, testAmount(1000)},
+		{"when the sale ends", models.Variant{}, end, 1000, false, nil},
+		{"variant price during the sale", models.Variant{Price: testAmount(1200)}, start.Add(time.Hour), 700, true, testAmount(1200)},
+		{"compare at price kept during the sale", models.Variant{CompareAtPrice: testAmount(1500)}, start.Add(time.Hour), 700, true, testAmount(1500)},
+		{"variant sale without a window", models.Variant{SalePrice: testAmount(600)}, start.Add(-time.Hour), 600, true, testAmount(1000)},
+		{"variant sale window over the product's", models.Variant{SalePrice: testAmount(600), SaleStartsAt: &end}, start.Add(time.Hour), 1000, false, nil},
+	}
+
+	for _, test := range tests {
+		price := ResolvePrice(product, &test.variant, test.at)
+		if price == nil {
+			t.Errorf("%s: no price", test.name)
+			continue
+		}
+		if price.Currency != "EUR" || price.Amount != test.amount || price.OnSale != test.onSale {
+			t.Errorf("%s: price = %s %d on sale %t, want EUR %d on sale %t", test.name, price.Currency, price.Amount, price.OnSale, test.amount, test.onSale)
+		}
+		if (price.CompareAtPrice == nil) != (test.compareAtPrice == nil) || (price.CompareAtPrice != nil && *price.CompareAtPrice != *test.compareAtPrice) {
+			t.Errorf("%s: compare at price = %v, want %v", test.name, price.CompareAtPrice, test.compareAtPrice)
+		}
+	}
+
+	if price := ResolvePrice(&models.Product{Currency: "EUR"}, &models.Variant{}, start); price != nil {
+		t.Errorf("unpriced variant resolved to %+v, want nil", price)
+	}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PrincipalKey is the gin context key the authentication middlewares store
+// the *Principal under.
+const PrincipalKey = "principal"
+
+var ErrNoPrincipal = errors.New("no authenticated principal in context")
+
+// Principal is whoever a request was authenticated as, built once by the
+// authentication middleware from a verified JWT or API key.
+type Principal struct {
+	AdminID        uuid.UUID
+	Role           string
+	OrganizationID uuid.UUID
+	AuthMethod     string
+	TokenID        string
+	TokenExpiresAt time.Time
+	Superadmin     bool
+	// Scopes is only set for API keys and further limits what the role allows
+	Scopes []string
+}
+
+func PrincipalFromClaims(claims *Claims) *Principal {
+	principal := &Principal{
+		AdminID:        claims.AdminID,
+		Role:           claims.Role,
+		OrganizationID: claims.OrganizationID,
+		AuthMethod:     claims.AuthMethod,
+		TokenID:        claims.ID,
+		Superadmin:     claims.Superadmin,
+	}
+
+	if claims.ExpiresAt != nil {
+		principal.TokenExpiresAt = claims.ExpiresAt.Time
+	}
+
+	return principal
+}
+
+func SetPrincipal(c *gin.Context, principal *Principal) {
+	c.Set(PrincipalKey, principal)
+}
+
+func PrincipalFromContext(c *gin.Context) (*Principal, error) {
+	value, exists := c.Get(PrincipalKey)
+	if !exists {
+		return nil, ErrNoPrincipal
+	}
+
+	principal, ok := value.(*Principal)
+	if !ok || principal == nil {
+		return nil, ErrNoPrincipal
+	}
+
+	return principal, nil
+}
+
+func (principal *Principal) IsAPIKey() bool {
+	return principal.AuthMethod == AuthMethodAPIKey
+}
+
+// Can checks the role's permissions and, for API keys, the key's scopes.
+func (principal *Principal) Can(permission string) bool {
+	if !HasPermission(principal.Role, permission) {
+		return false
+	}
+
+	return !principal.IsAPIKey() || ScopesAllow(principal.Scopes, permission)
+}
@@ -11,12 +11,12 @@ func CreateProduct(db *gorm.DB, product *models.Product) error {
 	return db.Create(&product).Error
 }
 
-func GetAllProductsWithPaginationAndSearch(db *gorm.DB, page, pageSize int, searchName string) ([]models.Product, error) {
+func GetAllProductsWithPaginationAndSearch(db *gorm.DB, tenant Tenant, page, pageSize int, searchName string) ([]models.Product, error) {
 	var products []models.Product
 
 	offset := (page - 1) * pageSize
 
-	query := db.Offset(offset).Limit(pageSize)
+	query := db.Scopes(ProductTenantScope(tenant)).Offset(offset).Limit(pageSize)
 
 	if searchName != "" {
 		query = query.Where("name LIKE ?", "%"+searchName+"%")
@@ -29,26 +29,33 @@ func GetAllProductsWithPaginationAndSearch(db *gorm.DB, page, pageSize int, sear
 	return products, nil
 }
 
-func GetAllProducts(db *gorm.DB) ([]models.Product, error) {
+func GetAllProducts(db *gorm.DB, tenant Tenant) ([]models.Product, error) {
 	var products []models.Product
 
-	if err := db.Find(&products).Error; err != nil {
+	if err := db.Scopes(ProductTenantScope(tenant)).Find(&products).Error; err != nil {
 		return nil, err
 	}
 
 	return products, nil
 }
 
-func GetProductByID(db *gorm.DB, id uuid.UUID) (*models.Product, error) {
+func GetProductByID(db *gorm.DB, tenant Tenant, id uuid.UUID) (*models.Product, error) {
 	var product models.Product
-	err := db.First(&product, id).Error
+	err := db.Scopes(ProductTenantScope(tenant)).First(&product, id).Error
 	return &product, err
 }
 
-func UpdateProductByID(db *gorm.DB, id uuid.UUID, product *models.Product) error {
-	return db.Model(&models.Product{}).Where("id = ?", id).Updates(product).Error
+func UpdateProductByID(db *gorm.DB, tenant Tenant, id uuid.UUID, product *models.Product) error {
+	return db.Model(&models.Product{}).Scopes(ProductTenantScope(tenant)).Where("id = ?", id).Updates(product).Error
 }
 
-func DeleteProductByID(db *gorm.DB, id uuid.UUID) error {
-	return db.Delete(&models.Product{}, id).Error
+func DeleteProductByID(db *gorm.DB, tenant Tenant, id uuid.UUID) error {
+	return db.Scopes(ProductTenantScope(tenant)).Delete(&models.Product{}, id).Error
+}
+
+// UpdateProductThresholds sets the defaults for the product's variants; nil
+// clears a threshold.
+func UpdateProductThresholds(db *gorm.DB, tenant Tenant, id uuid.UUID, lowStockThreshold, reorderPoint *int) error {
+	return db.Model(&models.Product{}).Scopes(ProductTenantScope(tenant)).Where("id = ?", id).
+		Updates(map[string]interface{}{"low_stock_threshold": lowStockThreshold, "reorder_point": reorderPoint}).Error
 }
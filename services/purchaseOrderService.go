@@ -0,0 +1,287 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	PurchaseOrderDraft             = "draft"
+	PurchaseOrderSubmitted         = "submitted"
+	PurchaseOrderPartiallyReceived = "partially_received"
+	PurchaseOrderReceived          = "received"
+	PurchaseOrderCancelled         = "cancelled"
+	PurchaseOrderClosed            = "closed"
+)
+
+var (
+	ErrPurchaseOrderNotDraft       = errors.New("purchase order is not a draft")
+	ErrPurchaseOrderNotReceivable  = errors.New("purchase order is not awaiting goods")
+	ErrPurchaseOrderNotCancellable = errors.New("purchase order can no longer be cancelled")
+	ErrPurchaseOrderNotClosable    = errors.New("purchase order is not partially received")
+	ErrInvalidPurchaseOrderLines   = errors.New("purchase order lines need distinct variants and positive quantities")
+	ErrVariantNotInOrganization    = errors.New("variant does not belong to the organization")
+	ErrVariantNotOnPurchaseOrder   = errors.New("variant is not on the purchase order")
+	ErrOverReceipt                 = errors.New("received quantity exceeds the quantity outstanding")
+)
+
+type PurchaseOrderLineInput struct {
+	VariantID uuid.UUID
+	Quantity  int
+}
+
+type GoodsReceipt struct {
+	VariantID uuid.UUID
+	Quantity  int
+}
+
+func validatePurchaseOrderLines(db *gorm.DB, organizationID uuid.UUID, lines []PurchaseOrderLineInput) error {
+	if len(lines) == 0 {
+		return ErrInvalidPurchaseOrderLines
+	}
+
+	seen := make(map[uuid.UUID]bool, len(lines))
+	variantIDs := make([]uuid.UUID, 0, len(lines))
+	for _, line := range lines {
+		if line.Quantity <= 0 || seen[line.VariantID] {
+			return ErrInvalidPurchaseOrderLines
+		}
+		seen[line.VariantID] = true
+		variantIDs = append(variantIDs, line.VariantID)
+	}
+
+	inOrganization, err := variantsInOrganization(db, organizationID, variantIDs)
+	if err != nil {
+		return err
+	}
+	if !inOrganization {
+		return ErrVariantNotInOrganization
+	}
+
+	return nil
+}
+
+func purchaseOrderLines(purchaseOrderID uuid.UUID, lines []PurchaseOrderLineInput) []models.PurchaseOrderLine {
+	orderLines := make([]models.PurchaseOrderLine, 0, len(lines))
+	for _, line := range lines {
+		orderLines = append(orderLines, models.PurchaseOrderLine{
+			PurchaseOrderID: purchaseOrderID,
+			VariantID:       line.VariantID,
+			QuantityOrdered: line.Quantity,
+		})
+	}
+	return orderLines
+}
+
+// CreatePurchaseOrder creates a draft order. The supplier, the location and
+// the variants must all belong to the order's organization.
+func CreatePurchaseOrder(db *gorm.DB, order *models.PurchaseOrder, lines []PurchaseOrderLineInput) error {
+	if err := validatePurchaseOrderLines(db, order.OrganizationID, lines); err != nil {
+		return err
+	}
+
+	order.Status = PurchaseOrderDraft
+	order.Lines = purchaseOrderLines(uuid.Nil, lines)
+
+	return db.Create(order).Error
+}
+
+func GetAllPurchaseOrders(db *gorm.DB, tenant Tenant, status string, page, pageSize int) ([]models.PurchaseOrder, error) {
+	var orders []models.PurchaseOrder
+
+	offset := (page - 1) * pageSize
+
+	query := db.Scopes(PurchaseOrderTenantScope(tenant)).Preload("Supplier").Order("created_at DESC").Offset(offset).Limit(pageSize)
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Find(&orders).Error; err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+func GetPurchaseOrderByID(db *gorm.DB, tenant Tenant, id uuid.UUID) (*models.PurchaseOrder, error) {
+	var order models.PurchaseOrder
+	err := db.Scopes(PurchaseOrderTenantScope(tenant)).
+		Preload("Supplier").
+		Preload("Location").
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+		First(&order, id).Error
+	return &order, err
+}
+
+// UpdatePurchaseOrder replaces the note, reference and lines of a draft.
+func UpdatePurchaseOrder(db *gorm.DB, id uuid.UUID, reference, note string, lines []PurchaseOrderLineInput) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var order models.PurchaseOrder
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error; err != nil {
+			return err
+		}
+		if order.Status != PurchaseOrderDraft {
+			return ErrPurchaseOrderNotDraft
+		}
+
+		if err := validatePurchaseOrderLines(tx, order.OrganizationID, lines); err != nil {
+			return err
+		}
+
+		err := tx.Model(&models.PurchaseOrder{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"reference": reference, "note": note}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("purchase_order_id = ?", id).Delete(&models.PurchaseOrderLine{}).Error; err != nil {
+			return err
+		}
+
+		orderLines := purchaseOrderLines(id, lines)
+		return tx.Create(&orderLines).Error
+	})
+}
+
+func SubmitPurchaseOrder(db *gorm.DB, id uuid.UUID) error {
+	res := db.Model(&models.PurchaseOrder{}).
+		Where("id = ? AND status = ?", id, PurchaseOrderDraft).
+		Updates(map[string]interface{}{"status": PurchaseOrderSubmitted, "submitted_at": time.Now()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrPurchaseOrderNotDraft
+	}
+
+	return nil
+}
+
+func CancelPurchaseOrder(db *gorm.DB, id uuid.UUID) error {
+	res := db.Model(&models.PurchaseOrder{}).
+		Where("id = ? AND status IN ?", id, []string{PurchaseOrderDraft, PurchaseOrderSubmitted}).
+		Updates(map[string]interface{}{"status": PurchaseOrderCancelled, "cancelled_at": time.Now()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrPurchaseOrderNotCancellable
+	}
+
+	return nil
+}
+
+// ClosePurchaseOrder closes a partially received order short: what arrived
+// stays booked in and the rest is no longer on order.
+func ClosePurchaseOrder(db *gorm.DB, id uuid.UUID) error {
+	res := db.Model(&models.PurchaseOrder{}).
+		Where("id = ? AND status = ?", id, PurchaseOrderPartiallyReceived).
+		Updates(map[string]interface{}{"status": PurchaseOrderClosed, "closed_at": time.Now()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrPurchaseOrderNotClosable
+	}
+
+	return nil
+}
+
+// ReceivePurchaseOrder books the received goods in as receive movements, at
+// input.LocationID or the order's location, and moves the order to
+// partially_received or received. Nothing is booked if any receipt is
+// invalid.
+func ReceivePurchaseOrder(db *gorm.DB, id uuid.UUID, receipts []GoodsReceipt, input StockMovementInput) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var order models.PurchaseOrder
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Lines").First(&order, id).Error; err != nil {
+			return err
+		}
+		if order.Status != PurchaseOrderSubmitted && order.Status != PurchaseOrderPartiallyReceived {
+			return ErrPurchaseOrderNotReceivable
+		}
+
+		lines := make(map[uuid.UUID]*models.PurchaseOrderLine, len(order.Lines))
+		for i := range order.Lines {
+			lines[order.Lines[i].VariantID] = &order.Lines[i]
+		}
+
+		input.Type = MovementReceive
+		if input.LocationID == nil {
+			input.LocationID = &order.LocationID
+		}
+		if input.Reference == "" {
+			input.Reference = "purchase-order:" + order.ID.String()
+		}
+
+		for _, receipt := range receipts {
+			line, ok := lines[receipt.VariantID]
+			if !ok {
+				return ErrVariantNotOnPurchaseOrder
+			}
+			if receipt.Quantity <= 0 {
+				return ErrInvalidMovementQuantity
+			}
+			if line.QuantityReceived+receipt.Quantity > line.QuantityOrdered {
+				return ErrOverReceipt
+			}
+
+			input.Quantity = receipt.Quantity
+			if _, err := RecordStockMovement(tx, receipt.VariantID, input); err != nil {
+				return err
+			}
+
+			err := tx.Model(&models.PurchaseOrderLine{}).Where("id = ?", line.ID).
+				Update("quantity_received", gorm.Expr("quantity_received + ?", receipt.Quantity)).Error
+			if err != nil {
+				return err
+			}
+			line.QuantityReceived += receipt.Quantity
+		}
+
+		updates := map[string]interface{}{"status": PurchaseOrderReceived, "received_at": time.Now()}
+		for _, line := range order.Lines {
+			if line.QuantityReceived < line.QuantityOrdered {
+				updates = map[string]interface{}{"status": PurchaseOrderPartiallyReceived}
+				break
+			}
+		}
+
+		return tx.Model(&models.PurchaseOrder{}).Where("id = ?", order.ID).Updates(updates).Error
+	})
+}
+
+// GetOnOrderQuantities returns, per variant, the units ordered but not yet
+// received on submitted and partially received purchase orders.
+func GetOnOrderQuantities(db *gorm.DB, variantIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	onOrder := make(map[uuid.UUID]int, len(variantIDs))
+	if len(variantIDs) == 0 {
+		return onOrder, nil
+	}
+
+	var rows []struct {
+		VariantID uuid.UUID
+		OnOrder   int
+	}
+	err := db.Model(&models.PurchaseOrderLine{}).
+		Select("purchase_order_lines.variant_id, SUM(purchase_order_lines.quantity_ordered - purchase_order_lines.quantity_received) AS on_order").
+		Joins("JOIN purchase_orders ON purchase_orders.id = purchase_order_lines.purchase_order_id").
+		Where("purchase_order_lines.variant_id IN ? AND purchase_orders.status IN ?", variantIDs, []string{PurchaseOrderSubmitted, PurchaseOrderPartiallyReceived}).
+		Group("purchase_order_lines.variant_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		onOrder[row.VariantID] = row.OnOrder
+	}
+
+	return onOrder, nil
+}
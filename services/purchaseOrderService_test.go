@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// createTestPurchaseOrder drafts an order for quantity units of the variant,
+// received at the organization's default location.
+func createTestPurchaseOrder(t *testing.T, db *gorm.DB, admin *models.Admin, variant *models.Variant, quantity int) *models.PurchaseOrder {
+	t.Helper()
+
+	organizationID, err := GetDefaultOrganizationID(db, admin.ID)
+	if err != nil {
+		t.Fatalf("GetDefaultOrganizationID: %v", err)
+	}
+	locationID, err := EnsureDefaultLocation(db, organizationID)
+	if err != nil {
+		t.Fatalf("EnsureDefaultLocation: %v", err)
+	}
+
+	supplier := models.Supplier{OrganizationID: organizationID, Name: "Mill"}
+	if err := CreateSupplier(db, &supplier); err != nil {
+		t.Fatalf("CreateSupplier: %v", err)
+	}
+
+	order := &models.PurchaseOrder{OrganizationID: organizationID, SupplierID: supplier.ID, LocationID: locationID, CreatedByID: admin.ID}
+	if err := CreatePurchaseOrder(db, order, []PurchaseOrderLineInput{{VariantID: variant.ID, Quantity: quantity}}); err != nil {
+		t.Fatalf("CreatePurchaseOrder: %v", err)
+	}
+
+	return order
+}
+
+func loadTestPurchaseOrder(t *testing.T, db *gorm.DB, order *models.PurchaseOrder) models.PurchaseOrder {
+	t.Helper()
+
+	var loaded models.PurchaseOrder
+	if err := db.Preload("Lines").First(&loaded, order.ID).Error; err != nil {
+		t.Fatalf("load purchase order: %v", err)
+	}
+	return loaded
+}
+
+func TestReceivePurchaseOrder(t *testing.T) {
+	db := openTestDB(t)
+	admin, variant := createTestVariant(t, db, 2)
+	order := createTestPurchaseOrder(t, db, admin, variant, 10)
+
+	receipt := []GoodsReceipt{{VariantID: variant.ID, Quantity: 4}}
+	if err := ReceivePurchaseOrder(db, order.ID, receipt, StockMovementInput{}); !errors.Is(err, ErrPurchaseOrderNotReceivable) {
+		t.Fatalf("receive a draft error = %v, want ErrPurchaseOrderNotReceivable", err)
+	}
+
+	if err := SubmitPurchaseOrder(db, order.ID); err != nil {
+		t.Fatalf("SubmitPurchaseOrder: %v", err)
+	}
+	if err := SubmitPurchaseOrder(db, order.ID); !errors.Is(err, ErrPurchaseOrderNotDraft) {
+		t.Fatalf("second submit error = %v, want ErrPurchaseOrderNotDraft", err)
+	}
+
+	if err := ReceivePurchaseOrder(db, order.ID, receipt, StockMovementInput{}); err != nil {
+		t.Fatalf("ReceivePurchaseOrder: %v", err)
+	}
+	if got := loadTestPurchaseOrder(t, db, order); got.Status != PurchaseOrderPartiallyReceived || got.Lines[0].QuantityReceived != 4 {
+		t.Fatalf("status %s received %d, want partially_received and 4", got.Status, got.Lines[0].QuantityReceived)
+	}
+
+	// Over-receipt books nothing, not even the valid part
+	overReceipt := []GoodsReceipt{{VariantID: variant.ID, Quantity: 7}}
+	if err := ReceivePurchaseOrder(db, order.ID, overReceipt, StockMovementInput{}); !errors.Is(err, ErrOverReceipt) {
+		t.Fatalf("over-receipt error = %v, want ErrOverReceipt", err)
+	}
+	if got := loadTestVariant(t, db, variant.ID); got.Quantity != 6 {
+		t.Fatalf("quantity after the refused over-receipt = %d, want 6", got.Quantity)
+	}
+
+	other := models.Variant{VariantName: "Small", ProductID: variant.ProductID}
+	if err := CreateVariant(db, &other); err != nil {
+		t.Fatalf("CreateVariant: %v", err)
+	}
+	if err := ReceivePurchaseOrder(db, order.ID, []GoodsReceipt{{VariantID: other.ID, Quantity: 1}}, StockMovementInput{}); !errors.Is(err, ErrVariantNotOnPurchaseOrder) {
+		t.Fatalf("receive a variant not on the order error = %v, want ErrVariantNotOnPurchaseOrder", err)
+	}
+
+	if err := CancelPurchaseOrder(db, order.ID); !errors.Is(err, ErrPurchaseOrderNotCancellable) {
+		t.Fatalf("cancel a partially received order error = %v, want ErrPurchaseOrderNotCancellable", err)
+	}
+
+	if err := ReceivePurchaseOrder(db, order.ID, []GoodsReceipt{{VariantID: variant.ID, Quantity: 6}}, StockMovementInput{}); err != nil {
+		t.Fatalf("receive the rest: %v", err)
+	}
+	got := loadTestPurchaseOrder(t, db, order)
+	if got.Status != PurchaseOrderReceived || got.ReceivedAt == nil {
+		t.Fatalf("status %s received at %v, want received", got.Status, got.ReceivedAt)
+	}
+	if stocked := loadTestVariant(t, db, variant.ID); stocked.Quantity != 12 {
+		t.Fatalf("quantity = %d, want 12", stocked.Quantity)
+	}
+
+	if err := ReceivePurchaseOrder(db, order.ID, receipt, StockMovementInput{}); !errors.Is(err, ErrPurchaseOrderNotReceivable) {
+		t.Fatalf("receive a received order error = %v, want ErrPurchaseOrderNotReceivable", err)
+	}
+	if err := ClosePurchaseOrder(db, order.ID); !errors.Is(err, ErrPurchaseOrderNotClosable) {
+		t.Fatalf("close a received order error = %v, want ErrPurchaseOrderNotClosable", err)
+	}
+}
+
+func TestCancelPurchaseOrder(t *testing.T) {
+	db := openTestDB(t)
+	admin, variant := createTestVariant(t, db, 0)
+
+	draft := createTestPurchaseOrder(t, db, admin, variant, 5)
+	if err := CancelPurchaseOrder(db, draft.ID); err != nil {
+		t.Fatalf("cancel a draft: %v", err)
+	}
+
+	submitted := createTestPurchaseOrder(t, db, admin, variant, 5)
+	if err := SubmitPurchaseOrder(db, submitted.ID); err != nil {
+		t.Fatalf("SubmitPurchaseOrder: %v", err)
+	}
+	if err := ClosePurchaseOrder(db, submitted.ID); !errors.Is(err, ErrPurchaseOrderNotClosable) {
+		t.Fatalf("close a submitted order error = %v, want ErrPurchaseOrderNotClosable", err)
+	}
+	if err := CancelPurchaseOrder(db, submitted.ID); err != nil {
+		t.Fatalf("cancel a submitted order: %v", err)
+	}
+
+	if err := SubmitPurchaseOrder(db, submitted.ID); !errors.Is(err, ErrPurchaseOrderNotDraft) {
+		t.Fatalf("submit a cancelled order error = %v, want ErrPurchaseOrderNotDraft", err)
+	}
+	if err := CancelPurchaseOrder(db, submitted.ID); !errors.Is(err, ErrPurchaseOrderNotCancellable) {
+		t.Fatalf("cancel twice error = %v, want ErrPurchaseOrderNotCancellable", err)
+	}
+}
+
+func TestClosePartiallyReceivedPurchaseOrder(t *testing.T) {
+	db := openTestDB(t)
+	admin, variant := createTestVariant(t, db, 0)
+	order := createTestPurchaseOrder(t, db, admin, variant, 10)
+
+	if err := SubmitPurchaseOrder(db, order.ID); err != nil {
+		t.Fatalf("SubmitPurchaseOrder: %v", err)
+	}
+	if err := ReceivePurchaseOrder(db, order.ID, []GoodsReceipt{{VariantID: variant.ID, Quantity: 3}}, StockMovementInput{}); err != nil {
+		t.Fatalf("ReceivePurchaseOrder: %v", err)
+	}
+
+	onOrder, err := GetOnOrderQuantities(db, []uuid.UUID{variant.ID})
+	if err != nil || onOrder[variant.ID] != 7 {
+		t.Fatalf("on order = %v, %v; want 7", onOrder, err)
+	}
+
+	if err := ClosePurchaseOrder(db, order.ID); err != nil {
+		t.Fatalf("ClosePurchaseOrder: %v", err)
+	}
+
+	got := loadTestPurchaseOrder(t, db, order)
+	if got.Status != PurchaseOrderClosed || got.ClosedAt == nil {
+		t.Fatalf("status %s closed at %v, want closed", got.Status, got.ClosedAt)
+	}
+
+	if err := ReceivePurchaseOrder(db, order.ID, []GoodsReceipt{{VariantID: variant.ID, Quantity: 1}}, StockMovementInput{}); !errors.Is(err, ErrPurchaseOrderNotReceivable) {
+		t.Fatalf("receive a closed order error = %v, want ErrPurchaseOrderNotReceivable", err)
+	}
+
+	onOrder, err = GetOnOrderQuantities(db, []uuid.UUID{variant.ID})
+	if err != nil || onOrder[variant.ID] != 0 {
+		t.Fatalf("on order after closing = %v, %v; want 0", onOrder, err)
+	}
+
+	// The received units stay and the variant is no longer held by the order
+	if got := loadTestVariant(t, db, variant.ID); got.Quantity != 3 {
+		t.Fatalf("quantity = %d, want 3", got.Quantity)
+	}
+	if err := DeleteVariantByID(db, Tenant{AdminID: admin.ID}, variant.ID); err != nil {
+		t.Fatalf("DeleteVariantByID after closing: %v", err)
+	}
+}
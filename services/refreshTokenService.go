@@ -0,0 +1,156 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const RefreshTokenTTL = 30 * 24 * time.Hour
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+)
+
+// IssueRefreshToken starts a new token family for the admin and returns the
+// opaque token. Only its hash is stored. The auth method is carried over to
+// every access token the family is exchanged for.
+func IssueRefreshToken(db *gorm.DB, adminID uuid.UUID, authMethod string) (string, error) {
+	return createRefreshToken(db, adminID, uuid.New(), authMethod)
+}
+
+// RotateRefreshToken exchanges a refresh token for a new one in the same
+// family. Presenting a token that was already rotated or revoked revokes the
+// whole family, since it means the token has leaked.
+func RotateRefreshToken(db *gorm.DB, token string) (*models.RefreshToken, string, error) {
+	var current models.RefreshToken
+	if err := db.Where("token_hash = ?", HashToken(token)).First(&current).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrInvalidRefreshToken
+		}
+		return nil, "", err
+	}
+
+	if current.RevokedAt != nil {
+		if err := RevokeRefreshTokenFamily(db, current.FamilyID); err != nil {
+			return nil, "", err
+		}
+		return nil, "", ErrRefreshTokenReused
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	var newToken string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Only one concurrent rotation may win; the loser is treated as reuse.
+		res := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Update("revoked_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		var err error
+		newToken, err = createRefreshToken(tx, current.AdminID, current.FamilyID, current.AuthMethod)
+		return err
+	})
+
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if err := RevokeRefreshTokenFamily(db, current.FamilyID); err != nil {
+			return nil, "", err
+		}
+		return nil, "", ErrRefreshTokenReused
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &current, newToken, nil
+}
+
+func RevokeRefreshTokenFamily(db *gorm.DB, familyID uuid.UUID) error {
+	return db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func createRefreshToken(db *gorm.DB, adminID, familyID uuid.UUID, authMethod string) (string, error) {
+	token, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	refreshToken := models.RefreshToken{
+		AdminID:    adminID,
+		FamilyID:   familyID,
+		TokenHash:  HashToken(token),
+		AuthMethod: authMethod,
+		ExpiresAt:  time.Now().Add(RefreshTokenTTL),
+	}
+
+	if err := db.Create(&refreshToken).Error; err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func RevokeAdminRefreshTokens(db *gorm.DB, adminID uuid.UUID) error {
+	return db.Model(&models.RefreshToken{}).
+		Where("admin_id = ? AND revoked_at IS NULL", adminID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeRefreshToken revokes the family of the given token, provided it
+// belongs to the admin.
+func RevokeRefreshToken(db *gorm.DB, adminID uuid.UUID, token string) error {
+	var refreshToken models.RefreshToken
+	err := db.Where("token_hash = ? AND admin_id = ?", HashToken(token), adminID).First(&refreshToken).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		return err
+	}
+
+	return RevokeRefreshTokenFamily(db, refreshToken.FamilyID)
+}
+
+// IssueAccessToken issues an access token scoped to the admin's default
+// organization, if they have one.
+func IssueAccessToken(db *gorm.DB, admin *models.Admin, authMethod string) (string, error) {
+	if admin.DeactivatedAt != nil {
+		return "", ErrAdminDeactivated
+	}
+
+	organizationID, err := GetDefaultOrganizationID(db, admin.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+
+	return GenerateJWT(admin, organizationID, authMethod)
+}
+
+// IssueSession returns a new access token and a new refresh token family.
+func IssueSession(db *gorm.DB, admin *models.Admin, authMethod string) (string, string, error) {
+	token, err := IssueAccessToken(db, admin, authMethod)
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := IssueRefreshToken(db, admin.ID, authMethod)
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, refreshToken, nil
+}
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"os"
+)
+
+const (
+	RegistrationOpen        = "open"
+	RegistrationVerifyEmail = "verify"
+	RegistrationInviteOnly  = "invite"
+)
+
+// RegistrationMode reads REGISTRATION_MODE and falls back to open
+// registration.
+func RegistrationMode() string {
+	switch mode := os.Getenv("REGISTRATION_MODE"); mode {
+	case RegistrationVerifyEmail, RegistrationInviteOnly:
+		return mode
+	default:
+		return RegistrationOpen
+	}
+}
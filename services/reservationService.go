@@ -0,0 +1,208 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	ReservationActive    = "active"
+	ReservationConfirmed = "confirmed"
+	ReservationReleased  = "released"
+	ReservationExpired   = "expired"
+)
+
+const (
+	DefaultReservationTTL = 15 * time.Minute
+	MaxReservationTTL     = 24 * time.Hour
+)
+
+var (
+	ErrInvalidReservationQuantity = errors.New("invalid reservation quantity")
+	ErrInsufficientAvailableStock = errors.New("insufficient available stock")
+	ErrReservationNotActive       = errors.New("reservation is no longer active")
+)
+
+// ReserveStock holds quantity units of the variant until ttl passes. Reserved
+// units are counted in Variant.Reserved, so available stock is Quantity -
+// Reserved; the conditional UPDATE keeps concurrent reservations from
+// overselling.
+func ReserveStock(db *gorm.DB, variantID, adminID uuid.UUID, quantity int, ttl time.Duration, reference string) (*models.StockReservation, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidReservationQuantity
+	}
+
+	var reservation models.StockReservation
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.Variant{}).
+			Where("id = ? AND quantity - reserved >= ?", variantID, quantity).
+			Update("reserved", gorm.Expr("reserved + ?", quantity))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			if _, err := GetVariantQuantity(tx, variantID); err != nil {
+				return err
+			}
+			return ErrInsufficientAvailableStock
+		}
+
+		reservation = models.StockReservation{
+			VariantID:   variantID,
+			Quantity:    quantity,
+			Status:      ReservationActive,
+			Reference:   reference,
+			CreatedByID: adminID,
+			ExpiresAt:   time.Now().Add(ttl),
+		}
+
+		return tx.Create(&reservation).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+func GetReservationByID(db *gorm.DB, id uuid.UUID) (*models.StockReservation, error) {
+	var reservation models.StockReservation
+	err := db.First(&reservation, id).Error
+	return &reservation, err
+}
+
+// ConfirmReservation turns the held units into a sale recorded in the stock
+// ledger.
+func ConfirmReservation(db *gorm.DB, id uuid.UUID, input StockMovementInput) (*models.StockReservation, error) {
+	var reservation *models.StockReservation
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		reservation, err = closeReservation(tx, id, ReservationConfirmed, "confirmed_at")
+		if err != nil {
+			return err
+		}
+
+		input.Type = MovementSell
+		input.Quantity = -reservation.Quantity
+		if input.Reference == "" {
+			input.Reference = "reservation:" + reservation.ID.String()
+		}
+
+		_, err = RecordStockMovement(tx, reservation.VariantID, input)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+func ReleaseReservation(db *gorm.DB, id uuid.UUID) (*models.StockReservation, error) {
+	var reservation *models.StockReservation
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		reservation, err = closeReservation(tx, id, ReservationReleased, "released_at")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+// ExpireReservations releases every active reservation past its expiry and
+// returns how many it released.
+func ExpireReservations(db *gorm.DB) (int, error) {
+	var reservations []models.StockReservation
+	err := db.Select("id").
+		Where("status = ? AND expires_at <= ?", ReservationActive, time.Now()).
+		Find(&reservations).Error
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, reservation := range reservations {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			_, err := closeReservation(tx, reservation.ID, ReservationExpired, "released_at")
+			return err
+		})
+		// Confirmed or released in the meantime
+		if errors.Is(err, ErrReservationNotActive) {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired++
+	}
+
+	return expired, nil
+}
+
+// StartReservationSweeper expires stale reservations in the background.
+func StartReservationSweeper(db *gorm.DB, interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			expired, err := ExpireReservations(db)
+			if err != nil {
+				log.Printf("Failed to expire stock reservations: %v", err)
+			}
+			if expired > 0 {
+				log.Printf("Expired %d stock reservations", expired)
+			}
+		}
+	}()
+}
+
+// closeReservation moves an active reservation to status and hands its units
+// back to available stock. Only one caller can win the status change, so a
+// reservation is never released twice.
+func closeReservation(tx *gorm.DB, id uuid.UUID, status, timestampColumn string) (*models.StockReservation, error) {
+	reservation, err := GetReservationByID(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expired reservations can't be confirmed, even before the sweeper ran
+	condition := tx.Where("id = ? AND status = ?", id, ReservationActive)
+	if status == ReservationConfirmed {
+		condition = condition.Where("expires_at > ?", time.Now())
+	}
+
+	now := time.Now()
+	res := condition.Model(&models.StockReservation{}).
+		Updates(map[string]interface{}{"status": status, timestampColumn: now})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrReservationNotActive
+	}
+
+	err = tx.Model(&models.Variant{}).
+		Where("id = ?", reservation.VariantID).
+		Update("reserved", gorm.Expr("reserved - ?", reservation.Quantity)).Error
+	if err != nil {
+		return nil, err
+	}
+
+	reservation.Status = status
+	if status == ReservationConfirmed {
+		reservation.ConfirmedAt = &now
+	} else {
+		reservation.ReleasedAt = &now
+	}
+
+	return reservation, nil
+}
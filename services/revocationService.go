@@ -0,0 +1,152 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevocationStore interface {
+	RevokeToken(tokenID string, expiresAt time.Time) error
+	IsTokenRevoked(tokenID string) (bool, error)
+	RevokeAdminTokens(adminID uuid.UUID, at time.Time) error
+	// AdminTokensRevokedAt returns the zero time when the admin never signed
+	// out everywhere.
+	AdminTokensRevokedAt(adminID uuid.UUID) (time.Time, error)
+}
+
+var TokenRevocations RevocationStore = NewMemoryRevocationStore()
+
+func IsClaimsRevoked(claims *Claims) (bool, error) {
+	if claims.ID != "" {
+		revoked, err := TokenRevocations.IsTokenRevoked(claims.ID)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	revokedAt, err := TokenRevocations.AdminTokensRevokedAt(claims.AdminID)
+	if err != nil || revokedAt.IsZero() {
+		return false, err
+	}
+
+	if claims.IssuedAt == nil {
+		return true, nil
+	}
+	return claims.IssuedAt.Round(time.Millisecond).Before(revokedAt), nil
+}
+
+type MemoryRevocationStore struct {
+	mu     sync.RWMutex
+	tokens map[string]time.Time
+	admins map[uuid.UUID]time.Time
+}
+
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		tokens: make(map[string]time.Time),
+		admins: make(map[uuid.UUID]time.Time),
+	}
+}
+
+func (s *MemoryRevocationStore) RevokeToken(tokenID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, exp := range s.tokens {
+		if now.After(exp) {
+			delete(s.tokens, id)
+		}
+	}
+
+	s.tokens[tokenID] = expiresAt
+	return nil
+}
+
+func (s *MemoryRevocationStore) IsTokenRevoked(tokenID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.tokens[tokenID]
+	return ok, nil
+}
+
+func (s *MemoryRevocationStore) RevokeAdminTokens(adminID uuid.UUID, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.admins[adminID] = at
+	return nil
+}
+
+func (s *MemoryRevocationStore) AdminTokensRevokedAt(adminID uuid.UUID) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.admins[adminID], nil
+}
+
+type GormRevocationStore struct {
+	db *gorm.DB
+}
+
+func NewGormRevocationStore(db *gorm.DB) *GormRevocationStore {
+	return &GormRevocationStore{db: db}
+}
+
+func (s *GormRevocationStore) RevokeToken(tokenID string, expiresAt time.Time) error {
+	if err := s.db.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error; err != nil {
+		return err
+	}
+
+	revokedToken := models.RevokedToken{TokenID: tokenID, ExpiresAt: expiresAt}
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&revokedToken).Error
+}
+
+func (s *GormRevocationStore) IsTokenRevoked(tokenID string) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.RevokedToken{}).Where("token_id = ?", tokenID).Count(&count).Error
+	return count > 0, err
+}
+
+func (s *GormRevocationStore) RevokeAdminTokens(adminID uuid.UUID, at time.Time) error {
+	revocation := models.AdminRevocation{AdminID: adminID, RevokedAt: at}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "admin_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_at"}),
+	}).Create(&revocation).Error
+}
+
+func (s *GormRevocationStore) AdminTokensRevokedAt(adminID uuid.UUID) (time.Time, error) {
+	var revocation models.AdminRevocation
+	err := s.db.Where("admin_id = ?", adminID).First(&revocation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+
+	return revocation.RevokedAt, err
+}
+
+// RevokeAdminSessions signs the admin out everywhere: all access tokens issued
+// before the current millisecond and all refresh tokens are invalidated. The
+// cutoff has the precision of the iat claim, so a token issued right after
+// the call, within the same millisecond, stays valid. Passing a transaction
+// makes the revocation part of it when the store lives in the database.
+func RevokeAdminSessions(db *gorm.DB, adminID uuid.UUID) error {
+	if err := RevokeAdminRefreshTokens(db, adminID); err != nil {
+		return err
+	}
+
+	store := TokenRevocations
+	if _, ok := store.(*GormRevocationStore); ok {
+		store = NewGormRevocationStore(db)
+	}
+
+	return store.RevokeAdminTokens(adminID, time.Now().Truncate(time.Millisecond))
+}
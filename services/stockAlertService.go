@@ -0,0 +1,449 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang-final-project/models"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	StockAlertLowStock = "low_stock"
+	StockAlertReorder  = "reorder"
+)
+
+// Alerts that keep failing are given up on after this many attempts.
+const maxStockAlertAttempts = 10
+
+var ErrWebhookAddressNotAllowed = errors.New("webhook address is not public")
+
+// nonPublicNetworks are refused as webhook targets on top of what the net.IP
+// predicates cover: "this network" and carrier-grade NAT, where some clouds
+// serve instance metadata.
+var nonPublicNetworks = []*net.IPNet{
+	{IP: net.IPv4(0, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+	{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)},
+}
+
+// isPublicIP reports whether a webhook may be delivered to ip. Loopback,
+// private and link-local addresses would let an organization reach services
+// inside our network.
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkWebhookHost resolves host and refuses it unless every address is
+// public. The addresses are checked again when connecting, since DNS can
+// change after the URL was saved.
+func checkWebhookHost(host string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addresses) == 0 {
+		return ErrInvalidWebhookURL
+	}
+
+	for _, address := range addresses {
+		if !isPublicIP(address.IP) {
+			return ErrWebhookAddressNotAllowed
+		}
+	}
+
+	return nil
+}
+
+// NewWebhookHTTPClient returns a client that refuses to connect to anything
+// but public addresses. The check runs on the address actually dialed, so it
+// also covers redirects and DNS answers that changed since the URL was saved.
+func NewWebhookHTTPClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return ErrWebhookAddressNotAllowed
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			// A proxy would be dialed instead of the webhook host
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+	}
+}
+
+// StockAlertEvent is what notifiers receive, and the webhook body.
+type StockAlertEvent struct {
+	ID             uuid.UUID `json:"id"`
+	Type           string    `json:"type"`
+	OrganizationID uuid.UUID `json:"organizationID"`
+	VariantID      uuid.UUID `json:"variantID"`
+	VariantName    string    `json:"variantName"`
+	ProductID      uuid.UUID `json:"productID"`
+	ProductName    string    `json:"productName"`
+	Threshold      int       `json:"threshold"`
+	Quantity       int       `json:"quantity"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+type StockAlertNotifier interface {
+	Notify(event StockAlertEvent) error
+}
+
+var DefaultStockAlertNotifier StockAlertNotifier = &LogStockAlertNotifier{}
+
+// NewStockAlertNotifierFromEnv routes alerts to each organization's own
+// webhook and mailbox, unless STOCK_ALERT_NOTIFIER is "log".
+func NewStockAlertNotifierFromEnv(db *gorm.DB) StockAlertNotifier {
+	if os.Getenv("STOCK_ALERT_NOTIFIER") == "log" {
+		return &LogStockAlertNotifier{}
+	}
+
+	return &OrganizationStockAlertNotifier{
+		DB:         db,
+		HTTPClient: NewWebhookHTTPClient(),
+		Fallback:   &LogStockAlertNotifier{},
+	}
+}
+
+// OrganizationStockAlertNotifier sends the alert to the webhook and email
+// addresses the variant's organization configured. Alerts of organizations
+// without either go to Fallback. Each channel's delivery is recorded on the
+// alert, so a retry after one channel failed does not repeat the other.
+type OrganizationStockAlertNotifier struct {
+	DB         *gorm.DB
+	HTTPClient *http.Client
+	Fallback   StockAlertNotifier
+}
+
+func (n *OrganizationStockAlertNotifier) Notify(event StockAlertEvent) error {
+	var organization models.Organization
+	if err := n.DB.First(&organization, event.OrganizationID).Error; err != nil {
+		return err
+	}
+
+	if organization.StockAlertWebhookURL == "" && organization.StockAlertEmailTo == "" {
+		return n.Fallback.Notify(event)
+	}
+
+	var alert models.StockAlert
+	if err := n.DB.Where("id = ?", event.ID).Limit(1).Find(&alert).Error; err != nil {
+		return err
+	}
+
+	var errs []error
+
+	if organization.StockAlertWebhookURL != "" && alert.WebhookSentAt == nil {
+		webhook := &WebhookStockAlertNotifier{
+			URL:        organization.StockAlertWebhookURL,
+			Secret:     organization.StockAlertWebhookSecret,
+			HTTPClient: n.HTTPClient,
+		}
+		if err := webhook.Notify(event); err != nil {
+			errs = append(errs, err)
+		} else if err := n.markDelivered(event.ID, "webhook_sent_at"); err != nil {
+			return err
+		}
+	}
+
+	if organization.StockAlertEmailTo != "" && alert.EmailSentAt == nil {
+		email := &EmailStockAlertNotifier{To: strings.Split(organization.StockAlertEmailTo, ",")}
+		if err := email.Notify(event); err != nil {
+			errs = append(errs, err)
+		} else if err := n.markDelivered(event.ID, "email_sent_at"); err != nil {
+			return err
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (n *OrganizationStockAlertNotifier) markDelivered(alertID uuid.UUID, column string) error {
+	return n.DB.Model(&models.StockAlert{}).Where("id = ?", alertID).Update(column, time.Now()).Error
+}
+
+type LogStockAlertNotifier struct{}
+
+func (n *LogStockAlertNotifier) Notify(event StockAlertEvent) error {
+	log.Printf("stock alert: %s %s / %s quantity=%d threshold=%d", event.Type, event.ProductName, event.VariantName, event.Quantity, event.Threshold)
+	return nil
+}
+
+// WebhookStockAlertNotifier POSTs the event as JSON. With a Secret, the body
+// is signed in the X-Signature header as "sha256=<hex hmac>".
+type WebhookStockAlertNotifier struct {
+	URL        string
+	Secret     string
+	HTTPClient *http.Client
+}
+
+func (n *WebhookStockAlertNotifier) Notify(event StockAlertEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	if n.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(n.Secret))
+		mac.Write(body)
+		request.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	response, err := n.HTTPClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("stock alert webhook returned %s", response.Status)
+	}
+
+	return nil
+}
+
+type EmailStockAlertNotifier struct {
+	To []string
+}
+
+func (n *EmailStockAlertNotifier) Notify(event StockAlertEvent) error {
+	subject := "Low stock: " + event.ProductName + " / " + event.VariantName
+	if event.Type == StockAlertReorder {
+		subject = "Reorder point reached: " + event.ProductName + " / " + event.VariantName
+	}
+
+	body := fmt.Sprintf("%s / %s is down to %d units (threshold %d).", event.ProductName, event.VariantName, event.Quantity, event.Threshold)
+
+	for _, to := range n.To {
+		to = strings.TrimSpace(to)
+		if to == "" {
+			continue
+		}
+
+		if err := DefaultMailer.Send(Email{To: to, Subject: subject, Body: body}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EffectiveThresholds falls back to the product's defaults for thresholds the
+// variant does not set.
+func EffectiveThresholds(variant *models.Variant, product *models.Product) (lowStock, reorderPoint *int) {
+	lowStock, reorderPoint = variant.LowStockThreshold, variant.ReorderPoint
+	if lowStock == nil {
+		lowStock = product.LowStockThreshold
+	}
+	if reorderPoint == nil {
+		reorderPoint = product.ReorderPoint
+	}
+	return lowStock, reorderPoint
+}
+
+// recordStockAlerts stores an alert for every threshold the variant's total
+// fell to or below. Alerts are written in the stock change's transaction and
+// delivered by the dispatcher, so a rolled back change never notifies.
+func recordStockAlerts(tx *gorm.DB, variantID uuid.UUID, before, after int) error {
+	if after >= before {
+		return nil
+	}
+
+	var variant models.Variant
+	if err := tx.First(&variant, variantID).Error; err != nil {
+		return err
+	}
+
+	var product models.Product
+	if err := tx.First(&product, variant.ProductID).Error; err != nil {
+		return err
+	}
+
+	lowStock, reorderPoint := EffectiveThresholds(&variant, &product)
+
+	thresholds := []struct {
+		alertType string
+		threshold *int
+	}{
+		{StockAlertLowStock, lowStock},
+		{StockAlertReorder, reorderPoint},
+	}
+
+	for _, t := range thresholds {
+		threshold := t.threshold
+		if threshold == nil || before <= *threshold || after > *threshold {
+			continue
+		}
+
+		alert := models.StockAlert{
+			VariantID:      variantID,
+			ProductID:      product.ID,
+			OrganizationID: product.OrganizationID,
+			Type:           t.alertType,
+			Threshold:      *threshold,
+			Quantity:       after,
+		}
+		if err := tx.Create(&alert).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DispatchStockAlerts sends pending alerts through DefaultStockAlertNotifier
+// and returns how many were delivered.
+func DispatchStockAlerts(db *gorm.DB) (int, error) {
+	var alerts []models.StockAlert
+	err := db.Where("sent_at IS NULL AND attempts < ?", maxStockAlertAttempts).
+		Order("created_at").
+		Limit(100).
+		Find(&alerts).Error
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, alert := range alerts {
+		event := StockAlertEvent{
+			ID:             alert.ID,
+			Type:           alert.Type,
+			OrganizationID: alert.OrganizationID,
+			VariantID:      alert.VariantID,
+			ProductID:      alert.ProductID,
+			Threshold:      alert.Threshold,
+			Quantity:       alert.Quantity,
+			CreatedAt:      alert.CreatedAt,
+		}
+
+		// Names are looked up at send time; the variant may be gone by then
+		var variant models.Variant
+		if db.Select("variant_name").First(&variant, alert.VariantID).Error == nil {
+			event.VariantName = variant.VariantName
+		}
+		var product models.Product
+		if db.Select("name", "organization_id").First(&product, alert.ProductID).Error == nil {
+			event.ProductName = product.Name
+			// Alerts recorded before they carried their organization
+			if event.OrganizationID == uuid.Nil {
+				event.OrganizationID = product.OrganizationID
+			}
+		}
+
+		updates := map[string]interface{}{"attempts": alert.Attempts + 1}
+		if err := DefaultStockAlertNotifier.Notify(event); err != nil {
+			message := err.Error()
+			if len(message) > 255 {
+				message = message[:255]
+			}
+			updates["last_error"] = message
+		} else {
+			updates["sent_at"] = time.Now()
+			sent++
+		}
+
+		if err := db.Model(&models.StockAlert{}).Where("id = ?", alert.ID).Updates(updates).Error; err != nil {
+			return sent, err
+		}
+	}
+
+	return sent, nil
+}
+
+func StartStockAlertDispatcher(db *gorm.DB, interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			if _, err := DispatchStockAlerts(db); err != nil {
+				log.Printf("Failed to dispatch stock alerts: %v", err)
+			}
+		}
+	}()
+}
+
+// LowStockItem is a row of the low-stock report, with thresholds already
+// resolved against the product defaults.
+type LowStockItem struct {
+	VariantID         uuid.UUID `json:"variantID"`
+	VariantName       string    `json:"variantName"`
+	ProductID         uuid.UUID `json:"productID"`
+	ProductName       string    `json:"productName"`
+	Quantity          int       `json:"quantity"`
+	Reserved          int       `json:"reserved"`
+	LowStockThreshold *int      `json:"lowStockThreshold"`
+	ReorderPoint      *int      `json:"reorderPoint"`
+	NeedsReorder      bool      `json:"needsReorder" gorm:"-"`
+}
+
+// GetLowStockVariants lists the tenant's variants at or below their low-stock
+// threshold or reorder point, emptiest first.
+func GetLowStockVariants(db *gorm.DB, tenant Tenant, page, pageSize int) ([]LowStockItem, error) {
+	var items []LowStockItem
+
+	offset := (page - 1) * pageSize
+
+	err := db.Model(&models.Variant{}).
+		Scopes(VariantTenantScope(tenant)).
+		Select(`variants.id AS variant_id, variants.variant_name, variants.product_id, products.name AS product_name,
+			variants.quantity, variants.reserved,
+			COALESCE(variants.low_stock_threshold, products.low_stock_threshold) AS low_stock_threshold,
+			COALESCE(variants.reorder_point, products.reorder_point) AS reorder_point`).
+		Joins("JOIN products ON products.id = variants.product_id").
+		Where(`variants.quantity <= COALESCE(variants.low_stock_threshold, products.low_stock_threshold)
+			OR variants.quantity <= COALESCE(variants.reorder_point, products.reorder_point)`).
+		Order("variants.quantity").
+		Offset(offset).
+		Limit(pageSize).
+		Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		items[i].NeedsReorder = items[i].ReorderPoint != nil && items[i].Quantity <= *items[i].ReorderPoint
+	}
+
+	return items, nil
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"golang-final-project/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestTransfersDoNotRaiseStockAlerts(t *testing.T) {
+	db := openTestDB(t)
+	admin, variant := createTestVariant(t, db, 10)
+	warehouse := createTestLocation(t, db, admin, "warehouse")
+
+	threshold := 5
+	if err := db.Model(variant).Update("low_stock_threshold", threshold).Error; err != nil {
+		t.Fatalf("set threshold: %v", err)
+	}
+
+	defaultLocationID, err := resolveLocation(db, variant.ID, nil)
+	if err != nil {
+		t.Fatalf("resolveLocation: %v", err)
+	}
+	if _, err := TransferStock(db, variant.ID, defaultLocationID, warehouse.ID, 8, StockMovementInput{}); err != nil {
+		t.Fatalf("TransferStock: %v", err)
+	}
+
+	var alerts int64
+	db.Model(&models.StockAlert{}).Count(&alerts)
+	if alerts != 0 {
+		t.Fatalf("transfer raised %d alerts, want none", alerts)
+	}
+
+	if _, err := RecordStockMovement(db, variant.ID, StockMovementInput{Type: MovementSell, Quantity: -6, LocationID: &warehouse.ID}); err != nil {
+		t.Fatalf("sell: %v", err)
+	}
+
+	db.Model(&models.StockAlert{}).Count(&alerts)
+	if alerts != 1 {
+		t.Fatalf("sale below the threshold raised %d alerts, want 1", alerts)
+	}
+}
+
+func TestStockAlertsGoToTheirOrganization(t *testing.T) {
+	db := openTestDB(t)
+	admin, variant := createTestVariant(t, db, 10)
+	other := createTestAdmin(t, db, "other@example.com", RoleOwner)
+
+	received := make(chan StockAlertEvent, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event StockAlertEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		received <- event
+	}))
+	t.Cleanup(server.Close)
+
+	organizationID, _ := GetDefaultOrganizationID(db, admin.ID)
+	otherOrganizationID, _ := GetDefaultOrganizationID(db, other.ID)
+
+	// The test server listens on loopback, which the settings refuse
+	setTestWebhook(t, db, organizationID, server.URL)
+	if err := UpdateOrganizationStockAlerts(db, otherOrganizationID, "ftp://example.com", "", ""); err != ErrInvalidWebhookURL {
+		t.Fatalf("ftp webhook error = %v, want ErrInvalidWebhookURL", err)
+	}
+
+	fallback := &recordingStockAlertNotifier{}
+	notifier := &OrganizationStockAlertNotifier{DB: db, HTTPClient: server.Client(), Fallback: fallback}
+
+	if err := notifier.Notify(StockAlertEvent{OrganizationID: organizationID, VariantID: variant.ID}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if event := <-received; event.VariantID != variant.ID {
+		t.Fatalf("webhook received %+v", event)
+	}
+
+	if err := notifier.Notify(StockAlertEvent{OrganizationID: otherOrganizationID}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(fallback.events) != 1 || len(received) != 0 {
+		t.Fatalf("an organization without settings reached another organization's webhook")
+	}
+}
+
+func setTestWebhook(t *testing.T, db *gorm.DB, organizationID uuid.UUID, webhookURL string) {
+	t.Helper()
+
+	err := db.Model(&models.Organization{}).Where("id = ?", organizationID).Update("stock_alert_webhook_url", webhookURL).Error
+	if err != nil {
+		t.Fatalf("set webhook url: %v", err)
+	}
+}
+
+func TestWebhooksOnlyReachPublicAddresses(t *testing.T) {
+	db := openTestDB(t)
+	admin := createTestAdmin(t, db, "owner@example.com", RoleOwner)
+	organizationID, _ := GetDefaultOrganizationID(db, admin.ID)
+
+	for _, webhookURL := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://[::1]/hook",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://100.100.100.200/hook",
+		"http://0.0.0.0/hook",
+	} {
+		if err := UpdateOrganizationStockAlerts(db, organizationID, webhookURL, "", ""); !errors.Is(err, ErrWebhookAddressNotAllowed) {
+			t.Errorf("UpdateOrganizationStockAlerts(%s) error = %v, want ErrWebhookAddressNotAllowed", webhookURL, err)
+		}
+	}
+
+	if err := UpdateOrganizationStockAlerts(db, organizationID, "https://93.184.215.14/hook", "", ""); err != nil {
+		t.Fatalf("public webhook: %v", err)
+	}
+
+	// A host that turns private after it was saved is refused on connect
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	t.Cleanup(server.Close)
+
+	webhook := &WebhookStockAlertNotifier{URL: server.URL, HTTPClient: NewWebhookHTTPClient()}
+	if err := webhook.Notify(StockAlertEvent{}); !errors.Is(err, ErrWebhookAddressNotAllowed) {
+		t.Fatalf("Notify error = %v, want ErrWebhookAddressNotAllowed", err)
+	}
+	if requests != 0 {
+		t.Fatalf("webhook reached a loopback address")
+	}
+}
+
+func TestStockAlertRetryOnlyResendsFailedChannels(t *testing.T) {
+	db := openTestDB(t)
+	admin, variant := createTestVariant(t, db, 10)
+	organizationID, _ := GetDefaultOrganizationID(db, admin.ID)
+
+	var webhooks int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		webhooks++
+	}))
+	t.Cleanup(server.Close)
+
+	setTestWebhook(t, db, organizationID, server.URL)
+	if err := db.Model(&models.Organization{}).Where("id = ?", organizationID).Update("stock_alert_email_to", "ops@example.com").Error; err != nil {
+		t.Fatalf("set email: %v", err)
+	}
+
+	mailer := &failingMailer{failures: 1}
+	previousMailer, previousNotifier := DefaultMailer, DefaultStockAlertNotifier
+	t.Cleanup(func() { DefaultMailer, DefaultStockAlertNotifier = previousMailer, previousNotifier })
+	DefaultMailer = mailer
+	DefaultStockAlertNotifier = &OrganizationStockAlertNotifier{DB: db, HTTPClient: server.Client(), Fallback: &recordingStockAlertNotifier{}}
+
+	if err := db.Model(variant).Update("low_stock_threshold", 5).Error; err != nil {
+		t.Fatalf("set threshold: %v", err)
+	}
+	if _, err := RecordStockMovement(db, variant.ID, StockMovementInput{Type: MovementSell, Quantity: -6}); err != nil {
+		t.Fatalf("sell: %v", err)
+	}
+
+	if sent, err := DispatchStockAlerts(db); err != nil || sent != 0 {
+		t.Fatalf("first dispatch sent %d, %v; want 0 with the mailer down", sent, err)
+	}
+	if sent, err := DispatchStockAlerts(db); err != nil || sent != 1 {
+		t.Fatalf("retry sent %d, %v; want 1", sent, err)
+	}
+
+	if webhooks != 1 || mailer.sent != 1 {
+		t.Fatalf("%d webhooks and %d emails sent, want one of each", webhooks, mailer.sent)
+	}
+
+	var alert models.StockAlert
+	if err := db.First(&alert).Error; err != nil {
+		t.Fatalf("load alert: %v", err)
+	}
+	if alert.SentAt == nil || alert.WebhookSentAt == nil || alert.EmailSentAt == nil || alert.Attempts != 2 {
+		t.Fatalf("alert = %+v, want delivered on both channels after 2 attempts", alert)
+	}
+}
+
+type failingMailer struct {
+	failures int
+	sent     int
+}
+
+func (m *failingMailer) Send(email Email) error {
+	if m.failures > 0 {
+		m.failures--
+		return errors.New("mail server unavailable")
+	}
+	m.sent++
+	return nil
+}
+
+type recordingStockAlertNotifier struct {
+	events []StockAlertEvent
+}
+
+func (n *recordingStockAlertNotifier) Notify(event StockAlertEvent) error {
+	n.events = append(n.events, event)
+	return nil
+}
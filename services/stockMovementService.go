@@ -0,0 +1,313 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	MovementReceive  = "receive"
+	MovementSell     = "sell"
+	MovementAdjust   = "adjust"
+	MovementReturn   = "return"
+	MovementTransfer = "transfer"
+)
+
+const (
+	ReasonInitial    = "initial"
+	ReasonCorrection = "correction"
+	ReasonDamaged    = "damaged"
+	ReasonLost       = "lost"
+	ReasonFound      = "found"
+	ReasonExpired    = "expired"
+	ReasonStockTake  = "stock_take"
+	ReasonOther      = "other"
+)
+
+var (
+	ErrInvalidMovementType     = errors.New("invalid stock movement type")
+	ErrInvalidMovementQuantity = errors.New("invalid stock movement quantity")
+	ErrInvalidReasonCode       = errors.New("invalid reason code")
+	ErrInsufficientStock       = errors.New("insufficient stock")
+	ErrMultiLocationStock      = errors.New("variant is stocked at more than one location")
+)
+
+// InsufficientStockError is returned when a movement would take a stock
+// level below zero or eat into reserved units. It matches
+// ErrInsufficientStock with errors.Is.
+type InsufficientStockError struct {
+	Available int
+}
+
+func (e *InsufficientStockError) Error() string {
+	return ErrInsufficientStock.Error()
+}
+
+func (e *InsufficientStockError) Is(target error) bool {
+	return target == ErrInsufficientStock
+}
+
+// StockMovementInput describes a change in stock. Quantity is a signed delta:
+// receive and return must add stock, sell must remove it, adjust and
+// transfer go either way. Transfers can only be made through TransferStock.
+// Without a LocationID the movement applies to the organization's default
+// location.
+type StockMovementInput struct {
+	Type       string
+	Quantity   int
+	LocationID *uuid.UUID
+	ReasonCode string
+	Reference  string
+	Note       string
+	ActorID    *uuid.UUID
+	AuthMethod string
+
+	// overReserve lets a decrement take the total below what is reserved.
+	// Only a stock take, which records what is physically there, sets it.
+	overReserve bool
+}
+
+func IsValidReasonCode(reasonCode string) bool {
+	switch reasonCode {
+	case ReasonInitial, ReasonCorrection, ReasonDamaged, ReasonLost, ReasonFound, ReasonExpired, ReasonStockTake, ReasonOther:
+		return true
+	}
+	return false
+}
+
+func (input StockMovementInput) Validate() error {
+	switch input.Type {
+	case MovementReceive, MovementReturn:
+		if input.Quantity <= 0 {
+			return ErrInvalidMovementQuantity
+		}
+	case MovementSell:
+		if input.Quantity >= 0 {
+			return ErrInvalidMovementQuantity
+		}
+	case MovementAdjust, MovementTransfer:
+		if input.Quantity == 0 {
+			return ErrInvalidMovementQuantity
+		}
+	default:
+		return ErrInvalidMovementType
+	}
+
+	// Adjustments must say why; other types may
+	if input.ReasonCode == "" && input.Type == MovementAdjust {
+		return ErrInvalidReasonCode
+	}
+	if input.ReasonCode != "" && !IsValidReasonCode(input.ReasonCode) {
+		return ErrInvalidReasonCode
+	}
+
+	return nil
+}
+
+// RecordStockMovement appends the movement to the ledger and applies it to
+// the location's stock level and Variant.Quantity, the total over all
+// locations, in the same transaction. Both change through conditional
+// UPDATEs, so concurrent movements never lose updates. A movement that would
+// take the level below zero, or take the total below what is reserved, fails
+// with an InsufficientStockError. Confirming a reservation hands its units
+// back before selling them, so it can always take its own units.
+// QuantityAfter is the location's level.
+func RecordStockMovement(db *gorm.DB, variantID uuid.UUID, input StockMovementInput) (*models.StockMovement, error) {
+	// A lone transfer leg would move stock out of one location into nowhere
+	if input.Type == MovementTransfer {
+		return nil, ErrInvalidMovementType
+	}
+
+	return recordStockMovement(db, variantID, input)
+}
+
+func recordStockMovement(db *gorm.DB, variantID uuid.UUID, input StockMovementInput) (*models.StockMovement, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	var movement models.StockMovement
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		locationID, err := resolveLocation(tx, variantID, input.LocationID)
+		if err != nil {
+			return err
+		}
+
+		// Every stock change locks the variant row before the level row.
+		// Transfer legs leave the total where it was, so only other
+		// decrements are held to the available quantity
+		variantUpdate := tx.Model(&models.Variant{}).Where("id = ?", variantID)
+		if input.Quantity < 0 && input.Type != MovementTransfer && !input.overReserve {
+			variantUpdate = variantUpdate.Where("quantity - reserved + ? >= 0", input.Quantity)
+		}
+		res := variantUpdate.Update("quantity", gorm.Expr("quantity + ?", input.Quantity))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			available, err := GetVariantAvailable(tx, variantID)
+			if err != nil {
+				return err
+			}
+			return &InsufficientStockError{Available: available}
+		}
+
+		level := models.StockLevel{VariantID: variantID, LocationID: locationID}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&level).Error; err != nil {
+			return err
+		}
+
+		res = tx.Model(&models.StockLevel{}).
+			Where("variant_id = ? AND location_id = ? AND quantity + ? >= 0", variantID, locationID, input.Quantity).
+			Update("quantity", gorm.Expr("quantity + ?", input.Quantity))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			available, err := getStockLevelQuantity(tx, variantID, locationID)
+			if err != nil {
+				return err
+			}
+			return &InsufficientStockError{Available: available}
+		}
+
+		// The UPDATEs hold the row locks, so these read our own results
+		quantityAfter, err := getStockLevelQuantity(tx, variantID, locationID)
+		if err != nil {
+			return err
+		}
+
+		total, err := GetVariantQuantity(tx, variantID)
+		if err != nil {
+			return err
+		}
+
+		// A transfer moves stock between locations and never lowers the
+		// total, so its legs don't alert
+		if input.Type != MovementTransfer {
+			if err := recordStockAlerts(tx, variantID, total-input.Quantity, total); err != nil {
+				return err
+			}
+		}
+
+		movement = models.StockMovement{
+			VariantID:     variantID,
+			LocationID:    &locationID,
+			Type:          input.Type,
+			Quantity:      input.Quantity,
+			QuantityAfter: quantityAfter,
+			ReasonCode:    input.ReasonCode,
+			Reference:     input.Reference,
+			Note:          input.Note,
+			ActorID:       input.ActorID,
+			AuthMethod:    input.AuthMethod,
+		}
+
+		return tx.Create(&movement).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &movement, nil
+}
+
+// SetStockLevel records whatever adjustment brings the stock at
+// input.LocationID to quantity, computed while the variant row is locked.
+// Without a LocationID quantity is the variant's total, which can only be set
+// while at most one location holds stock; otherwise it fails with
+// ErrMultiLocationStock. It returns nil when nothing changes.
+func SetStockLevel(db *gorm.DB, variantID uuid.UUID, quantity int, input StockMovementInput) (*models.StockMovement, error) {
+	if quantity < 0 {
+		return nil, ErrInvalidMovementQuantity
+	}
+
+	var movement *models.StockMovement
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var variant models.Variant
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&variant, variantID).Error; err != nil {
+			return err
+		}
+
+		current := variant.Quantity
+		if input.LocationID != nil {
+			var err error
+			if current, err = getStockLevelQuantity(tx, variantID, *input.LocationID); err != nil {
+				return err
+			}
+		} else {
+			var levels []models.StockLevel
+			if err := tx.Where("variant_id = ? AND quantity <> 0", variantID).Find(&levels).Error; err != nil {
+				return err
+			}
+			if len(levels) > 1 {
+				return ErrMultiLocationStock
+			}
+			if len(levels) == 1 {
+				input.LocationID = &levels[0].LocationID
+			}
+		}
+
+		if current == quantity {
+			return nil
+		}
+
+		input.Type = MovementAdjust
+		input.Quantity = quantity - current
+
+		var err error
+		movement, err = RecordStockMovement(tx, variantID, input)
+		return err
+	})
+
+	return movement, err
+}
+
+func GetStockMovementsByVariantID(db *gorm.DB, variantID uuid.UUID, page, pageSize int) ([]models.StockMovement, error) {
+	var movements []models.StockMovement
+
+	offset := (page - 1) * pageSize
+
+	err := db.Where("variant_id = ?", variantID).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&movements).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return movements, nil
+}
+
+// BackfillStockMovements records an opening balance for variants whose stock
+// predates the ledger, so that every quantity is explained by movements.
+func BackfillStockMovements(db *gorm.DB) error {
+	var variants []models.Variant
+	err := db.Where("quantity <> 0 AND id NOT IN (?)", db.Model(&models.StockMovement{}).Select("variant_id")).
+		Find(&variants).Error
+	if err != nil {
+		return err
+	}
+
+	for _, variant := range variants {
+		movement := models.StockMovement{
+			VariantID:     variant.ID,
+			Type:          MovementAdjust,
+			Quantity:      variant.Quantity,
+			QuantityAfter: variant.Quantity,
+			ReasonCode:    ReasonInitial,
+		}
+		if err := db.Create(&movement).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// createTestVariant sets up an organization with a product and a variant
+// holding quantity units at the default location.
+func createTestVariant(t *testing.T, db *gorm.DB, quantity int) (*models.Admin, *models.Variant) {
+	t.Helper()
+
+	admin := createTestAdmin(t, db, "stock@example.com", RoleOwner)
+
+	organizationID, err := GetDefaultOrganizationID(db, admin.ID)
+	if err != nil {
+		t.Fatalf("GetDefaultOrganizationID: %v", err)
+	}
+
+	product := models.Product{Name: "Shirt", AdminID: admin.ID, OrganizationID: organizationID}
+	if err := CreateProduct(db, &product); err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+
+	variant := models.Variant{VariantName: "Large", ProductID: product.ID}
+	if err := CreateVariant(db, &variant); err != nil {
+		t.Fatalf("CreateVariant: %v", err)
+	}
+
+	if quantity > 0 {
+		_, err := RecordStockMovement(db, variant.ID, StockMovementInput{Type: MovementReceive, Quantity: quantity, ReasonCode: ReasonInitial})
+		if err != nil {
+			t.Fatalf("RecordStockMovement: %v", err)
+		}
+	}
+
+	return admin, &variant
+}
+
+func loadTestVariant(t *testing.T, db *gorm.DB, id interface{}) models.Variant {
+	t.Helper()
+
+	var variant models.Variant
+	if err := db.First(&variant, id).Error; err != nil {
+		t.Fatalf("load variant: %v", err)
+	}
+	return variant
+}
+
+func TestStockMovementsLeaveReservedUnitsAlone(t *testing.T) {
+	db := openTestDB(t)
+	admin, variant := createTestVariant(t, db, 10)
+
+	reservation, err := ReserveStock(db, variant.ID, admin.ID, 8, time.Minute, "")
+	if err != nil {
+		t.Fatalf("ReserveStock: %v", err)
+	}
+
+	_, err = RecordStockMovement(db, variant.ID, StockMovementInput{Type: MovementAdjust, Quantity: -3, ReasonCode: ReasonDamaged})
+	var insufficient *InsufficientStockError
+	if !errors.As(err, &insufficient) || insufficient.Available != 2 {
+		t.Fatalf("adjust into reserved stock error = %v, want InsufficientStockError with 2 available", err)
+	}
+
+	if _, err := SetStockLevel(db, variant.ID, 5, StockMovementInput{ReasonCode: ReasonCorrection}); !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("SetStockLevel below reserved error = %v, want ErrInsufficientStock", err)
+	}
+
+	if _, err := RecordStockMovement(db, variant.ID, StockMovementInput{Type: MovementSell, Quantity: -2}); err != nil {
+		t.Fatalf("sell available stock: %v", err)
+	}
+
+	if _, err := ConfirmReservation(db, reservation.ID, StockMovementInput{}); err != nil {
+		t.Fatalf("ConfirmReservation with no stock to spare: %v", err)
+	}
+
+	got := loadTestVariant(t, db, variant.ID)
+	if got.Quantity != 0 || got.Reserved != 0 {
+		t.Fatalf("quantity %d reserved %d, want 0 and 0", got.Quantity, got.Reserved)
+	}
+}
+
+func TestConcurrentReserveConfirmAndAdjust(t *testing.T) {
+	db := openTestDB(t)
+	admin, variant := createTestVariant(t, db, 50)
+
+	const workers = 20
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		confirmed int
+		adjusted  int
+		failures  []error
+	)
+
+	fail := func(err error) {
+		mu.Lock()
+		failures = append(failures, err)
+		mu.Unlock()
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			reservation, err := ReserveStock(db, variant.ID, admin.ID, 3, time.Minute, "")
+			if errors.Is(err, ErrInsufficientAvailableStock) {
+				return
+			}
+			if err != nil {
+				fail(err)
+				return
+			}
+
+			if _, err := ConfirmReservation(db, reservation.ID, StockMovementInput{}); err != nil {
+				fail(err)
+				return
+			}
+
+			mu.Lock()
+			confirmed++
+			mu.Unlock()
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			_, err := RecordStockMovement(db, variant.ID, StockMovementInput{Type: MovementAdjust, Quantity: -2, ReasonCode: ReasonDamaged})
+			if errors.Is(err, ErrInsufficientStock) {
+				return
+			}
+			if err != nil {
+				fail(err)
+				return
+			}
+
+			mu.Lock()
+			adjusted++
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	for _, err := range failures {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Every confirmation must go through: a held unit is never taken by an
+	// adjustment in between
+	if taken := 3*confirmed + 2*adjusted; taken > 50 {
+		t.Fatalf("took %d units out of 50", taken)
+	}
+
+	got := loadTestVariant(t, db, variant.ID)
+	if want := 50 - 3*confirmed - 2*adjusted; got.Quantity != want {
+		t.Fatalf("quantity = %d, want %d", got.Quantity, want)
+	}
+	if got.Reserved != 0 {
+		t.Fatalf("reserved = %d, want 0 once every reservation is confirmed", got.Reserved)
+	}
+
+	var ledger, level int
+	db.Model(&models.StockMovement{}).Where("variant_id = ?", variant.ID).Select("COALESCE(SUM(quantity), 0)").Scan(&ledger)
+	db.Model(&models.StockLevel{}).Where("variant_id = ?", variant.ID).Select("COALESCE(SUM(quantity), 0)").Scan(&level)
+	if ledger != got.Quantity || level != got.Quantity {
+		t.Fatalf("ledger %d and levels %d disagree with quantity %d", ledger, level, got.Quantity)
+	}
+}
+
+func createTestLocation(t *testing.T, db *gorm.DB, admin *models.Admin, code string) *models.Location {
+	t.Helper()
+
+	organizationID, err := GetDefaultOrganizationID(db, admin.ID)
+	if err != nil {
+		t.Fatalf("GetDefaultOrganizationID: %v", err)
+	}
+
+	location := &models.Location{OrganizationID: organizationID, Name: code, Code: code}
+	if err := CreateLocation(db, location); err != nil {
+		t.Fatalf("CreateLocation: %v", err)
+	}
+
+	return location
+}
+
+func TestSetStockLevelAcrossLocations(t *testing.T) {
+	db := openTestDB(t)
+	admin, variant := createTestVariant(t, db, 10)
+	warehouse := createTestLocation(t, db, admin, "warehouse")
+
+	// Stock only at the warehouse: the total lands there, not at the default
+	if _, err := SetStockLevel(db, variant.ID, 0, StockMovementInput{ReasonCode: ReasonCorrection}); err != nil {
+		t.Fatalf("SetStockLevel: %v", err)
+	}
+	if _, err := SetStockLevel(db, variant.ID, 4, StockMovementInput{LocationID: &warehouse.ID, ReasonCode: ReasonCorrection}); err != nil {
+		t.Fatalf("SetStockLevel at the warehouse: %v", err)
+	}
+	if _, err := SetStockLevel(db, variant.ID, 6, StockMovementInput{ReasonCode: ReasonCorrection}); err != nil {
+		t.Fatalf("SetStockLevel with one stocked location: %v", err)
+	}
+	if quantity, _ := getStockLevelQuantity(db, variant.ID, warehouse.ID); quantity != 6 {
+		t.Fatalf("warehouse holds %d, want 6", quantity)
+	}
+
+	if _, err := RecordStockMovement(db, variant.ID, StockMovementInput{Type: MovementReceive, Quantity: 3}); err != nil {
+		t.Fatalf("receive at the default location: %v", err)
+	}
+
+	if _, err := SetStockLevel(db, variant.ID, 5, StockMovementInput{ReasonCode: ReasonCorrection}); !errors.Is(err, ErrMultiLocationStock) {
+		t.Fatalf("SetStockLevel of the total error = %v, want ErrMultiLocationStock", err)
+	}
+
+	if _, err := SetStockLevel(db, variant.ID, 2, StockMovementInput{LocationID: &warehouse.ID, ReasonCode: ReasonCorrection}); err != nil {
+		t.Fatalf("SetStockLevel at the warehouse: %v", err)
+	}
+	if got := loadTestVariant(t, db, variant.ID); got.Quantity != 5 {
+		t.Fatalf("quantity = %d, want 5", got.Quantity)
+	}
+}
+
+func TestTransfersOnlyThroughTransferStock(t *testing.T) {
+	db := openTestDB(t)
+	admin, variant := createTestVariant(t, db, 10)
+	warehouse := createTestLocation(t, db, admin, "warehouse")
+
+	_, err := RecordStockMovement(db, variant.ID, StockMovementInput{Type: MovementTransfer, Quantity: 5, LocationID: &warehouse.ID})
+	if !errors.Is(err, ErrInvalidMovementType) {
+		t.Fatalf("lone transfer leg error = %v, want ErrInvalidMovementType", err)
+	}
+
+	defaultLocationID, err := resolveLocation(db, variant.ID, nil)
+	if err != nil {
+		t.Fatalf("resolveLocation: %v", err)
+	}
+	if _, err := TransferStock(db, variant.ID, defaultLocationID, warehouse.ID, 4, StockMovementInput{}); err != nil {
+		t.Fatalf("TransferStock: %v", err)
+	}
+
+	if quantity, _ := getStockLevelQuantity(db, variant.ID, warehouse.ID); quantity != 4 {
+		t.Fatalf("warehouse holds %d, want 4", quantity)
+	}
+	if got := loadTestVariant(t, db, variant.ID); got.Quantity != 10 {
+		t.Fatalf("quantity = %d, want 10", got.Quantity)
+	}
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	StockTakeOpen      = "open"
+	StockTakePosted    = "posted"
+	StockTakeCancelled = "cancelled"
+)
+
+var (
+	ErrStockTakeNotOpen     = errors.New("stock take is not open")
+	ErrStockTakeEmpty       = errors.New("stock take has no counts")
+	ErrInvalidCountQuantity = errors.New("counted quantity must not be negative")
+	ErrVariantNotInTake     = errors.New("variant does not belong to the stock take's organization")
+)
+
+type StockCount struct {
+	VariantID       uuid.UUID
+	CountedQuantity int
+}
+
+// StockTakeLineReport is a line of a stock take's report. The system quantity
+// is the level when the variant was counted. OverReserved flags a variant
+// whose total is now below what is reserved for it, which a posted shortfall
+// can leave behind.
+type StockTakeLineReport struct {
+	models.StockTakeLine
+	VariantName  string `json:"variantName"`
+	ProductName  string `json:"productName"`
+	Reserved     int    `json:"reserved"`
+	OverReserved bool   `json:"overReserved"`
+}
+
+type StockTakeReport struct {
+	StockTake    *models.StockTake     `json:"stockTake"`
+	Lines        []StockTakeLineReport `json:"lines"`
+	Counted      int                   `json:"counted"`
+	WithVariance int                   `json:"withVariance"`
+	UnitsAdded   int                   `json:"unitsAdded"`
+	UnitsRemoved int                   `json:"unitsRemoved"`
+	OverReserved int                   `json:"overReserved"`
+}
+
+func OpenStockTake(db *gorm.DB, stockTake *models.StockTake) error {
+	stockTake.Status = StockTakeOpen
+	return db.Create(stockTake).Error
+}
+
+func GetAllStockTakes(db *gorm.DB, tenant Tenant, page, pageSize int) ([]models.StockTake, error) {
+	var stockTakes []models.StockTake
+
+	offset := (page - 1) * pageSize
+
+	err := db.Scopes(StockTakeTenantScope(tenant)).
+		Preload("Location").
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&stockTakes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return stockTakes, nil
+}
+
+func GetStockTakeByID(db *gorm.DB, tenant Tenant, id uuid.UUID) (*models.StockTake, error) {
+	var stockTake models.StockTake
+	err := db.Scopes(StockTakeTenantScope(tenant)).Preload("Location").First(&stockTake, id).Error
+	return &stockTake, err
+}
+
+// SubmitStockCounts records counts for an open stock take along with the
+// level at the location when each was counted, so that stock moving between
+// counting and posting is not mistaken for variance. Counting a variant again
+// replaces the earlier count.
+func SubmitStockCounts(db *gorm.DB, stockTakeID, adminID uuid.UUID, counts []StockCount) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var stockTake models.StockTake
+		if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).First(&stockTake, stockTakeID).Error; err != nil {
+			return err
+		}
+		if stockTake.Status != StockTakeOpen {
+			return ErrStockTakeNotOpen
+		}
+
+		now := time.Now()
+		for _, count := range counts {
+			if count.CountedQuantity < 0 {
+				return ErrInvalidCountQuantity
+			}
+
+			inOrganization, err := variantsInOrganization(tx, stockTake.OrganizationID, []uuid.UUID{count.VariantID})
+			if err != nil {
+				return err
+			}
+			if !inOrganization {
+				return ErrVariantNotInTake
+			}
+
+			// Stock changes lock the variant first, so the level read here
+			// is the one the count is taken against
+			var variant models.Variant
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&variant, count.VariantID).Error; err != nil {
+				return err
+			}
+
+			systemQuantity, err := getStockLevelQuantity(tx, count.VariantID, stockTake.LocationID)
+			if err != nil {
+				return err
+			}
+			variance := count.CountedQuantity - systemQuantity
+
+			line := models.StockTakeLine{
+				StockTakeID:     stockTakeID,
+				VariantID:       count.VariantID,
+				CountedQuantity: count.CountedQuantity,
+				CountedByID:     adminID,
+				CountedAt:       now,
+				SystemQuantity:  &systemQuantity,
+				Variance:        &variance,
+			}
+			err = tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "stock_take_id"}, {Name: "variant_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"counted_quantity", "counted_by_id", "counted_at", "system_quantity", "variance"}),
+			}).Create(&line).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// PostStockTake applies every line's variance at the stock take's location as
+// an adjustment, all in one transaction. Stock that moved after a variant was
+// counted stays moved. A variance that would now take the level below zero
+// fails the whole posting with an InsufficientStockError. Reservations do not
+// hold a shortfall back: the count is what is on the shelf, so the total may
+// end up below what is reserved, which the report flags.
+func PostStockTake(db *gorm.DB, stockTakeID uuid.UUID, input StockMovementInput) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var stockTake models.StockTake
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Lines").First(&stockTake, stockTakeID).Error; err != nil {
+			return err
+		}
+		if stockTake.Status != StockTakeOpen {
+			return ErrStockTakeNotOpen
+		}
+		if len(stockTake.Lines) == 0 {
+			return ErrStockTakeEmpty
+		}
+
+		input.Type = MovementAdjust
+		input.ReasonCode = ReasonStockTake
+		input.LocationID = &stockTake.LocationID
+		input.overReserve = true
+		if input.Reference == "" {
+			input.Reference = "stock-take:" + stockTake.ID.String()
+		}
+
+		for _, line := range stockTake.Lines {
+			// Counts submitted before snapshots were kept reconcile against
+			// the current level
+			if line.Variance == nil {
+				var variant models.Variant
+				if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&variant, line.VariantID).Error; err != nil {
+					return err
+				}
+
+				systemQuantity, err := getStockLevelQuantity(tx, line.VariantID, stockTake.LocationID)
+				if err != nil {
+					return err
+				}
+				variance := line.CountedQuantity - systemQuantity
+
+				updates := map[string]interface{}{"system_quantity": systemQuantity, "variance": variance}
+				if err := tx.Model(&models.StockTakeLine{}).Where("id = ?", line.ID).Updates(updates).Error; err != nil {
+					return err
+				}
+				line.Variance = &variance
+			}
+
+			if *line.Variance == 0 {
+				continue
+			}
+
+			input.Quantity = *line.Variance
+
+			movement, err := RecordStockMovement(tx, line.VariantID, input)
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Model(&models.StockTakeLine{}).Where("id = ?", line.ID).Update("movement_id", movement.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&models.StockTake{}).Where("id = ?", stockTake.ID).Updates(map[string]interface{}{
+			"status":       StockTakePosted,
+			"posted_by_id": input.ActorID,
+			"posted_at":    time.Now(),
+		}).Error
+	})
+}
+
+func CancelStockTake(db *gorm.DB, stockTakeID uuid.UUID) error {
+	res := db.Model(&models.StockTake{}).
+		Where("id = ? AND status = ?", stockTakeID, StockTakeOpen).
+		Updates(map[string]interface{}{"status": StockTakeCancelled, "cancelled_at": time.Now()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrStockTakeNotOpen
+	}
+
+	return nil
+}
+
+// GetStockTakeReport lists who counted what and when, with the variances
+// against the system quantity at counting time.
+func GetStockTakeReport(db *gorm.DB, stockTake *models.StockTake) (*StockTakeReport, error) {
+	var lines []StockTakeLineReport
+	err := db.Model(&models.StockTakeLine{}).
+		Select(`stock_take_lines.*, variants.variant_name, products.name AS product_name,
+			variants.reserved, variants.quantity < variants.reserved AS over_reserved`).
+		Joins("JOIN variants ON variants.id = stock_take_lines.variant_id").
+		Joins("JOIN products ON products.id = variants.product_id").
+		Where("stock_take_lines.stock_take_id = ?", stockTake.ID).
+		Order("products.name, variants.variant_name").
+		Scan(&lines).Error
+	if err != nil {
+		return nil, err
+	}
+
+	report := &StockTakeReport{StockTake: stockTake, Lines: lines, Counted: len(lines)}
+
+	for i := range report.Lines {
+		line := &report.Lines[i]
+
+		if line.OverReserved {
+			report.OverReserved++
+		}
+
+		if line.Variance == nil || *line.Variance == 0 {
+			continue
+		}
+
+		report.WithVariance++
+		if *line.Variance > 0 {
+			report.UnitsAdded += *line.Variance
+		} else {
+			report.UnitsRemoved -= *line.Variance
+		}
+	}
+
+	return report, nil
+}
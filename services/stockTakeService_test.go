@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"testing"
+	"time"
+)
+
+func TestStockTakeKeepsMovementsAfterCounting(t *testing.T) {
+	db := openTestDB(t)
+	admin, variant := createTestVariant(t, db, 10)
+
+	organizationID, err := GetDefaultOrganizationID(db, admin.ID)
+	if err != nil {
+		t.Fatalf("GetDefaultOrganizationID: %v", err)
+	}
+	locationID, err := EnsureDefaultLocation(db, organizationID)
+	if err != nil {
+		t.Fatalf("EnsureDefaultLocation: %v", err)
+	}
+
+	stockTake := &models.StockTake{OrganizationID: organizationID, LocationID: locationID, OpenedByID: admin.ID}
+	if err := OpenStockTake(db, stockTake); err != nil {
+		t.Fatalf("OpenStockTake: %v", err)
+	}
+
+	if err := SubmitStockCounts(db, stockTake.ID, admin.ID, []StockCount{{VariantID: variant.ID, CountedQuantity: 7}}); err != nil {
+		t.Fatalf("SubmitStockCounts: %v", err)
+	}
+
+	// Sold while the rest of the shop is still being counted
+	if _, err := RecordStockMovement(db, variant.ID, StockMovementInput{Type: MovementSell, Quantity: -2}); err != nil {
+		t.Fatalf("sell: %v", err)
+	}
+
+	report, err := GetStockTakeReport(db, stockTake)
+	if err != nil {
+		t.Fatalf("GetStockTakeReport: %v", err)
+	}
+	line := report.Lines[0]
+	if *line.SystemQuantity != 10 || *line.Variance != -3 || report.UnitsRemoved != 3 {
+		t.Fatalf("open report line system %d variance %d, removed %d; want 10, -3, 3", *line.SystemQuantity, *line.Variance, report.UnitsRemoved)
+	}
+
+	if err := PostStockTake(db, stockTake.ID, StockMovementInput{ActorID: &admin.ID}); err != nil {
+		t.Fatalf("PostStockTake: %v", err)
+	}
+
+	if got := loadTestVariant(t, db, variant.ID); got.Quantity != 5 {
+		t.Fatalf("quantity after posting = %d, want 5: the counted 7 less the 2 sold since", got.Quantity)
+	}
+}
+
+func TestStockTakePostsShortfallsOfReservedStock(t *testing.T) {
+	db := openTestDB(t)
+	admin, variant := createTestVariant(t, db, 10)
+
+	organizationID, err := GetDefaultOrganizationID(db, admin.ID)
+	if err != nil {
+		t.Fatalf("GetDefaultOrganizationID: %v", err)
+	}
+	locationID, err := EnsureDefaultLocation(db, organizationID)
+	if err != nil {
+		t.Fatalf("EnsureDefaultLocation: %v", err)
+	}
+
+	if _, err := ReserveStock(db, variant.ID, admin.ID, 8, time.Minute, ""); err != nil {
+		t.Fatalf("ReserveStock: %v", err)
+	}
+
+	stockTake := &models.StockTake{OrganizationID: organizationID, LocationID: locationID, OpenedByID: admin.ID}
+	if err := OpenStockTake(db, stockTake); err != nil {
+		t.Fatalf("OpenStockTake: %v", err)
+	}
+
+	// Only 6 on the shelf although 8 are promised
+	if err := SubmitStockCounts(db, stockTake.ID, admin.ID, []StockCount{{VariantID: variant.ID, CountedQuantity: 6}}); err != nil {
+		t.Fatalf("SubmitStockCounts: %v", err)
+	}
+
+	if err := PostStockTake(db, stockTake.ID, StockMovementInput{ActorID: &admin.ID}); err != nil {
+		t.Fatalf("PostStockTake with reserved stock: %v", err)
+	}
+
+	got := loadTestVariant(t, db, variant.ID)
+	if got.Quantity != 6 || got.Reserved != 8 {
+		t.Fatalf("quantity %d reserved %d, want 6 and 8", got.Quantity, got.Reserved)
+	}
+
+	report, err := GetStockTakeReport(db, stockTake)
+	if err != nil {
+		t.Fatalf("GetStockTakeReport: %v", err)
+	}
+	if !report.Lines[0].OverReserved || report.Lines[0].Reserved != 8 || report.OverReserved != 1 {
+		t.Fatalf("report line %+v, %d over-reserved; want the line flagged", report.Lines[0], report.OverReserved)
+	}
+
+	// Regular adjustments are still held to the available quantity
+	_, err = RecordStockMovement(db, variant.ID, StockMovementInput{Type: MovementAdjust, Quantity: -1, ReasonCode: ReasonStockTake})
+	if !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("adjust with the stock take reason error = %v, want ErrInsufficientStock", err)
+	}
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrSupplierInUse = errors.New("supplier has purchase orders")
+
+func CreateSupplier(db *gorm.DB, supplier *models.Supplier) error {
+	return db.Create(supplier).Error
+}
+
+func GetAllSuppliers(db *gorm.DB, tenant Tenant, searchName string) ([]models.Supplier, error) {
+	var suppliers []models.Supplier
+
+	query := db.Scopes(SupplierTenantScope(tenant)).Order("name")
+
+	if searchName != "" {
+		query = query.Where("name LIKE ?", "%"+searchName+"%")
+	}
+
+	if err := query.Find(&suppliers).Error; err != nil {
+		return nil, err
+	}
+
+	return suppliers, nil
+}
+
+func GetSupplierByID(db *gorm.DB, tenant Tenant, id uuid.UUID) (*models.Supplier, error) {
+	var supplier models.Supplier
+	err := db.Scopes(SupplierTenantScope(tenant)).First(&supplier, id).Error
+	return &supplier, err
+}
+
+func UpdateSupplierByID(db *gorm.DB, tenant Tenant, id uuid.UUID, supplier *models.Supplier) error {
+	res := db.Model(&models.Supplier{}).Scopes(SupplierTenantScope(tenant)).Where("id = ?", id).
+		Select("name", "email", "phone", "note").
+		Updates(supplier)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		if _, err := GetSupplierByID(db, tenant, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteSupplierByID refuses to delete a supplier that purchase orders still
+// point at.
+func DeleteSupplierByID(db *gorm.DB, tenant Tenant, id uuid.UUID) error {
+	if _, err := GetSupplierByID(db, tenant, id); err != nil {
+		return err
+	}
+
+	var orders int64
+	if err := db.Model(&models.PurchaseOrder{}).Where("supplier_id = ?", id).Count(&orders).Error; err != nil {
+		return err
+	}
+	if orders > 0 {
+		return ErrSupplierInUse
+	}
+
+	return db.Delete(&models.Supplier{}, id).Error
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const CrossTenantHeader = "X-Cross-Tenant"
+
+var ErrCrossTenantForbidden = errors.New("cross-tenant access requires a superadmin")
+
+// Tenant limits product and variant queries to the organizations the admin
+// is a member of. Superadmins can opt out per request with the
+// X-Cross-Tenant header, which is meant for support staff only. The
+// superadmin flag on Admin can only be granted directly in the database.
+type Tenant struct {
+	AdminID     uuid.UUID
+	CrossTenant bool
+}
+
+func TenantFromContext(c *gin.Context) (Tenant, error) {
+	principal, err := PrincipalFromContext(c)
+	if err != nil {
+		return Tenant{}, err
+	}
+
+	tenant := Tenant{AdminID: principal.AdminID}
+
+	if c.GetHeader(CrossTenantHeader) == "true" {
+		if !principal.Superadmin {
+			return Tenant{}, ErrCrossTenantForbidden
+		}
+		tenant.CrossTenant = true
+	}
+
+	return tenant, nil
+}
+
+// CanAccess reports whether products of the organization are visible to the
+// tenant.
+func (tenant Tenant) CanAccess(db *gorm.DB, organizationID uuid.UUID) (bool, error) {
+	if tenant.CrossTenant {
+		return true, nil
+	}
+
+	return IsOrganizationMember(db, organizationID, tenant.AdminID)
+}
+
+func ProductTenantScope(tenant Tenant) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if tenant.CrossTenant {
+			return db
+		}
+
+		return db.Where("products.organization_id IN (?)", tenantOrganizationIDs(db, tenant))
+	}
+}
+
+func VariantTenantScope(tenant Tenant) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if tenant.CrossTenant {
+			return db
+		}
+
+		products := db.Session(&gorm.Session{NewDB: true}).
+			Model(&models.Product{}).
+			Select("id").
+			Where("organization_id IN (?)", tenantOrganizationIDs(db, tenant))
+
+		return db.Where("variants.product_id IN (?)", products)
+	}
+}
+
+func LocationTenantScope(tenant Tenant) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if tenant.CrossTenant {
+			return db
+		}
+
+		return db.Where("locations.organization_id IN (?)", tenantOrganizationIDs(db, tenant))
+	}
+}
+
+func StockTakeTenantScope(tenant Tenant) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if tenant.CrossTenant {
+			return db
+		}
+
+		return db.Where("stock_takes.organization_id IN (?)", tenantOrganizationIDs(db, tenant))
+	}
+}
+
+func SupplierTenantScope(tenant Tenant) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if tenant.CrossTenant {
+			return db
+		}
+
+		return db.Where("suppliers.organization_id IN (?)", tenantOrganizationIDs(db, tenant))
+	}
+}
+
+func PurchaseOrderTenantScope(tenant Tenant) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if tenant.CrossTenant {
+			return db
+		}
+
+		return db.Where("purchase_orders.organization_id IN (?)", tenantOrganizationIDs(db, tenant))
+	}
+}
+
+func OrderTenantScope(tenant Tenant) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if tenant.CrossTenant {
+			return db
+		}
+
+		return db.Where("orders.organization_id IN (?)", tenantOrganizationIDs(db, tenant))
+	}
+}
+
+func CustomerGroupTenantScope(tenant Tenant) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if tenant.CrossTenant {
+			return db
+		}
+
+		return db.Where("customer_groups.organization_id IN (?)", tenantOrganizationIDs(db, tenant))
+	}
+}
+
+func PriceListTenantScope(tenant Tenant) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if tenant.CrossTenant {
+			return db
+		}
+
+		return db.Where("price_lists.organization_id IN (?)", tenantOrganizationIDs(db, tenant))
+	}
+}
+
+// AdminTenantScope limits admin queries to admins who share an organization
+// with the tenant.
+func AdminTenantScope(tenant Tenant) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if tenant.CrossTenant {
+			return db
+		}
+
+		members := db.Session(&gorm.Session{NewDB: true}).
+			Model(&models.Membership{}).
+			Select("admin_id").
+			Where("organization_id IN (?)", tenantOrganizationIDs(db, tenant))
+
+		return db.Where("admins.id IN (?)", members)
+	}
+}
+
+func tenantOrganizationIDs(db *gorm.DB, tenant Tenant) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).
+		Model(&models.Membership{}).
+		Select("organization_id").
+		Where("admin_id = ?", tenant.AdminID)
+}
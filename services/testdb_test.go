@@ -0,0 +1,89 @@
+package services
+
+import (
+	"fmt"
+	"golang-final-project/models"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB migrates a fresh database for a test. It uses a throwaway
+// SQLite file unless TEST_MYSQL_DSN points at a MySQL database, which the
+// test then empties and migrates.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+
+	var dialector gorm.Dialector
+	if dsn := os.Getenv("TEST_MYSQL_DSN"); dsn != "" {
+		dialector = mysql.Open(dsn)
+	} else {
+		// BEGIN IMMEDIATE makes concurrent transactions queue on the write
+		// lock instead of failing to upgrade to it
+		path := filepath.Join(t.TempDir(), "test.db")
+		dialector = sqlite.Open(fmt.Sprintf("file:%s?_busy_timeout=10000&_txlock=immediate&_foreign_keys=1", path))
+	}
+
+	db, err := gorm.Open(dialector, config)
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+
+	tables := []interface{}{
+		&models.Admin{},
+		&models.Product{},
+		&models.Variant{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.AdminRevocation{},
+		&models.Organization{},
+		&models.Membership{},
+		&models.EmailVerification{},
+		&models.Invitation{},
+		&models.PasswordReset{},
+		&models.LoginAttempt{},
+		&models.AuditEvent{},
+		&models.RecoveryCode{},
+		&models.APIKey{},
+		&models.SigningKey{},
+		&models.OIDCLoginState{},
+		&models.StockMovement{},
+		&models.StockReservation{},
+		&models.Location{},
+		&models.StockLevel{},
+		&models.StockAlert{},
+		&models.StockTake{},
+		&models.StockTakeLine{},
+		&models.Supplier{},
+		&models.PurchaseOrder{},
+		&models.PurchaseOrderLine{},
+		&models.Order{},
+		&models.OrderLine{},
+		&models.CustomerGroup{},
+		&models.PriceList{},
+		&models.PriceListPrice{},
+		&models.PriceListAssignment{},
+	}
+
+	if err := db.Migrator().DropTable(tables...); err != nil {
+		t.Fatalf("drop test tables: %v", err)
+	}
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	return db
+}
@@ -1,6 +1,10 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"strings"
 
 	"golang.org/x/crypto/bcrypt"
@@ -19,9 +23,32 @@ func CheckPassword(password, hashedPassword string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
 
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// CheckDummyPassword does the same bcrypt work as CheckPassword, so a login
+// for an unknown email takes as long as one with a wrong password.
+func CheckDummyPassword(password string) {
+	_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+}
+
 func GetPublicImageIDFromCloudinaryURL(url string) string {
 	parts := strings.Split(url, "/")
 	lastPart := parts[len(parts)-1]
 	publicID := strings.Split(lastPart, ".")[0]
 	return publicID
 }
+
+// GenerateOpaqueToken returns a random URL-safe token with 256 bits of entropy.
+func GenerateOpaqueToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,22 +1,26 @@
 package services
 
 import (
+	"errors"
 	"golang-final-project/models"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+var ErrVariantInUse = errors.New("variant is on an open order, an open purchase order or an active reservation")
+
 func CreateVariant(db *gorm.DB, variant *models.Variant) error {
 	return db.Create(&variant).Error
 }
 
-func GetAllVariantsWithPaginationAndSearch(db *gorm.DB, page, pageSize int, searchName string) ([]models.Variant, error) {
+func GetAllVariantsWithPaginationAndSearch(db *gorm.DB, tenant Tenant, page, pageSize int, searchName string) ([]models.Variant, error) {
 	var variants []models.Variant
 
 	offset := (page - 1) * pageSize
 
-	query := db.Offset(offset).Limit(pageSize)
+	query := db.Scopes(VariantTenantScope(tenant)).Preload("StockLevels.Location").Offset(offset).Limit(pageSize)
 
 	if searchName != "" {
 		query = query.Where("variant_name LIKE ?", "%"+searchName+"%")
@@ -26,35 +30,161 @@ func GetAllVariantsWithPaginationAndSearch(db *gorm.DB, page, pageSize int, sear
 		return nil, err
 	}
 
+	if err := fillVariantDetails(db, variants); err != nil {
+		return nil, err
+	}
+
 	return variants, nil
 }
 
-func GetVariantByID(db *gorm.DB, id uuid.UUID) (*models.Variant, error) {
+// fillVariantDetails sets the on-order quantity and the current price of the
+// variants.
+func fillVariantDetails(db *gorm.DB, variants []models.Variant) error {
+	variantIDs := make([]uuid.UUID, 0, len(variants))
+	for _, variant := range variants {
+		variantIDs = append(variantIDs, variant.ID)
+	}
+
+	onOrder, err := GetOnOrderQuantities(db, variantIDs)
+	if err != nil {
+		return err
+	}
+
+	prices, err := resolveVariantPrices(db, variants, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for i := range variants {
+		variants[i].OnOrder = onOrder[variants[i].ID]
+		variants[i].EffectivePrice = prices[variants[i].ID]
+	}
+
+	return nil
+}
+
+func GetVariantByID(db *gorm.DB, tenant Tenant, id uuid.UUID) (*models.Variant, error) {
 	var variant models.Variant
-	err := db.First(&variant, id).Error
-	return &variant, err
+	if err := db.Scopes(VariantTenantScope(tenant)).Preload("StockLevels.Location").First(&variant, id).Error; err != nil {
+		return &variant, err
+	}
+
+	variants := []models.Variant{variant}
+	err := fillVariantDetails(db, variants)
+	return &variants[0], err
+}
+
+func GetVariantQuantity(db *gorm.DB, id uuid.UUID) (int, error) {
+	var variant models.Variant
+	err := db.Select("quantity").First(&variant, id).Error
+	return variant.Quantity, err
 }
 
-func GetVariantsByProductID(db *gorm.DB, productID uuid.UUID) (*models.Variant, error) {
+// GetVariantAvailable returns on-hand stock minus active reservations.
+func GetVariantAvailable(db *gorm.DB, id uuid.UUID) (int, error) {
 	var variant models.Variant
-	err := db.Where("product_id = ?", productID).First(&variant).Error
+	err := db.Select("quantity", "reserved").First(&variant, id).Error
+	return variant.Available, err
+}
+
+func GetVariantsByProductID(db *gorm.DB, tenant Tenant, productID uuid.UUID) (*models.Variant, error) {
+	var variant models.Variant
+	err := db.Scopes(VariantTenantScope(tenant)).Where("product_id = ?", productID).First(&variant).Error
 	return &variant, err
 }
 
-func UpdateVariantByID(db *gorm.DB, id uuid.UUID, variant *models.Variant) error {
-	return db.Model(&models.Variant{}).Where("id = ?", id).Updates(variant).Error
+func UpdateVariantByID(db *gorm.DB, tenant Tenant, id uuid.UUID, variant *models.Variant) error {
+	return db.Model(&models.Variant{}).Scopes(VariantTenantScope(tenant)).Where("id = ?", id).Updates(variant).Error
 }
 
-func DeleteVariantsByProductID(db *gorm.DB, productID uuid.UUID) error {
-	res := db.Where("product_id = ?", productID).Delete(models.Variant{}).Error
+// DeleteVariantsByProductID fails with ErrVariantInUse while any of the
+// product's variants is still in use.
+func DeleteVariantsByProductID(db *gorm.DB, tenant Tenant, productID uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		variantIDs := tx.Session(&gorm.Session{NewDB: true}).
+			Model(&models.Variant{}).
+			Scopes(VariantTenantScope(tenant)).
+			Select("id").
+			Where("product_id = ?", productID)
+		if err := deleteVariantRecords(tx, variantIDs); err != nil {
+			return err
+		}
+
+		return tx.Scopes(VariantTenantScope(tenant)).Where("product_id = ?", productID).Delete(models.Variant{}).Error
+	})
+}
 
-	if res != nil {
-		return res
+// DeleteVariantByID fails with ErrVariantInUse while the variant is still in
+// use.
+func DeleteVariantByID(db *gorm.DB, tenant Tenant, id uuid.UUID) error {
+	if _, err := GetVariantByID(db, tenant, id); err != nil {
+		return err
 	}
 
-	return nil
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteVariantRecords(tx, []uuid.UUID{id}); err != nil {
+			return err
+		}
+
+		return tx.Scopes(VariantTenantScope(tenant)).Delete(&models.Variant{}, id).Error
+	})
+}
+
+// deleteVariantRecords removes the stock levels and price list tiers of the
+// variants, which may be a slice of IDs or a subquery. Variants on a pending
+// or confirmed order, a purchase order still awaiting goods or an active
+// reservation are refused with ErrVariantInUse.
+func deleteVariantRecords(tx *gorm.DB, variantIDs interface{}) error {
+	var orderLines, purchaseOrderLines, reservations int64
+
+	err := tx.Model(&models.OrderLine{}).
+		Where("variant_id IN (?) AND status IN ?", variantIDs, []string{OrderPending, OrderConfirmed}).
+		Count(&orderLines).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Model(&models.PurchaseOrderLine{}).
+		Joins("JOIN purchase_orders ON purchase_orders.id = purchase_order_lines.purchase_order_id").
+		Where("purchase_order_lines.variant_id IN (?) AND purchase_orders.status IN ?", variantIDs,
+			[]string{PurchaseOrderDraft, PurchaseOrderSubmitted, PurchaseOrderPartiallyReceived}).
+		Count(&purchaseOrderLines).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Model(&models.StockReservation{}).
+		Where("variant_id IN (?) AND status = ?", variantIDs, ReservationActive).
+		Count(&reservations).Error
+	if err != nil {
+		return err
+	}
+
+	if orderLines > 0 || purchaseOrderLines > 0 || reservations > 0 {
+		return ErrVariantInUse
+	}
+
+	if err := tx.Where("variant_id IN (?)", variantIDs).Delete(&models.PriceListPrice{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Where("variant_id IN (?)", variantIDs).Delete(&models.StockLevel{}).Error
+}
+
+// UpdateVariantThresholds overrides the product's thresholds; nil falls back
+// to them again.
+func UpdateVariantThresholds(db *gorm.DB, tenant Tenant, id uuid.UUID, lowStockThreshold, reorderPoint *int) error {
+	return db.Model(&models.Variant{}).Scopes(VariantTenantScope(tenant)).Where("id = ?", id).
+		Updates(map[string]interface{}{"low_stock_threshold": lowStockThreshold, "reorder_point": reorderPoint}).Error
 }
 
-func DeleteVariantByID(db *gorm.DB, id uuid.UUID) error {
-	return db.Delete(&models.Variant{}, id).Error
+// variantsInOrganization reports whether every one of the distinct variants
+// belongs to a product of the organization.
+func variantsInOrganization(db *gorm.DB, organizationID uuid.UUID, variantIDs []uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Model(&models.Variant{}).
+		Joins("JOIN products ON products.id = variants.product_id").
+		Where("variants.id IN ? AND products.organization_id = ?", variantIDs, organizationID).
+		Count(&count).Error
+	return count == int64(len(variantIDs)), err
 }
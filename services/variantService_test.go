@@ -0,0 +1,56 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"testing"
+	"time"
+)
+
+func TestDeleteVariantRefusedWhileInUse(t *testing.T) {
+	db := openTestDB(t)
+	admin, variant := createTestVariant(t, db, 10)
+	tenant := Tenant{AdminID: admin.ID}
+
+	organizationID, err := GetDefaultOrganizationID(db, admin.ID)
+	if err != nil {
+		t.Fatalf("GetDefaultOrganizationID: %v", err)
+	}
+
+	priceList := models.PriceList{OrganizationID: organizationID, Name: "Wholesale", Currency: "USD"}
+	if err := db.Create(&priceList).Error; err != nil {
+		t.Fatalf("create price list: %v", err)
+	}
+
+	tier := models.PriceListPrice{PriceListID: priceList.ID, VariantID: variant.ID, MinQuantity: 1, Amount: 900}
+	if err := db.Create(&tier).Error; err != nil {
+		t.Fatalf("create price list tier: %v", err)
+	}
+
+	reservation, err := ReserveStock(db, variant.ID, admin.ID, 2, time.Minute, "")
+	if err != nil {
+		t.Fatalf("ReserveStock: %v", err)
+	}
+
+	if err := DeleteVariantByID(db, tenant, variant.ID); !errors.Is(err, ErrVariantInUse) {
+		t.Fatalf("DeleteVariantByID error = %v, want ErrVariantInUse", err)
+	}
+	if err := DeleteVariantsByProductID(db, tenant, variant.ProductID); !errors.Is(err, ErrVariantInUse) {
+		t.Fatalf("DeleteVariantsByProductID error = %v, want ErrVariantInUse", err)
+	}
+
+	if _, err := ReleaseReservation(db, reservation.ID); err != nil {
+		t.Fatalf("ReleaseReservation: %v", err)
+	}
+
+	if err := DeleteVariantByID(db, tenant, variant.ID); err != nil {
+		t.Fatalf("DeleteVariantByID: %v", err)
+	}
+
+	var tiers, levels int64
+	db.Model(&models.PriceListPrice{}).Where("variant_id = ?", variant.ID).Count(&tiers)
+	db.Model(&models.StockLevel{}).Where("variant_id = ?", variant.ID).Count(&levels)
+	if tiers != 0 || levels != 0 {
+		t.Fatalf("%d price list tiers and %d stock levels left behind", tiers, levels)
+	}
+}
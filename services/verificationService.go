@@ -0,0 +1,134 @@
+package services
+
+import (
+	"errors"
+	"golang-final-project/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const EmailVerificationTTL = 24 * time.Hour
+
+var (
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	ErrEmailTaken               = errors.New("email is already in use")
+)
+
+// CreateEmailVerification stores a verification token for the given address,
+// which is usually but not necessarily the admin's current email.
+func CreateEmailVerification(db *gorm.DB, admin *models.Admin, email string) (string, error) {
+	token, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	verification := models.EmailVerification{
+		AdminID:   admin.ID,
+		Email:     email,
+		TokenHash: HashToken(token),
+		ExpiresAt: time.Now().Add(EmailVerificationTTL),
+	}
+
+	if err := db.Create(&verification).Error; err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func SendVerificationEmail(email, name, token string) error {
+	return DefaultMailer.Send(Email{
+		To:      email,
+		Subject: "Verify your email address",
+		Body:    "Hi " + name + ",\n\nPlease verify your email address using this link:\n" + appLink("/verify-email", token) + "\n\nThe link expires in 24 hours.",
+	})
+}
+
+// VerifyEmail consumes the token and marks the address as verified.
+func VerifyEmail(db *gorm.DB, token string) (*models.EmailVerification, error) {
+	var verification models.EmailVerification
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", HashToken(token), time.Now()).
+			First(&verification).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		res := tx.Model(&models.EmailVerification{}).
+			Where("id = ? AND used_at IS NULL", verification.ID).
+			Update("used_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInvalidVerificationToken
+		}
+
+		// For an email change this is when the new address takes effect
+		var taken int64
+		err = tx.Model(&models.Admin{}).
+			Where("email = ? AND id <> ?", verification.Email, verification.AdminID).
+			Count(&taken).Error
+		if err != nil {
+			return err
+		}
+		if taken > 0 {
+			return ErrEmailTaken
+		}
+
+		return tx.Model(&models.Admin{}).
+			Where("id = ?", verification.AdminID).
+			Updates(map[string]interface{}{"email": verification.Email, "email_verified_at": now}).Error
+	})
+
+	return &verification, err
+}
+
+// RequestEmailChange replaces any pending verification of the admin with one
+// for the new address. The admin's email only changes once it is verified.
+func RequestEmailChange(db *gorm.DB, admin *models.Admin, email string) (string, error) {
+	var token string
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var taken int64
+		if err := tx.Model(&models.Admin{}).Where("email = ?", email).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return ErrEmailTaken
+		}
+
+		err := tx.Where("admin_id = ? AND used_at IS NULL", admin.ID).Delete(&models.EmailVerification{}).Error
+		if err != nil {
+			return err
+		}
+
+		token, err = CreateEmailVerification(tx, admin, email)
+		return err
+	})
+
+	return token, err
+}
+
+func SendEmailChangeNotice(email, name, newEmail string) error {
+	return DefaultMailer.Send(Email{
+		To:      email,
+		Subject: "Your email address is being changed",
+		Body:    "Hi " + name + ",\n\nA change of your account's email address to " + newEmail + " was requested. It takes effect once the new address is verified.\n\nIf this wasn't you, change your password right away.",
+	})
+}
+
+// BackfillEmailVerification marks admins that never had a verification
+// token, i.e. those registered before verification existed or while
+// registration was open, as verified.
+func BackfillEmailVerification(db *gorm.DB) error {
+	return db.Model(&models.Admin{}).
+		Where("email_verified_at IS NULL AND id NOT IN (?)", db.Model(&models.EmailVerification{}).Select("admin_id")).
+		Update("email_verified_at", gorm.Expr("created_at")).Error
+}